@@ -51,15 +51,33 @@ func main() {
 		}
 	}()
 
+	if metrics := serv.Metrics(); metrics != nil {
+		go func() {
+			if err := metrics.Listen(serv.Config().Server.MetricsAddress); err != nil {
+				serv.Logger().Errorf("Metrics server error: %s", err)
+			}
+		}()
+	}
+
 	// Wait for signal
 	<-quit
 
-	// err = serv.DB().Close()
-	err = serv.Cache().Close()
+	// Stop the HTTP server and background workers before closing what they use
 	err = serv.App().Shutdown()
 	if err != nil {
 		serv.Logger().Fatalf("%s", err)
 	}
+	if metrics := serv.Metrics(); metrics != nil {
+		if err := metrics.Shutdown(); err != nil {
+			serv.Logger().Errorf("Failed to stop metrics server: %s", err)
+		}
+	}
+	if err := serv.DB().Close(); err != nil {
+		serv.Logger().Errorf("Failed to close database: %s", err)
+	}
+	if err := serv.Cache().Close(); err != nil {
+		serv.Logger().Errorf("Failed to close cache: %s", err)
+	}
 
 	serv.Logger().Info(context.Background(), "Server exited gracefully")
 
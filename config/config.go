@@ -11,12 +11,16 @@ import (
 )
 
 type Configuration struct {
-	Server        ServerConfig       `envconfig:"SERVER"`
-	Authorization Authorization      `envconfig:"AUTHORIZATION"`
-	Logger        Logger             `envconfig:"LOGGER"`
-	Redis         RedisConfig        `envconfig:"REDIS"`
-	RedisCluster  RedisClusterConfig `envconfig:"REDIS_CLUSTER"`
-	Xero          XeroConfig         `envconfig:"XERO"`
+	Server        ServerConfig        `envconfig:"SERVER"`
+	Authorization Authorization       `envconfig:"AUTHORIZATION"`
+	Logger        Logger              `envconfig:"LOGGER"`
+	Redis         RedisConfig         `envconfig:"REDIS"`
+	RedisCluster  RedisClusterConfig  `envconfig:"REDIS_CLUSTER"`
+	RedisSentinel RedisSentinelConfig `envconfig:"REDIS_SENTINEL"`
+	Xero          XeroConfig          `envconfig:"XERO"`
+	Database      DatabaseConfig      `envconfig:"DATABASE"`
+	Webhook       WebhookConfig       `envconfig:"WEBHOOK"`
+	Import        ImportConfig        `envconfig:"IMPORT"`
 }
 
 var XeroOAuthConfig *oauth2.Config
@@ -35,6 +39,16 @@ type ServerConfig struct {
 	GrRunningThreshold  int           `envconfig:"GR_RUNNING_THRESHOLD" default:"100"`
 	GcPauseThreshold    int           `envconfig:"GC_PAUSE_THRESHOLD" default:"200"`
 	CacheDeploymentType int           `envconfig:"CACHE_DEPLOYMENT_TYPE" default:"1"`
+	// CacheKeyPrefix is put in front of every cache key, so a shared Redis can be reset safely
+	CacheKeyPrefix string `envconfig:"CACHE_KEY_PREFIX"`
+	// The in-memory cache evicts the least recently used items past CacheMaxItems (zero is
+	// unlimited) and removes expired ones every CacheCleanupInterval
+	CacheMaxItems        int           `envconfig:"CACHE_MAX_ITEMS" default:"100000"`
+	CacheCleanupInterval time.Duration `envconfig:"CACHE_CLEANUP_INTERVAL" default:"1m"`
+	// IdempotencyTTL is how long responses are kept for replay under their Idempotency-Key
+	IdempotencyTTL time.Duration `envconfig:"IDEMPOTENCY_TTL" default:"24h"`
+	// MetricsAddress is the internal listener serving /debug/vars; empty disables it
+	MetricsAddress string `envconfig:"METRICS_ADDRESS" default:"127.0.0.1:9090"`
 }
 
 type Authorization struct {
@@ -59,24 +73,131 @@ type RedisConfig struct {
 	PoolSize    int    `envconfig:"REDIS_POOL_SIZE"`
 	PoolTimeout int    `envconfig:"REDIS_POOL_TIMEOUT"`
 	DB          int    `envconfig:"REDIS_DB"`
+	// Username is the ACL user, Password alone authenticates the default user
+	Username string `envconfig:"REDIS_USERNAME"`
+	// TLS connects over TLS, verifying the server against TLSCAFile (PEM) when set
+	// or the system roots otherwise
+	TLS           bool   `envconfig:"REDIS_TLS"`
+	TLSCAFile     string `envconfig:"REDIS_TLS_CA_FILE"`
+	TLSServerName string `envconfig:"REDIS_TLS_SERVER_NAME"`
 }
 
 type RedisClusterConfig struct {
-	Delimiter   string `envconfig:"REDIS_CLUSTER_DELIMITER"`
-	ReadOnly    bool   `envconfig:"REDIS_CLUSTER_READ_ONLY"`
-	Address     string `envconfig:"REDIS_CLUSTER_ADDRESS"`
-	DefaultDb   string `envconfig:"REDIS_CLUSTER_DEFAULT_DB"`
-	MinIdleCons int    `envconfig:"REDIS_CLUSTER_MIN_IDLE_CONNS"`
-	PoolSize    int    `envconfig:"REDIS_CLUSTER_POOL_SIZE"`
-	PoolTimeout int    `envconfig:"REDIS_CLUSTER_POOL_TIMEOUT"`
-	Password    string `envconfig:"REDIS_CLUSTER_PASSWORD"`
-	DB          int    `envconfig:"REDIS_CLUSTER_DB"`
+	Delimiter     string `envconfig:"REDIS_CLUSTER_DELIMITER"`
+	ReadOnly      bool   `envconfig:"REDIS_CLUSTER_READ_ONLY"`
+	Address       string `envconfig:"REDIS_CLUSTER_ADDRESS"`
+	DefaultDb     string `envconfig:"REDIS_CLUSTER_DEFAULT_DB"`
+	MinIdleCons   int    `envconfig:"REDIS_CLUSTER_MIN_IDLE_CONNS"`
+	PoolSize      int    `envconfig:"REDIS_CLUSTER_POOL_SIZE"`
+	PoolTimeout   int    `envconfig:"REDIS_CLUSTER_POOL_TIMEOUT"`
+	Password      string `envconfig:"REDIS_CLUSTER_PASSWORD"`
+	DB            int    `envconfig:"REDIS_CLUSTER_DB"`
+	Username      string `envconfig:"REDIS_CLUSTER_USERNAME"`
+	TLS           bool   `envconfig:"REDIS_CLUSTER_TLS"`
+	TLSCAFile     string `envconfig:"REDIS_CLUSTER_TLS_CA_FILE"`
+	TLSServerName string `envconfig:"REDIS_CLUSTER_TLS_SERVER_NAME"`
+}
+
+// RedisSentinelConfig connects to the master Sentinel currently reports for MasterName
+type RedisSentinelConfig struct {
+	MasterName string `envconfig:"REDIS_SENTINEL_MASTER_NAME"`
+	// Address lists the Sentinels, split by Delimiter
+	Address   string `envconfig:"REDIS_SENTINEL_ADDRESS"`
+	Delimiter string `envconfig:"REDIS_SENTINEL_DELIMITER" default:","`
+	// SentinelUsername and SentinelPassword authenticate with the Sentinels,
+	// Username and Password with the master
+	SentinelUsername string `envconfig:"REDIS_SENTINEL_SENTINEL_USERNAME"`
+	SentinelPassword string `envconfig:"REDIS_SENTINEL_SENTINEL_PASSWORD"`
+	Username         string `envconfig:"REDIS_SENTINEL_USERNAME"`
+	Password         string `envconfig:"REDIS_SENTINEL_PASSWORD"`
+	DB               int    `envconfig:"REDIS_SENTINEL_DB"`
+	MinIdleCons      int    `envconfig:"REDIS_SENTINEL_MIN_IDLE_CONNS"`
+	PoolSize         int    `envconfig:"REDIS_SENTINEL_POOL_SIZE"`
+	PoolTimeout      int    `envconfig:"REDIS_SENTINEL_POOL_TIMEOUT"`
+	// TLS is used for both the Sentinels and the master
+	TLS           bool   `envconfig:"REDIS_SENTINEL_TLS"`
+	TLSCAFile     string `envconfig:"REDIS_SENTINEL_TLS_CA_FILE"`
+	TLSServerName string `envconfig:"REDIS_SENTINEL_TLS_SERVER_NAME"`
+}
+
+type DatabaseConfig struct {
+	// Driver is "sqlite" for local runs or "postgres" for production
+	Driver          string        `envconfig:"DB_DRIVER" default:"sqlite"`
+	SQLitePath      string        `envconfig:"SQLITE_PATH" default:"usdw.db"`
+	Host            string        `envconfig:"POSTGRESQL_HOST"`
+	Port            int           `envconfig:"POSTGRESQL_PORT" default:"5432"`
+	User            string        `envconfig:"POSTGRESQL_USER"`
+	Password        string        `envconfig:"POSTGRESQL_PASSWORD"`
+	DBName          string        `envconfig:"POSTGRESQL_DBNAME"`
+	SSLMode         string        `envconfig:"POSTGRESQL_SSL_MODE" default:"disable"`
+	MaxOpenConns    int           `envconfig:"DB_MAX_OPEN_CONNS" default:"20"`
+	MaxIdleConns    int           `envconfig:"DB_MAX_IDLE_CONNS" default:"5"`
+	ConnMaxLifetime time.Duration `envconfig:"DB_CONN_MAX_LIFETIME" default:"30m"`
+}
+
+type ImportConfig struct {
+	// CSVProfilesFile is a JSON array of CSV mapping profiles available to every import
+	CSVProfilesFile string `envconfig:"CSV_PROFILES_FILE"`
+}
+
+// WebhookConfig controls delivery of outbound webhooks to subscribers
+type WebhookConfig struct {
+	// DeliveryInterval is how often queued deliveries are sent; zero disables delivery
+	DeliveryInterval time.Duration `envconfig:"DELIVERY_INTERVAL" default:"5s"`
+	Timeout          time.Duration `envconfig:"TIMEOUT" default:"10s"`
+	// Failed deliveries are retried with exponential backoff and dead-lettered after MaxAttempts
+	MaxAttempts int           `envconfig:"MAX_ATTEMPTS" default:"8"`
+	Backoff     time.Duration `envconfig:"BACKOFF" default:"30s"`
+	MaxBackoff  time.Duration `envconfig:"MAX_BACKOFF" default:"1h"`
+	BatchSize   int           `envconfig:"BATCH_SIZE" default:"50"`
+	// Concurrency is how many endpoints are sent to at once; deliveries to one endpoint go in order
+	Concurrency int `envconfig:"CONCURRENCY" default:"10"`
+	// InsecureURLs allows http and private, loopback or link-local subscription URLs.
+	// Only for local development: it lets API callers reach the internal network.
+	InsecureURLs bool `envconfig:"INSECURE_URLS" default:"false"`
 }
 
 type XeroConfig struct {
 	ClientID     string `envconfig:"CLIENT_ID"`
 	ClientSecret string `envconfig:"CLIENT_SECRET"`
 	RedirectURL  string `envconfig:"REDIRECT_URL" default:"https://usdw-g8afh6cmhkf3dgcb.southeastasia-01.azurewebsites.net/oauth/callback"`
+	// StateSecret signs the OAuth state parameter; falls back to the client secret
+	StateSecret string        `envconfig:"STATE_SECRET"`
+	StateTTL    time.Duration `envconfig:"STATE_TTL" default:"10m"`
+	// CustomConnection lets the app fetch its own token with the client_credentials grant,
+	// for a Xero custom connection. Otherwise tenants have to be connected through OAuth.
+	CustomConnection bool `envconfig:"CUSTOM_CONNECTION" default:"false"`
+	// WebhookKey verifies the x-xero-signature of incoming Xero webhooks
+	WebhookKey string `envconfig:"WEBHOOK_KEY"`
+	// Retry policy for rate limited and transient Xero failures
+	RetryCount       int           `envconfig:"RETRY_COUNT" default:"3"`
+	RetryWaitTime    time.Duration `envconfig:"RETRY_WAIT_TIME" default:"1s"`
+	RetryMaxWaitTime time.Duration `envconfig:"RETRY_MAX_WAIT_TIME" default:"60s"`
+	// Client-side token bucket per tenant. A full bucket allows burst plus rate calls in the first
+	// minute, so keep the sum below Xero's 60 calls per minute. A rate of 0 turns the limiter off.
+	RateLimitPerMinute int `envconfig:"RATE_LIMIT_PER_MINUTE" default:"50"`
+	RateLimitBurst     int `envconfig:"RATE_LIMIT_BURST" default:"5"`
+	// Statements with more lines or a larger JSON body are split into consecutive statements
+	// posted in separate requests. Only a single day that does not fit is rejected.
+	MaxStatementLines int `envconfig:"MAX_STATEMENT_LINES" default:"1000"`
+	MaxStatementBytes int `envconfig:"MAX_STATEMENT_BYTES" default:"3000000"`
+	// Xero GET responses are cached per tenant for these durations; zero disables caching of a
+	// resource. Writes through this service invalidate them, changes made elsewhere show once they expire.
+	ConnectionCacheTTL  time.Duration `envconfig:"CONNECTION_CACHE_TTL" default:"5m"`
+	ConnectionsCacheTTL time.Duration `envconfig:"CONNECTIONS_CACHE_TTL" default:"1m"`
+	StatementCacheTTL   time.Duration `envconfig:"STATEMENT_CACHE_TTL" default:"30s"`
+	StatementsCacheTTL  time.Duration `envconfig:"STATEMENTS_CACHE_TTL" default:"30s"`
+	// DedupMode is used when a request does not choose one: off, flag or strip
+	DedupMode string `envconfig:"DEDUP_MODE" default:"flag"`
+	// Pending statements are re-checked with exponential backoff until Xero delivers or rejects them.
+	// A zero interval disables the poller.
+	StatusPollInterval   time.Duration `envconfig:"STATUS_POLL_INTERVAL" default:"30s"`
+	StatusPollBackoff    time.Duration `envconfig:"STATUS_POLL_BACKOFF" default:"1m"`
+	StatusPollMaxBackoff time.Duration `envconfig:"STATUS_POLL_MAX_BACKOFF" default:"1h"`
+	StatusPollBatchSize  int           `envconfig:"STATUS_POLL_BATCH_SIZE" default:"50"`
+	// StatusPollMaxAge is how long after posting a statement that is still pending, or whose
+	// status cannot be checked, is given up on and marked FAILED
+	StatusPollMaxAge time.Duration `envconfig:"STATUS_POLL_MAX_AGE" default:"72h"`
 }
 
 func NewConfig() (*Configuration, error) {
@@ -75,8 +75,8 @@ const docTemplate = `{
                 ],
                 "summary": "Create a new feed connection",
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
                             "$ref": "#/definitions/domain.CreateConnectionsResponse"
                         }
@@ -1,26 +1,80 @@
 package app
 
 import (
+	"context"
 	"github.com/go-resty/resty/v2"
 	"usdw/config"
 	"usdw/pkg/cache"
 	"usdw/pkg/db"
 	"usdw/pkg/logger"
+	"usdw/pkg/middleware"
+	"usdw/pkg/ratelimit"
+	"usdw/pkg/xero"
 
 	"github.com/gofiber/fiber/v2"
 
 	bankfeedhandler "usdw/internal/usecase/bankfeed/controller/http"
+	"usdw/internal/usecase/bankfeed/importer"
 	bankfeedrepository "usdw/internal/usecase/bankfeed/repository"
 	bankfeedservice "usdw/internal/usecase/bankfeed/service"
+	bankfeedworker "usdw/internal/usecase/bankfeed/worker"
+
+	webhookhandler "usdw/internal/usecase/webhook/controller/http"
+	webhookrepository "usdw/internal/usecase/webhook/repository"
+	webhookservice "usdw/internal/usecase/webhook/service"
+	webhookworker "usdw/internal/usecase/webhook/worker"
 
 	xeroauthhandler "usdw/internal/usecase/xero/controller/http"
 )
 
-func NewApplication(app fiber.Router, logger logger.Logger, client *resty.Client, _ *db.DB, cache cache.Engine, config *config.Configuration) {
-	bankFeedRepository := bankfeedrepository.NewBankFeedRepository(client, config)
-	bankFeedService := bankfeedservice.NewBankFeedService(bankFeedRepository, config, cache, logger)
-	bankFeedHandler := bankfeedhandler.NewBankFeedHandler(bankFeedService, config)
+// NewApplication registers the routes and starts the background workers. The returned
+// function stops the workers and must be called on shutdown.
+func NewApplication(app fiber.Router, logger logger.Logger, client *resty.Client, db *db.DB, cache cache.Engine, config *config.Configuration) func() {
+	tokenManager := xero.NewTokenManager(xero.NewCacheTokenStore(cache), config.Xero.CustomConnection)
+	tenantResolver := xero.NewTenantResolver(tokenManager)
+
+	xeroLimiter := ratelimit.NewTokenBucket("xero", cache, config.Xero.RateLimitPerMinute, config.Xero.RateLimitBurst, logger)
+
+	webhookStore := webhookrepository.NewWebhookStore(db)
+	webhookService := webhookservice.NewWebhookService(webhookStore, config, logger)
+	webhookHandler := webhookhandler.NewWebhookHandler(webhookService, config)
+	webhookHandler.InitRoute(app)
+
+	bankFeedRepository := bankfeedrepository.NewBankFeedRepository(client, config, tokenManager, tenantResolver, xeroLimiter)
+	bankFeedStore := bankfeedrepository.NewBankFeedStore(db)
+	csvProfiles, err := importer.LoadCSVProfiles(config.Import.CSVProfilesFile)
+	if err != nil {
+		logger.Fatalf("Failed to load CSV import profiles: %v", err)
+	}
+	importProfileStore := bankfeedrepository.NewImportProfileStore(db, csvProfiles)
+	bankFeedService := bankfeedservice.NewBankFeedService(bankFeedRepository, bankFeedStore, importProfileStore, webhookService, config, cache, logger)
+	idempotency := middleware.IdempotencyMiddleware(cache, config.Server.IdempotencyTTL, logger)
+	bankFeedHandler := bankfeedhandler.NewBankFeedHandler(bankFeedService, idempotency, config)
 	bankFeedHandler.InitRoute(app)
+	bankFeedHandler.InitRoute(app.Group("/tenants/:"+middleware.TenantIDParam, middleware.TenantMiddleware()))
+
+	xeroauthhandler.NewXeroAuthHandler(app, config, tokenManager, tenantResolver)
+
+	// A disconnected organisation loses its token; any connection change refreshes the tenant list
+	xeroWebhooks := xero.NewWebhookRouter()
+	xeroWebhooks.Handle(xero.EventCategoryConnection, xero.Wildcard, func(ctx context.Context, event xero.WebhookEvent) error {
+		tenantResolver.Invalidate()
+		return nil
+	})
+	xeroWebhooks.Handle(xero.EventCategoryConnection, xero.EventTypeDelete, func(ctx context.Context, event xero.WebhookEvent) error {
+		logger.Infof("Xero tenant %s disconnected the app", event.TenantID)
+		return tokenManager.DeleteToken(ctx, event.TenantID)
+	})
+	xeroauthhandler.NewXeroWebhookHandler(app, config, xeroWebhooks, logger)
+
+	statementPoller := bankfeedworker.NewStatementPoller(bankFeedRepository, bankFeedStore, webhookService, config, logger)
+	statementPoller.Start()
+
+	webhookDispatcher := webhookworker.NewWebhookDispatcher(webhookStore, config, logger)
+	webhookDispatcher.Start()
 
-	xeroauthhandler.NewXeroAuthHandler(app)
+	return func() {
+		statementPoller.Stop()
+		webhookDispatcher.Stop()
+	}
 }
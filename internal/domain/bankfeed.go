@@ -2,7 +2,12 @@ package domain
 
 import (
 	"context"
+	"fmt"
+	"iter"
+	"strconv"
+	"time"
 	"usdw/internal/domain/entity"
+	"usdw/pkg/money"
 )
 
 type BankFeedService interface {
@@ -11,11 +16,27 @@ type BankFeedService interface {
 	GetConnectionByID(ctx context.Context, feedConnectionID string) (*Connection, error)
 	DeleteConnection(ctx context.Context, feedConnectionID string) (*DeleteResult, error)
 	PostStatements(ctx context.Context, request PostStatementRequest) (*PostStatementResponse, error)
-	GetStatements(ctx context.Context, page, pageSize int) (*GetStatementsResponse, error)
+	GetStatements(ctx context.Context, filter StatementFilter, page, pageSize int) (*GetStatementsResponse, error)
 	GetStatementByID(ctx context.Context, statementID string) (*StatementResult, error)
+	GetLocalConnections(ctx context.Context, page, pageSize int) (*ConnectionsResponse, error)
+	GetLocalStatements(ctx context.Context, filter StatementFilter, page, pageSize int) (*GetStatementsResponse, error)
+	GetStatementStatus(ctx context.Context, statementID string) (*StatementStatus, error)
+	ImportStatements(ctx context.Context, request ImportStatementsRequest) (*ImportStatementsResponse, error)
+	CreateImportProfile(ctx context.Context, profile CSVProfile) (*CSVProfile, error)
+	GetImportProfiles(ctx context.Context) ([]CSVProfile, error)
+	DeleteImportProfile(ctx context.Context, name string) error
+	GetConnectionContinuity(ctx context.Context, request ContinuityRequest) (*ContinuityReport, error)
+	BulkCreateConnections(ctx context.Context, request BulkCreateConnectionsRequest) (*BulkResponse, error)
+	BulkDeleteConnections(ctx context.Context, request BulkDeleteConnectionsRequest) (*BulkResponse, error)
+	SyncConnections(ctx context.Context, request SyncConnectionsRequest) (*SyncConnectionsResponse, error)
+	// AllConnections and AllStatements fetch the next page from Xero only as the previous one is consumed
+	AllConnections(ctx context.Context) iter.Seq2[Connection, error]
+	AllStatements(ctx context.Context, filter StatementFilter) iter.Seq2[StatementResult, error]
 }
 
 type BankFeedRepository interface {
+	// TenantID returns the Xero tenant the request in ctx is made for
+	TenantID(ctx context.Context) (string, error)
 	CreateConnections(ctx context.Context, request entity.FeedConnectionRequest) (*entity.FeedConnectionResponse, error)
 	FetchConnections(ctx context.Context, page, pageSize int) (*entity.FetchConnectionsResponse, error)
 	FetchConnectionByID(ctx context.Context, feedConnectionID string) (*entity.FeedConnection, error)
@@ -25,6 +46,30 @@ type BankFeedRepository interface {
 	GetStatementByID(ctx context.Context, statementID string) (*entity.StatementResult, error)
 }
 
+// BankFeedStore keeps a local history of the feed connections and statements sent to Xero
+type BankFeedStore interface {
+	SaveConnections(ctx context.Context, records []entity.FeedConnectionRecord) error
+	MarkConnectionDeleted(ctx context.Context, tenantID, feedConnectionID string) error
+	ListConnections(ctx context.Context, tenantID string, page, pageSize int) ([]entity.FeedConnectionRecord, int, error)
+	SaveStatements(ctx context.Context, records []entity.StatementRecord) error
+	ListStatements(ctx context.Context, tenantID string, filter StatementFilter, page, pageSize int) ([]entity.StatementRecord, int, error)
+	GetStatement(ctx context.Context, tenantID, statementID string) (*entity.StatementRecord, error)
+	// ClaimDueStatements returns PENDING statements whose next poll is due at now and moves
+	// their next poll to now+lease, so other replicas leave them alone while they are checked
+	ClaimDueStatements(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]entity.StatementRecord, error)
+	UpdateStatement(ctx context.Context, record *entity.StatementRecord) error
+	// FindOverlappingStatements returns the statements of the connection that were not
+	// rejected and share a day with startDate to endDate
+	FindOverlappingStatements(ctx context.Context, tenantID, feedConnectionID, startDate, endDate string) ([]entity.StatementRecord, error)
+	SavePostedTransactions(ctx context.Context, records []entity.PostedTransactionRecord) error
+	// FindPostedTransactions returns which of transactionIDs were already posted to the connection
+	FindPostedTransactions(ctx context.Context, tenantID, feedConnectionID string, transactionIDs []string) ([]string, error)
+	// DeletePostedTransactions forgets the transactions of a statement Xero rejected
+	DeletePostedTransactions(ctx context.Context, statementID uint) error
+	// ListConnectionStatements returns the statements of the connection that were not rejected
+	ListConnectionStatements(ctx context.Context, tenantID, feedConnectionID string) ([]entity.StatementRecord, error)
+}
+
 type Pagination struct {
 	Page      int `json:"page"`
 	PageSize  int `json:"pageSize"`
@@ -52,13 +97,13 @@ type CreateConnectionsRequest struct {
 }
 
 type CreateConnectionItem struct {
-	AccountToken  string `json:"accountToken"`
-	AccountNumber string `json:"accountNumber,omitempty"`
-	AccountName   string `json:"accountName,omitempty"`
-	AccountType   string `json:"accountType"`
-	Currency      string `json:"currency"`
-	AccountID     string `json:"accountId,omitempty"`
-	Country       string `json:"country,omitempty"`
+	AccountToken  string `json:"accountToken" yaml:"accountToken"`
+	AccountNumber string `json:"accountNumber,omitempty" yaml:"accountNumber"`
+	AccountName   string `json:"accountName,omitempty" yaml:"accountName"`
+	AccountType   string `json:"accountType" yaml:"accountType"`
+	Currency      string `json:"currency" yaml:"currency"`
+	AccountID     string `json:"accountId,omitempty" yaml:"accountId"`
+	Country       string `json:"country,omitempty" yaml:"country"`
 }
 
 type CreateConnectionsResponse struct {
@@ -86,10 +131,52 @@ type DeleteResult struct {
 	Error        *FeedError `json:"error,omitempty"`
 }
 
+const (
+	StatementStatusPending   = "PENDING"
+	StatementStatusDelivered = "DELIVERED"
+	StatementStatusRejected  = "REJECTED"
+	// StatementStatusValid marks statements that passed validation but were not
+	// sent because another statement in the same request failed
+	StatementStatusValid = "VALID"
+	// StatementStatusSkipped marks statements not sent because every line was already posted
+	StatementStatusSkipped = "SKIPPED"
+	// StatementStatusFailed marks statements whose status could not be checked for
+	// XERO_STATUS_POLL_MAX_AGE, e.g. because Xero purged them; their outcome is unknown
+	StatementStatusFailed = "FAILED"
+)
+
+// StatementValidationError is returned when statements fail validation before
+// they reach Xero. Items line up with the request items.
+type StatementValidationError struct {
+	Items []StatementResult
+}
+
+func (e *StatementValidationError) Error() string {
+	rejected := 0
+	for _, item := range e.Items {
+		if item.Status == StatementStatusRejected {
+			rejected++
+		}
+	}
+	return fmt.Sprintf("%d of %d statements failed validation", rejected, len(e.Items))
+}
+
 type PostStatementRequest struct {
 	Items []PostStatementItem `json:"items"`
+	// Dedup is one of the Dedup modes, the configured default when empty
+	Dedup string `json:"-"`
 }
 
+// Dedup modes decide what happens to statement lines and date ranges already posted
+// to a feed connection
+const (
+	DedupOff = "off"
+	// DedupFlag rejects the statement before it is sent
+	DedupFlag = "flag"
+	// DedupStrip leaves posted lines out and moves the statement past posted ranges
+	DedupStrip = "strip"
+)
+
 type PostStatementItem struct {
 	FeedConnectionID string          `json:"feedConnectionId"`
 	StartDate        string          `json:"startDate"`
@@ -101,23 +188,30 @@ type PostStatementItem struct {
 
 type PostStatementResponse struct {
 	Items []StatementResult `json:"items"`
+	// Stripped lists the lines left out in strip mode because they were already posted
+	Stripped []StrippedLines `json:"stripped,omitempty"`
+}
+
+type StrippedLines struct {
+	FeedConnectionID string   `json:"feedConnectionId"`
+	TransactionIDs   []string `json:"transactionIds"`
 }
 
 type Balance struct {
-	Amount               float64 `json:"amount"`
-	CreditDebitIndicator string  `json:"creditDebitIndicator"`
+	Amount               money.Amount `json:"amount"`
+	CreditDebitIndicator string       `json:"creditDebitIndicator"`
 }
 
 type StatementLine struct {
-	PostedDate           string  `json:"postedDate"`
-	Description          string  `json:"description"`
-	Amount               float64 `json:"amount"`
-	CreditDebitIndicator string  `json:"creditDebitIndicator"`
-	TransactionID        string  `json:"transactionId"`
-	PayeeName            string  `json:"payeeName,omitempty"`
-	Reference            string  `json:"reference,omitempty"`
-	ChequeNumber         string  `json:"chequeNumber,omitempty"`
-	TransactionType      string  `json:"transactionType,omitempty"`
+	PostedDate           string       `json:"postedDate"`
+	Description          string       `json:"description"`
+	Amount               money.Amount `json:"amount"`
+	CreditDebitIndicator string       `json:"creditDebitIndicator"`
+	TransactionID        string       `json:"transactionId"`
+	PayeeName            string       `json:"payeeName,omitempty"`
+	Reference            string       `json:"reference,omitempty"`
+	ChequeNumber         string       `json:"chequeNumber,omitempty"`
+	TransactionType      string       `json:"transactionType,omitempty"`
 }
 
 type StatementResult struct {
@@ -132,7 +226,214 @@ type StatementResult struct {
 	Errors             *[]FeedError `json:"errors,omitempty"`
 }
 
+// ImportStatementsRequest is a bank statement file to convert and post
+type ImportStatementsRequest struct {
+	FeedConnectionID string
+	FileName         string
+	Data             []byte
+	// Format is detected from the file when empty
+	Format string
+	// Profile names the CSV mapping profile, required for CSV files
+	Profile string
+	// DayFirst reads ambiguous dates as DD/MM/YYYY
+	DayFirst bool
+	// StartBalance is required for formats without balances, zero otherwise
+	StartBalance *Balance
+	// DryRun returns the converted statements without posting them
+	DryRun bool
+	// Dedup is passed on to PostStatementRequest.Dedup
+	Dedup string
+}
+
+type ImportStatementsResponse struct {
+	Format     string              `json:"format"`
+	Statements []PostStatementItem `json:"statements,omitempty"`
+	Items      []StatementResult   `json:"items,omitempty"`
+	Stripped   []StrippedLines     `json:"stripped,omitempty"`
+}
+
+// StatementStatus is the delivery state of a posted statement as last seen by the poller
+type StatementStatus struct {
+	ID               string       `json:"id"`
+	FeedConnectionID string       `json:"feedConnectionId"`
+	Status           string       `json:"status"`
+	Errors           *[]FeedError `json:"errors,omitempty"`
+	Attempts         int          `json:"attempts"`
+	LastCheckedAt    *time.Time   `json:"lastCheckedAt,omitempty"`
+	NextCheckAt      *time.Time   `json:"nextCheckAt,omitempty"`
+	PostedAt         time.Time    `json:"postedAt"`
+}
+
+// StatementFilter narrows down a statement list. Empty fields and zero line counts match everything.
+type StatementFilter struct {
+	FeedConnectionID string
+	Status           string
+	// StartDate and EndDate keep the statements covering at least one day of the period
+	StartDate string
+	EndDate   string
+	MinLines  int
+	MaxLines  int
+}
+
+func (f StatementFilter) IsZero() bool {
+	return f == StatementFilter{}
+}
+
+func (f StatementFilter) Matches(statement StatementResult) bool {
+	// Xero sends the line count as a string
+	lines, _ := strconv.Atoi(statement.StatementLineCount)
+	switch {
+	case f.FeedConnectionID != "" && statement.FeedConnectionID != f.FeedConnectionID:
+		return false
+	case f.Status != "" && statement.Status != f.Status:
+		return false
+	case f.StartDate != "" && statement.EndDate < f.StartDate:
+		return false
+	case f.EndDate != "" && statement.StartDate > f.EndDate:
+		return false
+	case f.MinLines > 0 && lines < f.MinLines:
+		return false
+	case f.MaxLines > 0 && lines > f.MaxLines:
+		return false
+	}
+	return true
+}
+
 type GetStatementsResponse struct {
 	Pagination *Pagination       `json:"pagination,omitempty"`
 	Items      []StatementResult `json:"items"`
 }
+
+type ContinuityRequest struct {
+	FeedConnectionID string
+	// FromLocal reads the recorded statements instead of paging through Xero
+	FromLocal bool
+	// StartDate and EndDate optionally limit the check to a period, e.g. a month being closed
+	StartDate string
+	EndDate   string
+}
+
+// ContinuityReport lists the problems in the statement history of a feed connection.
+// Rejected statements are not counted.
+type ContinuityReport struct {
+	FeedConnectionID string             `json:"feedConnectionId"`
+	StartDate        string             `json:"startDate,omitempty"`
+	EndDate          string             `json:"endDate,omitempty"`
+	StatementCount   int                `json:"statementCount"`
+	Continuous       bool               `json:"continuous"`
+	Gaps             []DateGap          `json:"gaps"`
+	Overlaps         []StatementOverlap `json:"overlaps"`
+	BalanceBreaks    []BalanceBreak     `json:"balanceBreaks"`
+}
+
+// DateGap is a run of days not covered by any statement
+type DateGap struct {
+	StartDate string `json:"startDate"`
+	EndDate   string `json:"endDate"`
+	Days      int    `json:"days"`
+	// The statements either side of the gap, empty at the edges of the period
+	PreviousStatementID string `json:"previousStatementId,omitempty"`
+	NextStatementID     string `json:"nextStatementId,omitempty"`
+}
+
+// StatementOverlap is a run of days covered by two statements
+type StatementOverlap struct {
+	StartDate   string `json:"startDate"`
+	EndDate     string `json:"endDate"`
+	StatementID string `json:"statementId"`
+	OtherID     string `json:"otherStatementId"`
+}
+
+// BalanceBreak is a statement whose opening balance differs from the closing balance
+// of the statement before it
+type BalanceBreak struct {
+	PreviousStatementID string       `json:"previousStatementId"`
+	StatementID         string       `json:"statementId"`
+	PreviousEndBalance  Balance      `json:"previousEndBalance"`
+	StartBalance        Balance      `json:"startBalance"`
+	Difference          money.Amount `json:"difference"`
+}
+
+type BulkCreateConnectionsRequest struct {
+	Items []CreateConnectionItem `json:"items"`
+	// Reconcile checks afterwards that a connection exists for every account token
+	Reconcile bool `json:"reconcile,omitempty"`
+}
+
+// BulkDeleteConnectionsRequest names the connections to delete by ID or account token
+type BulkDeleteConnectionsRequest struct {
+	IDs           []string `json:"ids,omitempty"`
+	AccountTokens []string `json:"accountTokens,omitempty"`
+	// Reconcile checks afterwards that none of the connections exists any more
+	Reconcile bool `json:"reconcile,omitempty"`
+}
+
+// BulkResponse is a multi-status answer with one result per requested connection
+type BulkResponse struct {
+	Summary        BulkSummary      `json:"summary"`
+	Items          []BulkItemResult `json:"items"`
+	Reconciliation *Reconciliation  `json:"reconciliation,omitempty"`
+}
+
+type BulkSummary struct {
+	Total     int `json:"total"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+}
+
+type BulkItemResult struct {
+	ID           string `json:"id,omitempty"`
+	AccountToken string `json:"accountToken,omitempty"`
+	// Status is the HTTP status of the item on its own
+	Status     int        `json:"status"`
+	XeroStatus string     `json:"xeroStatus,omitempty"`
+	Error      *FeedError `json:"error,omitempty"`
+}
+
+// Reconciliation compares the connections in Xero after a bulk request with the desired state
+type Reconciliation struct {
+	InSync bool `json:"inSync"`
+	// Missing are the requested account tokens without a connection
+	Missing []string `json:"missing"`
+	// Remaining are the connections that should have been deleted but still exist
+	Remaining []string `json:"remaining"`
+}
+
+// ConnectionManifest is the desired set of feed connections, read from YAML or JSON
+type ConnectionManifest struct {
+	Connections []CreateConnectionItem `json:"connections" yaml:"connections"`
+}
+
+type SyncConnectionsRequest struct {
+	// Manifest is a ConnectionManifest as YAML or JSON
+	Manifest []byte
+	// DryRun returns the plan without changing anything
+	DryRun bool
+	// Prune deletes the connections the manifest does not list
+	Prune bool
+}
+
+// SyncPlan is the difference between the manifest and the connections in Xero, matched by account token
+type SyncPlan struct {
+	Create []CreateConnectionItem `json:"create"`
+	// Delete is only filled when pruning, otherwise the same connections are listed as Extra
+	Delete     []Connection         `json:"delete"`
+	Extra      []Connection         `json:"extra"`
+	Unchanged  []string             `json:"unchanged"`
+	Mismatched []ConnectionMismatch `json:"mismatched"`
+}
+
+// ConnectionMismatch is an existing connection whose details differ from the manifest.
+// Xero cannot update connections, so it is reported and left alone.
+type ConnectionMismatch struct {
+	AccountToken string   `json:"accountToken"`
+	ID           string   `json:"id"`
+	Fields       []string `json:"fields"`
+}
+
+type SyncConnectionsResponse struct {
+	DryRun  bool          `json:"dryRun"`
+	Plan    SyncPlan      `json:"plan"`
+	Created *BulkResponse `json:"created,omitempty"`
+	Deleted *BulkResponse `json:"deleted,omitempty"`
+}
@@ -15,9 +15,11 @@ const (
 	INVALID           string = "40000"
 	DEADLINE_EXCEEDED string = "50400"
 	NOT_FOUND         string = "40400"
+	CONFLICT          string = "40900"
 	UNAUTHENTICATED   string = "40100"
 	PERMISSION_DENIED string = "40300"
 	INTERNAL_ERROR    string = "50000"
 	UNAVAILABLE       string = "50300"
 	FRAMEWORK_ERROR   string = "50200"
+	UPSTREAM_ERROR    string = "50201"
 )
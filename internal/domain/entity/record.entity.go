@@ -0,0 +1,72 @@
+package entity
+
+import (
+	"time"
+	"usdw/pkg/money"
+
+	"gorm.io/gorm"
+)
+
+// FeedConnectionRecord is the local audit copy of a feed connection created through this service
+type FeedConnectionRecord struct {
+	ID            uint   `gorm:"primaryKey"`
+	TenantID      string `gorm:"index;size:64"`
+	XeroID        string `gorm:"index;size:64"` // empty when Xero rejected the connection
+	AccountToken  string `gorm:"index;size:128"`
+	AccountNumber string `gorm:"size:64"`
+	AccountName   string
+	AccountType   string `gorm:"size:32"`
+	AccountID     string `gorm:"size:64"`
+	Currency      string `gorm:"size:3"`
+	Country       string `gorm:"size:2"`
+	Status        string `gorm:"index;size:32"`
+	Error         string `gorm:"type:text"` // FeedError as JSON
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	DeletedAt     gorm.DeletedAt `gorm:"index"` // set when the connection is deleted in Xero
+}
+
+// StatementRecord is the local audit copy of a statement posted through this service
+type StatementRecord struct {
+	ID                 uint         `gorm:"primaryKey"`
+	TenantID           string       `gorm:"index;size:64"`
+	XeroID             string       `gorm:"index;size:64"` // empty when Xero rejected the statement
+	FeedConnectionID   string       `gorm:"index;size:64"`
+	Status             string       `gorm:"index;size:32"`
+	StartDate          string       `gorm:"size:10"`
+	EndDate            string       `gorm:"size:10"`
+	StartBalance       money.Amount `gorm:"type:varchar(32)"`
+	StartIndicator     string       `gorm:"size:6"`
+	EndBalance         money.Amount `gorm:"type:varchar(32)"`
+	EndIndicator       string       `gorm:"size:6"`
+	StatementLineCount int
+	StatementLines     string `gorm:"type:text"` // []StatementLine as JSON
+	Errors             string `gorm:"type:text"` // []FeedError as JSON
+	// Polling state while the statement is PENDING in Xero
+	Attempts      int
+	NextPollAt    *time.Time `gorm:"index"`
+	LastCheckedAt *time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// ImportProfileRecord stores a CSV mapping profile created through the API
+type ImportProfileRecord struct {
+	ID         uint   `gorm:"primaryKey"`
+	Name       string `gorm:"uniqueIndex;size:64"`
+	Definition string `gorm:"type:text"` // CSVProfile as JSON
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// PostedTransactionRecord remembers a transaction ID sent to Xero for a feed connection
+// so it is not posted again
+type PostedTransactionRecord struct {
+	ID               uint   `gorm:"primaryKey"`
+	TenantID         string `gorm:"uniqueIndex:idx_posted_transaction;size:64"`
+	FeedConnectionID string `gorm:"uniqueIndex:idx_posted_transaction;size:64"`
+	TransactionID    string `gorm:"uniqueIndex:idx_posted_transaction;size:255"`
+	StatementID      uint   `gorm:"index"` // StatementRecord.ID
+	PostedDate       string `gorm:"size:10"`
+	CreatedAt        time.Time
+}
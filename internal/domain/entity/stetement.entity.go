@@ -1,5 +1,7 @@
 package entity
 
+import "usdw/pkg/money"
+
 type StatementRequest struct {
 	Items []StatementItem `json:"items"`
 }
@@ -31,18 +33,18 @@ type StatementItem struct {
 }
 
 type Balance struct {
-	Amount               float64 `json:"amount"`
-	CreditDebitIndicator string  `json:"creditDebitIndicator"`
+	Amount               money.Amount `json:"amount"`
+	CreditDebitIndicator string       `json:"creditDebitIndicator"`
 }
 
 type StatementLine struct {
-	PostedDate           string  `json:"postedDate"`
-	Description          string  `json:"description"`
-	Amount               float64 `json:"amount"`
-	CreditDebitIndicator string  `json:"creditDebitIndicator"`
-	TransactionID        string  `json:"transactionId"`
-	PayeeName            string  `json:"payeeName,omitempty"`
-	Reference            string  `json:"reference,omitempty"`
-	ChequeNumber         string  `json:"chequeNumber,omitempty"`
-	TransactionType      string  `json:"transactionType,omitempty"`
+	PostedDate           string       `json:"postedDate"`
+	Description          string       `json:"description"`
+	Amount               money.Amount `json:"amount"`
+	CreditDebitIndicator string       `json:"creditDebitIndicator"`
+	TransactionID        string       `json:"transactionId"`
+	PayeeName            string       `json:"payeeName,omitempty"`
+	Reference            string       `json:"reference,omitempty"`
+	ChequeNumber         string       `json:"chequeNumber,omitempty"`
+	TransactionType      string       `json:"transactionType,omitempty"`
 }
@@ -0,0 +1,30 @@
+package entity
+
+import "time"
+
+type WebhookSubscriptionRecord struct {
+	ID        uint   `gorm:"primaryKey"`
+	URL       string `gorm:"size:2048"`
+	Secret    string `gorm:"size:128"`
+	Events    string // comma separated event types
+	TenantID  string `gorm:"index;size:64"` // empty for every tenant
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// WebhookDeliveryRecord is one event queued for one subscription
+type WebhookDeliveryRecord struct {
+	ID             uint   `gorm:"primaryKey"`
+	SubscriptionID uint   `gorm:"index"`
+	URL            string `gorm:"size:2048"`
+	EventID        string `gorm:"index;size:64"`
+	EventType      string `gorm:"size:64"`
+	Payload        string `gorm:"type:text"` // WebhookEvent as JSON, signed as is
+	Status         string `gorm:"index;size:16"`
+	Attempts       int
+	NextAttemptAt  *time.Time `gorm:"index"`
+	LastAttemptAt  *time.Time
+	LastError      string `gorm:"type:text"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
@@ -0,0 +1,53 @@
+package domain
+
+import "context"
+
+// CSVProfile describes how the columns of a bank's CSV export map onto statement lines.
+// Columns are header names, or 1-based column numbers when the file has no header.
+type CSVProfile struct {
+	Name      string `json:"name"`
+	Delimiter string `json:"delimiter,omitempty"` // defaults to ","
+	HasHeader bool   `json:"hasHeader"`
+	SkipRows  int    `json:"skipRows,omitempty"` // lines before the header or first row
+	// DateFormat uses YYYY, YY, MMM, MM, M, DD and D, e.g. DD/MM/YYYY; defaults to YYYY-MM-DD
+	DateFormat   string `json:"dateFormat,omitempty"`
+	DecimalComma bool   `json:"decimalComma,omitempty"` // 1.234,56 instead of 1,234.56
+	// NewestFirst is set for exports that list the latest transaction first
+	NewestFirst bool       `json:"newestFirst,omitempty"`
+	Columns     CSVColumns `json:"columns"`
+	// DebitPositive is set when a single amount column shows money out as positive
+	DebitPositive bool `json:"debitPositive,omitempty"`
+	// Values of the indicator column, default CR/C/CREDIT and DR/D/DEBIT
+	CreditValues []string `json:"creditValues,omitempty"`
+	DebitValues  []string `json:"debitValues,omitempty"`
+	// Source is "config" for profiles from the profiles file and "db" for stored ones
+	Source string `json:"source,omitempty"`
+}
+
+// CSVColumns maps statement line fields to columns. Either Amount or Debit and Credit is required.
+type CSVColumns struct {
+	Date            string `json:"date"`
+	Description     string `json:"description,omitempty"`
+	Amount          string `json:"amount,omitempty"`
+	Debit           string `json:"debit,omitempty"`
+	Credit          string `json:"credit,omitempty"`
+	Indicator       string `json:"indicator,omitempty"`
+	TransactionID   string `json:"transactionId,omitempty"`
+	PayeeName       string `json:"payeeName,omitempty"`
+	Reference       string `json:"reference,omitempty"`
+	ChequeNumber    string `json:"chequeNumber,omitempty"`
+	TransactionType string `json:"transactionType,omitempty"`
+	// Balance is the running balance after each row. When set the start and end
+	// balances are worked out from it, otherwise the statement starts from the
+	// startBalance given on import.
+	Balance string `json:"balance,omitempty"`
+}
+
+// ImportProfileStore holds CSV profiles from the profiles file and the database
+type ImportProfileStore interface {
+	SaveProfile(ctx context.Context, profile CSVProfile) error
+	// GetProfile returns nil when no profile has the name
+	GetProfile(ctx context.Context, name string) (*CSVProfile, error)
+	ListProfiles(ctx context.Context) ([]CSVProfile, error)
+	DeleteProfile(ctx context.Context, name string) error
+}
@@ -0,0 +1,105 @@
+package domain
+
+import (
+	"context"
+	"time"
+	"usdw/internal/domain/entity"
+)
+
+// Events sent to webhook subscribers
+const (
+	EventStatementDelivered = "statement.delivered"
+	EventStatementRejected  = "statement.rejected"
+	EventStatementFailed    = "statement.failed"
+	EventConnectionCreated  = "connection.created"
+	EventConnectionDeleted  = "connection.deleted"
+)
+
+// WebhookEvents lists every event a subscription can ask for
+var WebhookEvents = []string{
+	EventStatementDelivered,
+	EventStatementRejected,
+	EventStatementFailed,
+	EventConnectionCreated,
+	EventConnectionDeleted,
+}
+
+const (
+	DeliveryStatusPending   = "PENDING"
+	DeliveryStatusDelivered = "DELIVERED"
+	// DeliveryStatusDead marks deliveries that ran out of attempts, the dead-letter list
+	DeliveryStatusDead = "DEAD"
+)
+
+// WebhookPublisher queues an event for every subscriber interested in it
+type WebhookPublisher interface {
+	Publish(ctx context.Context, tenantID, eventType string, data interface{}) error
+}
+
+type WebhookService interface {
+	WebhookPublisher
+	CreateSubscription(ctx context.Context, request CreateSubscriptionRequest) (*Subscription, error)
+	GetSubscriptions(ctx context.Context) ([]Subscription, error)
+	DeleteSubscription(ctx context.Context, subscriptionID uint) error
+	GetDeadLetters(ctx context.Context, page, pageSize int) (*DeadLettersResponse, error)
+	RetryDeadLetter(ctx context.Context, deliveryID uint) error
+}
+
+type WebhookStore interface {
+	SaveSubscription(ctx context.Context, record *entity.WebhookSubscriptionRecord) error
+	ListSubscriptions(ctx context.Context) ([]entity.WebhookSubscriptionRecord, error)
+	GetSubscription(ctx context.Context, subscriptionID uint) (*entity.WebhookSubscriptionRecord, error)
+	DeleteSubscription(ctx context.Context, subscriptionID uint) error
+	SaveDeliveries(ctx context.Context, records []entity.WebhookDeliveryRecord) error
+	// ClaimDueDeliveries returns PENDING deliveries whose next attempt is due at now and moves
+	// their next attempt to now+lease, so other replicas do not send them as well
+	ClaimDueDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]entity.WebhookDeliveryRecord, error)
+	ListDeadDeliveries(ctx context.Context, page, pageSize int) ([]entity.WebhookDeliveryRecord, int, error)
+	GetDelivery(ctx context.Context, deliveryID uint) (*entity.WebhookDeliveryRecord, error)
+	UpdateDelivery(ctx context.Context, record *entity.WebhookDeliveryRecord) error
+}
+
+// WebhookEvent is the JSON body posted to subscribers
+type WebhookEvent struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	TenantID  string      `json:"tenantId"`
+	CreatedAt time.Time   `json:"createdAt"`
+	Data      interface{} `json:"data"`
+}
+
+type CreateSubscriptionRequest struct {
+	URL string `json:"url"`
+	// Events defaults to every event
+	Events []string `json:"events,omitempty"`
+	// TenantID limits the subscription to one Xero organisation
+	TenantID string `json:"tenantId,omitempty"`
+	// Secret signs the payloads; generated when empty
+	Secret string `json:"secret,omitempty"`
+}
+
+type Subscription struct {
+	ID        uint      `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	TenantID  string    `json:"tenantId,omitempty"`
+	Secret    string    `json:"secret,omitempty"` // only returned when the subscription is created
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type DeadLetter struct {
+	ID             uint       `json:"id"`
+	SubscriptionID uint       `json:"subscriptionId"`
+	URL            string     `json:"url"`
+	EventID        string     `json:"eventId"`
+	EventType      string     `json:"eventType"`
+	Attempts       int        `json:"attempts"`
+	LastError      string     `json:"lastError"`
+	LastAttemptAt  *time.Time `json:"lastAttemptAt,omitempty"`
+	CreatedAt      time.Time  `json:"createdAt"`
+}
+
+type DeadLettersResponse struct {
+	Pagination Pagination   `json:"pagination"`
+	Items      []DeadLetter `json:"items"`
+}
@@ -2,31 +2,48 @@ package http
 
 import (
 	"github.com/gofiber/fiber/v2"
+	"io"
 	"strconv"
+	"strings"
+	"time"
 	"usdw/config"
 	"usdw/internal/domain"
+	"usdw/pkg/common/exception"
+	"usdw/pkg/money"
 )
 
 type BankFeedHandler struct {
 	domain.BankFeedService
 	config.Configuration
+	// Idempotency guards the POST routes that create resources in Xero
+	Idempotency fiber.Handler
 }
 
-func NewBankFeedHandler(bankFeedService domain.BankFeedService, config *config.Configuration) BankFeedHandler {
+func NewBankFeedHandler(bankFeedService domain.BankFeedService, idempotency fiber.Handler, config *config.Configuration) BankFeedHandler {
 	return BankFeedHandler{
 		BankFeedService: bankFeedService,
 		Configuration:   *config,
+		Idempotency:     idempotency,
 	}
 }
 
 func (h *BankFeedHandler) InitRoute(app fiber.Router) {
-	app.Post("/feed-connections", h.CreateConnections)
+	app.Post("/feed-connections", h.Idempotency, h.CreateConnections)
+	app.Post("/feed-connections/batch", h.Idempotency, h.BulkCreateConnections)
+	app.Post("/feed-connections/delete", h.Idempotency, h.BulkDeleteConnections)
+	app.Post("/feed-connections/sync", h.Idempotency, h.SyncConnections)
 	app.Get("/feed-connections", h.GetConnections)
 	app.Get("/feed-connections/:id", h.GetConnectionByID)
+	app.Get("/feed-connections/:id/continuity", h.GetConnectionContinuity)
 	app.Delete("/feed-connections/:id", h.DeleteFeedConnection)
-	app.Post("/statements", h.PostStatements)
+	app.Post("/statements", h.Idempotency, h.PostStatements)
+	app.Post("/statements/import", h.ImportStatements)
 	app.Get("/statements", h.GetStatements)
 	app.Get("/statements/:id", h.GetStatementByID)
+	app.Get("/statements/:id/status", h.GetStatementStatus)
+	app.Post("/import-profiles", h.CreateImportProfile)
+	app.Get("/import-profiles", h.GetImportProfiles)
+	app.Delete("/import-profiles/:name", h.DeleteImportProfile)
 }
 
 // @Summary Create a new feed connection
@@ -34,41 +51,144 @@ func (h *BankFeedHandler) InitRoute(app fiber.Router) {
 // @Tags FeedConnections
 // @Accept json
 // @Produce json
-// @Success 201 {object} domain.CreateConnectionsResponse
-// @Failure 400 {object} exception.ErrorResponse
-// @Failure 500 {object} exception.ErrorResponse
+// @Param Idempotency-Key header string false "Replays the stored response when a request is retried with the same key"
+// @Success 200 {object} domain.CreateConnectionsResponse
+// @Failure 400 {object} exception.ProblemDetails
+// @Failure 409 {object} exception.ProblemDetails
+// @Failure 429 {object} exception.ProblemDetails
+// @Failure 500 {object} exception.ProblemDetails
 // @Router /feed-connections [post]
 func (h *BankFeedHandler) CreateConnections(c *fiber.Ctx) error {
 	var request domain.CreateConnectionsRequest
 	if err := c.BodyParser(&request); err != nil {
-		return c.Status(fiber.StatusBadRequest).SendString("Invalid request format")
+		return exception.BadRequestError{Message: "Invalid request format"}
 	}
 
-	response, err := h.BankFeedService.CreateConnections(c.Context(), request)
+	response, err := h.BankFeedService.CreateConnections(c.UserContext(), request)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+		return err
 	}
 
 	return c.JSON(response)
 }
 
+// @Summary Create many feed connections
+// @Description Creates the feed connections in batches and reports the outcome of each one
+// @Tags FeedConnections
+// @Accept json
+// @Produce json
+// @Param Idempotency-Key header string false "Replays the stored response when a request is retried with the same key"
+// @Param request body domain.BulkCreateConnectionsRequest true "Connections to create"
+// @Success 207 {object} domain.BulkResponse
+// @Failure 400 {object} exception.ProblemDetails
+// @Failure 409 {object} exception.ProblemDetails
+// @Failure 500 {object} exception.ProblemDetails
+// @Router /feed-connections/batch [post]
+func (h *BankFeedHandler) BulkCreateConnections(c *fiber.Ctx) error {
+	var request domain.BulkCreateConnectionsRequest
+	if err := c.BodyParser(&request); err != nil {
+		return exception.BadRequestError{Message: "Invalid request format"}
+	}
+
+	response, err := h.BankFeedService.BulkCreateConnections(c.UserContext(), request)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusMultiStatus).JSON(response)
+}
+
+// @Summary Delete many feed connections
+// @Description Deletes feed connections by ID or account token and reports the outcome of each one
+// @Tags FeedConnections
+// @Accept json
+// @Produce json
+// @Param Idempotency-Key header string false "Replays the stored response when a request is retried with the same key"
+// @Param request body domain.BulkDeleteConnectionsRequest true "Connections to delete"
+// @Success 207 {object} domain.BulkResponse
+// @Failure 400 {object} exception.ProblemDetails
+// @Failure 409 {object} exception.ProblemDetails
+// @Failure 500 {object} exception.ProblemDetails
+// @Router /feed-connections/delete [post]
+func (h *BankFeedHandler) BulkDeleteConnections(c *fiber.Ctx) error {
+	var request domain.BulkDeleteConnectionsRequest
+	if err := c.BodyParser(&request); err != nil {
+		return exception.BadRequestError{Message: "Invalid request format"}
+	}
+
+	response, err := h.BankFeedService.BulkDeleteConnections(c.UserContext(), request)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusMultiStatus).JSON(response)
+}
+
+// @Summary Sync feed connections with a manifest
+// @Description Creates the connections a YAML or JSON manifest lists that do not exist yet. Connections missing from the manifest are only deleted with prune=true.
+// @Tags FeedConnections
+// @Accept json,application/yaml
+// @Produce json
+// @Param Idempotency-Key header string false "Replays the stored response when a request is retried with the same key"
+// @Param dryRun query bool false "Return the plan without changing anything"
+// @Param prune query bool false "Delete the connections the manifest does not list"
+// @Param request body domain.ConnectionManifest true "Connections that should exist"
+// @Success 200 {object} domain.SyncConnectionsResponse
+// @Failure 400 {object} exception.ProblemDetails
+// @Failure 409 {object} exception.ProblemDetails
+// @Failure 500 {object} exception.ProblemDetails
+// @Router /feed-connections/sync [post]
+func (h *BankFeedHandler) SyncConnections(c *fiber.Ctx) error {
+	response, err := h.BankFeedService.SyncConnections(c.UserContext(), domain.SyncConnectionsRequest{
+		Manifest: c.Body(),
+		DryRun:   c.QueryBool("dryRun"),
+		Prune:    c.QueryBool("prune"),
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
 // @Summary Get all feed connections
 // @Description Retrieves a paginated list of feed connections
 // @Tags FeedConnections
 // @Accept json
-// @Produce json
+// @Produce json,application/x-ndjson
 // @Param page query int false "Page number"
 // @Param pageSize query int false "Number of items per page"
+// @Param source query string false "Set to local to read the connections recorded by this service" Enums(xero, local)
+// @Param all query bool false "Stream every connection from Xero as NDJSON, one per line, instead of one page"
 // @Success 200 {object} domain.ConnectionsResponse
-// @Failure 500 {object} exception.ErrorResponse
+// @Failure 400 {object} exception.ProblemDetails
+// @Failure 500 {object} exception.ProblemDetails
 // @Router /feed-connections [get]
 func (h *BankFeedHandler) GetConnections(c *fiber.Ctx) error {
+	if c.QueryBool("all") {
+		if fromLocalStore(c) {
+			return exception.BadRequestError{Message: "all is not supported with source=local"}
+		}
+		return streamNDJSON(c, h.BankFeedService.AllConnections(c.UserContext()))
+	}
+
 	page, _ := strconv.Atoi(c.Query("page", "1"))
 	pageSize, _ := strconv.Atoi(c.Query("pageSize", "20"))
 
-	response, err := h.BankFeedService.GetConnections(c.Context(), page, pageSize)
+	if fromLocalStore(c) {
+		if page < 1 || pageSize < 1 {
+			return exception.BadRequestError{Message: "Invalid page or pageSize parameter"}
+		}
+		response, err := h.BankFeedService.GetLocalConnections(c.UserContext(), page, pageSize)
+		if err != nil {
+			return err
+		}
+		return c.JSON(response)
+	}
+
+	response, err := h.BankFeedService.GetConnections(c.UserContext(), page, pageSize)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+		return err
 	}
 
 	return c.JSON(response)
@@ -81,18 +201,51 @@ func (h *BankFeedHandler) GetConnections(c *fiber.Ctx) error {
 // @Produce json
 // @Param id path string true "Feed Connection ID"
 // @Success 200 {object} domain.Connection
-// @Failure 404 {object} exception.ErrorResponse
-// @Failure 500 {object} exception.ErrorResponse
+// @Failure 404 {object} exception.ProblemDetails
+// @Failure 500 {object} exception.ProblemDetails
 // @Router /feed-connections/{id} [get]
 func (h *BankFeedHandler) GetConnectionByID(c *fiber.Ctx) error {
 	feedConnectionID := c.Params("id")
 
-	response, err := h.BankFeedService.GetConnectionByID(c.Context(), feedConnectionID)
+	response, err := h.BankFeedService.GetConnectionByID(c.UserContext(), feedConnectionID)
 	if err != nil {
-		if err.Error() == "feed connection not found" {
-			return c.Status(fiber.StatusNotFound).SendString(err.Error())
-		}
-		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+		return err
+	}
+
+	return c.JSON(response)
+}
+
+// @Summary Check the continuity of a feed connection's statements
+// @Description Reports the days no statement covers, the days covered twice and the opening balances that do not match the previous closing balance
+// @Tags FeedConnections
+// @Produce json
+// @Param id path string true "Feed Connection ID"
+// @Param source query string false "Set to local to check the statements recorded by this service" Enums(xero, local)
+// @Param startDate query string false "First day of the period to check (YYYY-MM-DD)"
+// @Param endDate query string false "Last day of the period to check (YYYY-MM-DD)"
+// @Success 200 {object} domain.ContinuityReport
+// @Failure 400 {object} exception.ProblemDetails
+// @Failure 404 {object} exception.ProblemDetails
+// @Failure 500 {object} exception.ProblemDetails
+// @Router /feed-connections/{id}/continuity [get]
+func (h *BankFeedHandler) GetConnectionContinuity(c *fiber.Ctx) error {
+	request := domain.ContinuityRequest{
+		FeedConnectionID: c.Params("id"),
+		FromLocal:        fromLocalStore(c),
+		StartDate:        c.Query("startDate"),
+		EndDate:          c.Query("endDate"),
+	}
+
+	if !isDate(request.StartDate) || !isDate(request.EndDate) {
+		return exception.BadRequestError{Message: "Invalid startDate or endDate parameter, expected YYYY-MM-DD"}
+	}
+	if request.StartDate != "" && request.EndDate != "" && request.StartDate > request.EndDate {
+		return exception.BadRequestError{Message: "startDate must not be after endDate"}
+	}
+
+	response, err := h.BankFeedService.GetConnectionContinuity(c.UserContext(), request)
+	if err != nil {
+		return err
 	}
 
 	return c.JSON(response)
@@ -105,14 +258,14 @@ func (h *BankFeedHandler) GetConnectionByID(c *fiber.Ctx) error {
 // @Produce json
 // @Param id path string true "Feed Connection ID"
 // @Success 200 {object} domain.DeleteResult
-// @Failure 500 {object} exception.ErrorResponse
+// @Failure 500 {object} exception.ProblemDetails
 // @Router /feed-connections/{id} [delete]
 func (h *BankFeedHandler) DeleteFeedConnection(c *fiber.Ctx) error {
 	feedConnectionID := c.Params("id")
 
-	response, err := h.BankFeedService.DeleteConnection(c.Context(), feedConnectionID)
+	response, err := h.BankFeedService.DeleteConnection(c.UserContext(), feedConnectionID)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+		return err
 	}
 
 	return c.JSON(response)
@@ -123,61 +276,155 @@ func (h *BankFeedHandler) DeleteFeedConnection(c *fiber.Ctx) error {
 // @Tags Statements
 // @Accept json
 // @Produce json
+// @Param dedup query string false "What to do with transactions and date ranges already posted, the configured default when omitted" Enums(off, flag, strip)
+// @Param Idempotency-Key header string false "Replays the stored response when a request is retried with the same key"
 // @Success 202 {object} domain.PostStatementResponse
-// @Failure 400 {object} exception.ErrorResponse
-// @Failure 500 {object} exception.ErrorResponse
+// @Failure 400 {object} exception.ProblemDetails
+// @Failure 409 {object} exception.ProblemDetails
+// @Failure 429 {object} exception.ProblemDetails
+// @Failure 500 {object} exception.ProblemDetails
 // @Router /statements [post]
 func (h *BankFeedHandler) PostStatements(c *fiber.Ctx) error {
 	var request domain.PostStatementRequest
 
 	// Parse the request body
 	if err := c.BodyParser(&request); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request format",
-		})
+		return exception.BadRequestError{Message: "Invalid request format"}
 	}
 
+	request.Dedup = c.Query("dedup")
+
 	// Call service layer to process statements
-	response, err := h.BankFeedService.PostStatements(c.Context(), request)
+	response, err := h.BankFeedService.PostStatements(c.UserContext(), request)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return err
 	}
 
 	return c.Status(fiber.StatusAccepted).JSON(response)
 }
 
+// @Summary Import a bank statement file
+// @Description Converts an OFX/QFX, QIF, CAMT.053, MT940 or CSV file into statements and posts them to the feed connection
+// @Tags Statements
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "Statement file"
+// @Param feedConnectionId formData string true "Feed Connection ID"
+// @Param format formData string false "File format, detected when omitted" Enums(ofx, qif, camt053, mt940, csv)
+// @Param profile formData string false "CSV mapping profile, required for CSV files"
+// @Param dateOrder formData string false "Order of ambiguous QIF dates" Enums(mdy, dmy)
+// @Param startBalance formData string false "Signed opening balance for files without balances (QIF, CSV)"
+// @Param dryRun formData bool false "Return the converted statements without posting them"
+// @Param dedup formData string false "What to do with transactions and date ranges already posted" Enums(off, flag, strip)
+// @Success 202 {object} domain.ImportStatementsResponse
+// @Success 200 {object} domain.ImportStatementsResponse
+// @Failure 400 {object} exception.ProblemDetails
+// @Failure 500 {object} exception.ProblemDetails
+// @Router /statements/import [post]
+func (h *BankFeedHandler) ImportStatements(c *fiber.Ctx) error {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return exception.BadRequestError{Message: "file is required"}
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+
+	request := domain.ImportStatementsRequest{
+		FeedConnectionID: c.FormValue("feedConnectionId"),
+		FileName:         fileHeader.Filename,
+		Data:             data,
+		Format:           c.FormValue("format"),
+		Profile:          c.FormValue("profile"),
+		DayFirst:         c.FormValue("dateOrder") == "dmy",
+		DryRun:           c.FormValue("dryRun") == "true",
+		Dedup:            c.FormValue("dedup"),
+	}
+
+	if value := c.FormValue("startBalance"); value != "" {
+		amount, err := money.Parse(value)
+		if err != nil {
+			return exception.BadRequestError{Message: "Invalid startBalance parameter"}
+		}
+		request.StartBalance = &domain.Balance{Amount: amount.Abs(), CreditDebitIndicator: "CREDIT"}
+		if amount.IsNegative() {
+			request.StartBalance.CreditDebitIndicator = "DEBIT"
+		}
+	}
+
+	response, err := h.BankFeedService.ImportStatements(c.UserContext(), request)
+	if err != nil {
+		return err
+	}
+
+	if request.DryRun {
+		return c.JSON(response)
+	}
+	return c.Status(fiber.StatusAccepted).JSON(response)
+}
+
 // @Summary Get all statements
-// @Description Retrieves a paginated list of statements
+// @Description Retrieves a paginated list of statements. Xero cannot filter statements, so filtering without source=local reads every page from Xero.
 // @Tags Statements
 // @Accept json
-// @Produce json
+// @Produce json,application/x-ndjson
 // @Param page query int false "Page number"
 // @Param pageSize query int false "Number of items per page"
+// @Param source query string false "Set to local to read the statements recorded by this service" Enums(xero, local)
+// @Param all query bool false "Stream every statement from Xero as NDJSON, one per line, instead of one page"
+// @Param feedConnectionId query string false "Only statements of this feed connection"
+// @Param status query string false "Only statements with this status" Enums(PENDING, DELIVERED, REJECTED, FAILED)
+// @Param startDate query string false "Only statements covering a day on or after this date (YYYY-MM-DD)"
+// @Param endDate query string false "Only statements covering a day on or before this date (YYYY-MM-DD)"
+// @Param minLines query int false "Only statements with at least this many lines"
+// @Param maxLines query int false "Only statements with at most this many lines"
 // @Success 200 {object} domain.GetStatementsResponse
-// @Failure 400 {object} exception.ErrorResponse
-// @Failure 500 {object} exception.ErrorResponse
+// @Failure 400 {object} exception.ProblemDetails
+// @Failure 500 {object} exception.ProblemDetails
 // @Router /statements [get]
 func (h *BankFeedHandler) GetStatements(c *fiber.Ctx) error {
+	filter, err := statementFilter(c)
+	if err != nil {
+		return err
+	}
+
+	if c.QueryBool("all") {
+		if fromLocalStore(c) {
+			return exception.BadRequestError{Message: "all is not supported with source=local"}
+		}
+		return streamNDJSON(c, h.BankFeedService.AllStatements(c.UserContext(), filter))
+	}
+
 	// Parse optional query parameters
 	page, err := strconv.Atoi(c.Query("page", "1"))
 	if err != nil || page < 1 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid page parameter",
-		})
+		return exception.BadRequestError{Message: "Invalid page parameter"}
 	}
 
 	pageSize, err := strconv.Atoi(c.Query("pageSize", "50"))
 	if err != nil || pageSize < 1 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid pageSize parameter",
-		})
+		return exception.BadRequestError{Message: "Invalid pageSize parameter"}
+	}
+
+	if fromLocalStore(c) {
+		response, err := h.BankFeedService.GetLocalStatements(c.UserContext(), filter, page, pageSize)
+		if err != nil {
+			return err
+		}
+		return c.JSON(response)
 	}
 
-	response, err := h.BankFeedService.GetStatements(c.Context(), page, pageSize)
+	response, err := h.BankFeedService.GetStatements(c.UserContext(), filter, page, pageSize)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+		return err
 	}
 
 	return c.JSON(response)
@@ -190,19 +437,141 @@ func (h *BankFeedHandler) GetStatements(c *fiber.Ctx) error {
 // @Produce json
 // @Param id path string true "Statement ID"
 // @Success 200 {object} domain.StatementResult
-// @Failure 404 {object} exception.ErrorResponse
-// @Failure 500 {object} exception.ErrorResponse
+// @Failure 404 {object} exception.ProblemDetails
+// @Failure 500 {object} exception.ProblemDetails
 // @Router /statements/{id} [get]
 func (h *BankFeedHandler) GetStatementByID(c *fiber.Ctx) error {
 	statementID := c.Params("id")
 
-	response, err := h.BankFeedService.GetStatementByID(c.Context(), statementID)
+	response, err := h.BankFeedService.GetStatementByID(c.UserContext(), statementID)
 	if err != nil {
-		if err.Error() == "statement not found" {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Statement not found"})
-		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return err
 	}
 
 	return c.JSON(response)
 }
+
+// @Summary Get the delivery status of a statement
+// @Description Returns the status recorded for a statement posted through this service. PENDING statements are re-checked in the background until Xero delivers or rejects them.
+// @Tags Statements
+// @Produce json
+// @Param id path string true "Statement ID"
+// @Success 200 {object} domain.StatementStatus
+// @Failure 404 {object} exception.ProblemDetails
+// @Failure 500 {object} exception.ProblemDetails
+// @Router /statements/{id}/status [get]
+func (h *BankFeedHandler) GetStatementStatus(c *fiber.Ctx) error {
+	statementID := c.Params("id")
+
+	response, err := h.BankFeedService.GetStatementStatus(c.UserContext(), statementID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response)
+}
+
+// fromLocalStore reports whether a list should be served from the local history
+// instead of Xero, which also covers items Xero has since purged
+func fromLocalStore(c *fiber.Ctx) bool {
+	return c.Query("source") == "local"
+}
+
+// statementFilter reads the statement list filters from the query
+func statementFilter(c *fiber.Ctx) (domain.StatementFilter, error) {
+	filter := domain.StatementFilter{
+		FeedConnectionID: c.Query("feedConnectionId"),
+		Status:           strings.ToUpper(c.Query("status")),
+		StartDate:        c.Query("startDate"),
+		EndDate:          c.Query("endDate"),
+	}
+
+	switch filter.Status {
+	case "", domain.StatementStatusPending, domain.StatementStatusDelivered, domain.StatementStatusRejected, domain.StatementStatusFailed:
+	default:
+		return filter, exception.BadRequestError{Message: "Invalid status parameter, expected PENDING, DELIVERED, REJECTED or FAILED"}
+	}
+	if !isDate(filter.StartDate) || !isDate(filter.EndDate) {
+		return filter, exception.BadRequestError{Message: "Invalid startDate or endDate parameter, expected YYYY-MM-DD"}
+	}
+	if filter.StartDate != "" && filter.EndDate != "" && filter.StartDate > filter.EndDate {
+		return filter, exception.BadRequestError{Message: "startDate must not be after endDate"}
+	}
+
+	var err error
+	filter.MinLines, err = strconv.Atoi(c.Query("minLines", "0"))
+	if err != nil || filter.MinLines < 0 {
+		return filter, exception.BadRequestError{Message: "Invalid minLines parameter"}
+	}
+	filter.MaxLines, err = strconv.Atoi(c.Query("maxLines", "0"))
+	if err != nil || filter.MaxLines < 0 {
+		return filter, exception.BadRequestError{Message: "Invalid maxLines parameter"}
+	}
+	if filter.MaxLines > 0 && filter.MinLines > filter.MaxLines {
+		return filter, exception.BadRequestError{Message: "minLines must not be more than maxLines"}
+	}
+	return filter, nil
+}
+
+// isDate accepts empty values and ISO dates
+func isDate(value string) bool {
+	_, err := time.Parse(time.DateOnly, value)
+	return value == "" || err == nil
+}
+
+// @Summary Create a CSV import profile
+// @Description Stores a column mapping used to import CSV statement files, replacing any stored profile with the same name
+// @Tags ImportProfiles
+// @Accept json
+// @Produce json
+// @Param profile body domain.CSVProfile true "CSV profile"
+// @Success 201 {object} domain.CSVProfile
+// @Failure 400 {object} exception.ProblemDetails
+// @Failure 500 {object} exception.ProblemDetails
+// @Router /import-profiles [post]
+func (h *BankFeedHandler) CreateImportProfile(c *fiber.Ctx) error {
+	var profile domain.CSVProfile
+	if err := c.BodyParser(&profile); err != nil {
+		return exception.BadRequestError{Message: "Invalid request format"}
+	}
+
+	response, err := h.BankFeedService.CreateImportProfile(c.UserContext(), profile)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(response)
+}
+
+// @Summary Get CSV import profiles
+// @Description Lists the CSV import profiles from the configuration file and the database
+// @Tags ImportProfiles
+// @Produce json
+// @Success 200 {array} domain.CSVProfile
+// @Failure 500 {object} exception.ProblemDetails
+// @Router /import-profiles [get]
+func (h *BankFeedHandler) GetImportProfiles(c *fiber.Ctx) error {
+	response, err := h.BankFeedService.GetImportProfiles(c.UserContext())
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response)
+}
+
+// @Summary Delete a CSV import profile
+// @Description Deletes a stored CSV import profile; profiles from the configuration file cannot be deleted
+// @Tags ImportProfiles
+// @Param name path string true "Profile name"
+// @Success 204
+// @Failure 404 {object} exception.ProblemDetails
+// @Failure 500 {object} exception.ProblemDetails
+// @Router /import-profiles/{name} [delete]
+func (h *BankFeedHandler) DeleteImportProfile(c *fiber.Ctx) error {
+	err := h.BankFeedService.DeleteImportProfile(c.UserContext(), c.Params("name"))
+	if err != nil {
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
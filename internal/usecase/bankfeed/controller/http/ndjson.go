@@ -0,0 +1,48 @@
+package http
+
+import (
+	"bufio"
+	"encoding/json"
+	"iter"
+
+	"github.com/gofiber/fiber/v2"
+	"usdw/pkg/common/exception"
+)
+
+const mimeNDJSON = "application/x-ndjson"
+
+// streamError is the last line of a stream that failed after the response started
+type streamError struct {
+	Error *exception.ProblemDetails `json:"error"`
+}
+
+// streamNDJSON writes every item as one JSON line, flushing as they arrive. An error
+// before the first item is returned as usual; once the response has started it can only
+// be reported as a final {"error": ...} line.
+func streamNDJSON[T any](c *fiber.Ctx, items iter.Seq2[T, error]) error {
+	next, stop := iter.Pull2(items)
+	first, err, ok := next()
+	if err != nil {
+		stop()
+		return err
+	}
+
+	c.Set(fiber.HeaderContentType, mimeNDJSON)
+	c.Status(fiber.StatusOK).Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer stop()
+
+		encoder := json.NewEncoder(w)
+		for item := first; ok; item, err, ok = next() {
+			if err != nil {
+				_ = encoder.Encode(streamError{Error: exception.NewProblemDetails(err)})
+				_ = w.Flush()
+				return
+			}
+			// Stop when the client has gone away
+			if encoder.Encode(item) != nil || w.Flush() != nil {
+				return
+			}
+		}
+	})
+	return nil
+}
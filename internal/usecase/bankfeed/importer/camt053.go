@@ -0,0 +1,207 @@
+package importer
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"usdw/internal/domain"
+)
+
+// camtDocument covers the parts of ISO 20022 camt.053 (versions .02 to .08) that map
+// onto a statement. Element names are matched without their namespace.
+type camtDocument struct {
+	Statements []camtStatement `xml:"BkToCstmrStmt>Stmt"`
+}
+
+type camtStatement struct {
+	ID       string        `xml:"Id"`
+	FromDate camtDateTime  `xml:"FrToDt>FrDtTm"`
+	ToDate   camtDateTime  `xml:"FrToDt>ToDtTm"`
+	Balances []camtBalance `xml:"Bal"`
+	Entries  []camtEntry   `xml:"Ntry"`
+}
+
+type camtBalance struct {
+	Type                 string     `xml:"Tp>CdOrPrtry>Cd"`
+	Amount               camtAmount `xml:"Amt"`
+	CreditDebitIndicator string     `xml:"CdtDbtInd"`
+	Date                 camtDate   `xml:"Dt"`
+}
+
+type camtEntry struct {
+	Reference            string     `xml:"NtryRef"`
+	Amount               camtAmount `xml:"Amt"`
+	CreditDebitIndicator string     `xml:"CdtDbtInd"`
+	Status               camtStatus `xml:"Sts"`
+	BookingDate          camtDate   `xml:"BookgDt"`
+	ServicerReference    string     `xml:"AcctSvcrRef"`
+	BankTransactionCode  string     `xml:"BkTxCd>Prtry>Cd"`
+	Details              []camtTx   `xml:"NtryDtls>TxDtls"`
+	AdditionalInfo       string     `xml:"AddtlNtryInf"`
+}
+
+type camtTx struct {
+	EndToEndID        string   `xml:"Refs>EndToEndId"`
+	TransactionID     string   `xml:"Refs>TxId"`
+	ServicerReference string   `xml:"Refs>AcctSvcrRef"`
+	DebtorName        string   `xml:"RltdPties>Dbtr>Nm"`
+	DebtorPartyName   string   `xml:"RltdPties>Dbtr>Pty>Nm"`
+	CreditorName      string   `xml:"RltdPties>Cdtr>Nm"`
+	CreditorPartyName string   `xml:"RltdPties>Cdtr>Pty>Nm"`
+	Unstructured      []string `xml:"RmtInf>Ustrd"`
+	CreditorReference string   `xml:"RmtInf>Strd>CdtrRefInf>Ref"`
+	ChequeNumber      string   `xml:"Refs>ChqNb"`
+	AdditionalInfo    string   `xml:"AddtlTxInf"`
+}
+
+type camtAmount struct {
+	Currency string `xml:"Ccy,attr"`
+	Value    string `xml:",chardata"`
+}
+
+// camtStatus is plain text up to version .06 and a <Cd> element since
+type camtStatus struct {
+	Code string `xml:"Cd"`
+	Text string `xml:",chardata"`
+}
+
+type camtDate struct {
+	Date     string `xml:"Dt"`
+	DateTime string `xml:"DtTm"`
+}
+
+type camtDateTime string
+
+func (d camtDate) value() string {
+	if d.Date != "" {
+		return d.Date
+	}
+	return isoDate(d.DateTime)
+}
+
+// isoDate keeps the date of an ISO 8601 date or datetime
+func isoDate(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) >= 10 {
+		return value[:10]
+	}
+	return value
+}
+
+func parseCAMT053(data []byte) ([]domain.PostStatementItem, error) {
+	var document camtDocument
+	err := xml.Unmarshal(data, &document)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]domain.PostStatementItem, 0, len(document.Statements))
+	for i, statement := range document.Statements {
+		item, err := camtItem(statement)
+		if err != nil {
+			return nil, fmt.Errorf("statement %d (%s): %w", i+1, statement.ID, err)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func camtItem(statement camtStatement) (domain.PostStatementItem, error) {
+	item := domain.PostStatementItem{
+		StartDate: isoDate(string(statement.FromDate)),
+		EndDate:   isoDate(string(statement.ToDate)),
+	}
+
+	hasStart, hasEnd := false, false
+	for _, balance := range statement.Balances {
+		amount, err := parseAmount(balance.Amount.Value)
+		if err != nil {
+			return item, err
+		}
+		switch balance.Type {
+		// Opening booked, or the previous statement's closing balance
+		case "OPBD", "PRCD":
+			if !hasStart {
+				item.StartBalance = domain.Balance{Amount: amount, CreditDebitIndicator: camtIndicator(balance.CreditDebitIndicator)}
+				hasStart = true
+			}
+		case "CLBD":
+			item.EndBalance = domain.Balance{Amount: amount, CreditDebitIndicator: camtIndicator(balance.CreditDebitIndicator)}
+			hasEnd = true
+			if item.EndDate == "" {
+				item.EndDate = balance.Date.value()
+			}
+		}
+	}
+
+	for i, entry := range statement.Entries {
+		status := strings.TrimSpace(entry.Status.Code + entry.Status.Text)
+		if status != "" && status != "BOOK" {
+			continue
+		}
+
+		amount, err := parseAmount(entry.Amount.Value)
+		if err != nil {
+			return item, fmt.Errorf("entry %d: %w", i+1, err)
+		}
+
+		line := domain.StatementLine{
+			PostedDate:           entry.BookingDate.value(),
+			Amount:               amount,
+			CreditDebitIndicator: camtIndicator(entry.CreditDebitIndicator),
+			TransactionID:        firstNonEmpty(entry.ServicerReference, entry.Reference),
+			TransactionType:      entry.BankTransactionCode,
+			Description:          entry.AdditionalInfo,
+		}
+
+		if len(entry.Details) > 0 {
+			tx := entry.Details[0]
+			// The counterparty is the creditor of a payment out and the debtor of a payment in
+			if line.CreditDebitIndicator == indicatorDebit {
+				line.PayeeName = firstNonEmpty(tx.CreditorName, tx.CreditorPartyName)
+			} else {
+				line.PayeeName = firstNonEmpty(tx.DebtorName, tx.DebtorPartyName)
+			}
+			line.Reference = firstNonEmpty(tx.CreditorReference, notProvided(tx.EndToEndID))
+			line.ChequeNumber = tx.ChequeNumber
+			line.TransactionID = firstNonEmpty(line.TransactionID, tx.ServicerReference, notProvided(tx.TransactionID))
+			line.Description = firstNonEmpty(strings.Join(tx.Unstructured, " "), tx.AdditionalInfo, line.Description)
+		}
+		if line.Description == "" {
+			line.Description = firstNonEmpty(line.PayeeName, line.Reference, line.TransactionType)
+		}
+		if line.TransactionID == "" {
+			line.TransactionID = transactionID("camt", statement.ID, strconv.Itoa(i), line.PostedDate, amount.String())
+		}
+		item.StatementLines = append(item.StatementLines, line)
+	}
+
+	completeItem(&item, hasStart, hasEnd)
+	return item, nil
+}
+
+// camtIndicator maps CRDT/DBIT onto Xero's CREDIT/DEBIT
+func camtIndicator(value string) string {
+	if strings.TrimSpace(value) == "DBIT" {
+		return indicatorDebit
+	}
+	return indicatorCredit
+}
+
+// notProvided drops the NOTPROVIDED placeholder banks put in mandatory references
+func notProvided(value string) string {
+	if value == "NOTPROVIDED" {
+		return ""
+	}
+	return value
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,361 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+	"usdw/internal/domain"
+	"usdw/pkg/money"
+)
+
+const FormatCSV Format = "csv"
+
+var (
+	ErrProfileRequired = errors.New("a CSV profile is required")
+	ErrInvalidProfile  = errors.New("invalid CSV profile")
+)
+
+var dateFormatReplacer = strings.NewReplacer(
+	"YYYY", "2006", "YY", "06",
+	"MMM", "Jan", "MM", "01", "M", "1",
+	"DD", "02", "D", "2",
+)
+
+// LoadCSVProfiles reads the JSON array of profiles configured in IMPORT_CSV_PROFILES_FILE
+func LoadCSVProfiles(path string) ([]domain.CSVProfile, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var profiles []domain.CSVProfile
+	err = json.Unmarshal(data, &profiles)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	for i := range profiles {
+		err = ValidateCSVProfile(profiles[i])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		profiles[i].Source = "config"
+	}
+	return profiles, nil
+}
+
+// ValidateCSVProfile checks that a profile describes enough columns to build statement lines
+func ValidateCSVProfile(profile domain.CSVProfile) error {
+	columns := profile.Columns
+	switch {
+	case strings.TrimSpace(profile.Name) == "":
+		return fmt.Errorf("%w: name is required", ErrInvalidProfile)
+	case utf8.RuneCountInString(profile.Delimiter) > 1:
+		return fmt.Errorf("%w %s: delimiter must be a single character", ErrInvalidProfile, profile.Name)
+	case columns.Date == "":
+		return fmt.Errorf("%w %s: columns.date is required", ErrInvalidProfile, profile.Name)
+	case columns.Amount == "" && (columns.Debit == "" || columns.Credit == ""):
+		return fmt.Errorf("%w %s: columns.amount or both columns.debit and columns.credit are required", ErrInvalidProfile, profile.Name)
+	}
+
+	if !profile.HasHeader {
+		for _, column := range []string{columns.Date, columns.Description, columns.Amount, columns.Debit, columns.Credit, columns.Indicator,
+			columns.TransactionID, columns.PayeeName, columns.Reference, columns.ChequeNumber, columns.TransactionType, columns.Balance} {
+			if number, err := strconv.Atoi(column); column != "" && (err != nil || number < 1) {
+				return fmt.Errorf("%w %s: column %q must be a column number when the file has no header", ErrInvalidProfile, profile.Name, column)
+			}
+		}
+	}
+	return nil
+}
+
+// csvRow gives access to the columns of one record by profile column reference
+type csvRow struct {
+	record  []string
+	indexes map[string]int
+}
+
+func (r csvRow) get(column string) string {
+	if column == "" {
+		return ""
+	}
+	index, ok := r.indexes[column]
+	if !ok || index >= len(r.record) {
+		return ""
+	}
+	return strings.TrimSpace(r.record[index])
+}
+
+func parseCSV(data []byte, options Options) ([]domain.PostStatementItem, error) {
+	profile := options.Profile
+	if profile == nil {
+		return nil, ErrProfileRequired
+	}
+
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	if profile.Delimiter != "" {
+		reader.Comma, _ = utf8.DecodeRuneInString(profile.Delimiter)
+	}
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if profile.SkipRows > len(records) {
+		return nil, nil
+	}
+	records = records[profile.SkipRows:]
+
+	indexes, records, err := csvColumnIndexes(profile, records)
+	if err != nil {
+		return nil, err
+	}
+	if profile.NewestFirst {
+		slices.Reverse(records)
+	}
+
+	dateLayout := "2006-01-02"
+	if profile.DateFormat != "" {
+		dateLayout = dateFormatReplacer.Replace(profile.DateFormat)
+	}
+
+	item := domain.PostStatementItem{StartBalance: domain.Balance{CreditDebitIndicator: indicatorCredit}}
+	hasStart := false
+	if options.StartBalance != nil {
+		item.StartBalance = *options.StartBalance
+		hasStart = true
+	}
+
+	seen := make(map[string]int)
+	var lastBalance *money.Amount
+	for i, record := range records {
+		row := csvRow{record: record, indexes: indexes}
+		rowNumber := profile.SkipRows + i + 1
+		if profile.HasHeader {
+			rowNumber++
+		}
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+
+		line, err := csvLine(profile, row, dateLayout)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", rowNumber, err)
+		}
+
+		if line.TransactionID == "" {
+			key := strings.Join(record, "\x1f")
+			seen[key]++
+			line.TransactionID = transactionID("csv", profile.Name, key, strconv.Itoa(seen[key]))
+		}
+
+		if profile.Columns.Balance != "" {
+			value := row.get(profile.Columns.Balance)
+			if value == "" {
+				return nil, fmt.Errorf("row %d: balance is empty", rowNumber)
+			}
+			balance, err := csvAmount(value, profile.DecimalComma)
+			if err != nil {
+				return nil, fmt.Errorf("row %d: balance: %w", rowNumber, err)
+			}
+			// The balance before the first row is its running balance less its amount
+			if lastBalance == nil {
+				start := balance.Sub(signed(line.Amount, line.CreditDebitIndicator))
+				item.StartBalance.Amount, item.StartBalance.CreditDebitIndicator = fromSigned(start)
+				hasStart = true
+			}
+			lastBalance = &balance
+		}
+
+		item.StatementLines = append(item.StatementLines, line)
+	}
+	if len(item.StatementLines) == 0 {
+		return nil, nil
+	}
+
+	if lastBalance != nil {
+		item.EndBalance.Amount, item.EndBalance.CreditDebitIndicator = fromSigned(*lastBalance)
+		completeItem(&item, true, true)
+	} else {
+		completeItem(&item, hasStart, false)
+	}
+	return []domain.PostStatementItem{item}, nil
+}
+
+// csvColumnIndexes resolves profile column references to record indexes and drops the header
+func csvColumnIndexes(profile *domain.CSVProfile, records [][]string) (map[string]int, [][]string, error) {
+	indexes := make(map[string]int)
+	if !profile.HasHeader {
+		for _, record := range records {
+			for i := range record {
+				indexes[strconv.Itoa(i+1)] = i
+			}
+		}
+		if len(records) == 0 {
+			return indexes, records, nil
+		}
+		err := checkCSVColumns(profile, indexes)
+		if err != nil {
+			return nil, nil, err
+		}
+		return indexes, records, nil
+	}
+
+	if len(records) == 0 {
+		return nil, nil, errors.New("header row is missing")
+	}
+	for i, name := range records[0] {
+		indexes[strings.TrimSpace(name)] = i
+	}
+
+	err := checkCSVColumns(profile, indexes)
+	if err != nil {
+		return nil, nil, err
+	}
+	return indexes, records[1:], nil
+}
+
+// checkCSVColumns fails when a column the profile maps is not in the file, rather than
+// reading it as empty cells
+func checkCSVColumns(profile *domain.CSVProfile, indexes map[string]int) error {
+	columns := profile.Columns
+	for _, column := range []string{columns.Date, columns.Description, columns.Amount, columns.Debit, columns.Credit, columns.Indicator,
+		columns.TransactionID, columns.PayeeName, columns.Reference, columns.ChequeNumber, columns.TransactionType, columns.Balance} {
+		if _, ok := indexes[column]; column != "" && !ok {
+			if profile.HasHeader {
+				return fmt.Errorf("column %q not found in header", column)
+			}
+			return fmt.Errorf("column %s not found, the widest row has %d columns", column, len(indexes))
+		}
+	}
+	return nil
+}
+
+func csvLine(profile *domain.CSVProfile, row csvRow, dateLayout string) (domain.StatementLine, error) {
+	columns := profile.Columns
+
+	date, err := time.Parse(dateLayout, row.get(columns.Date))
+	if err != nil {
+		return domain.StatementLine{}, fmt.Errorf("date %q does not match %s", row.get(columns.Date), profile.DateFormat)
+	}
+
+	var amount money.Amount
+	if columns.Amount != "" {
+		amount, err = csvAmount(row.get(columns.Amount), profile.DecimalComma)
+		if err != nil {
+			return domain.StatementLine{}, err
+		}
+		if profile.DebitPositive {
+			amount = amount.Neg()
+		}
+	} else {
+		debit, err := csvAmount(row.get(columns.Debit), profile.DecimalComma)
+		if err != nil {
+			return domain.StatementLine{}, fmt.Errorf("debit: %w", err)
+		}
+		credit, err := csvAmount(row.get(columns.Credit), profile.DecimalComma)
+		if err != nil {
+			return domain.StatementLine{}, fmt.Errorf("credit: %w", err)
+		}
+		amount = credit.Abs().Sub(debit.Abs())
+	}
+
+	line := domain.StatementLine{
+		PostedDate:      date.Format(isoDateLayout),
+		Description:     row.get(columns.Description),
+		TransactionID:   row.get(columns.TransactionID),
+		PayeeName:       row.get(columns.PayeeName),
+		Reference:       row.get(columns.Reference),
+		ChequeNumber:    row.get(columns.ChequeNumber),
+		TransactionType: row.get(columns.TransactionType),
+	}
+	line.Amount, line.CreditDebitIndicator = fromSigned(amount)
+
+	if columns.Indicator != "" {
+		indicator, err := csvIndicator(profile, row.get(columns.Indicator))
+		if err != nil {
+			return domain.StatementLine{}, err
+		}
+		line.CreditDebitIndicator = indicator
+	}
+	if line.Description == "" {
+		line.Description = firstNonEmpty(line.PayeeName, line.Reference, line.TransactionType)
+	}
+	return line, nil
+}
+
+func csvIndicator(profile *domain.CSVProfile, value string) (string, error) {
+	credits, debits := profile.CreditValues, profile.DebitValues
+	if len(credits) == 0 {
+		credits = []string{"CR", "C", "CREDIT"}
+	}
+	if len(debits) == 0 {
+		debits = []string{"DR", "D", "DEBIT"}
+	}
+
+	for _, credit := range credits {
+		if strings.EqualFold(value, credit) {
+			return indicatorCredit, nil
+		}
+	}
+	for _, debit := range debits {
+		if strings.EqualFold(value, debit) {
+			return indicatorDebit, nil
+		}
+	}
+	return "", fmt.Errorf("unknown credit/debit indicator %q", value)
+}
+
+// csvAmount reads amounts as banks print them, e.g. "$1,234.50", "(12.00)" or "-1.234,56".
+// The other separator than the decimal one is only accepted between groups of three digits,
+// so "1,5" is not read as 15. An empty cell is zero.
+func csvAmount(value string, decimalComma bool) (money.Amount, error) {
+	value = strings.TrimSpace(value)
+	negative := strings.HasPrefix(value, "(") && strings.HasSuffix(value, ")")
+
+	digits := strings.Map(func(r rune) rune {
+		if (r >= '0' && r <= '9') || r == '.' || r == ',' || r == '-' {
+			return r
+		}
+		return -1
+	}, value)
+	if digits == "" {
+		return money.Amount{}, nil
+	}
+
+	decimal, group := ".", ","
+	if decimalComma {
+		decimal, group = ",", "."
+	}
+	intPart, fracPart, hasFraction := strings.Cut(digits, decimal)
+	if strings.Contains(fracPart, group) || !thousandsGrouped(strings.TrimLeft(intPart, "-"), group) {
+		return money.Amount{}, fmt.Errorf("%w: ambiguous separators in %q", money.ErrInvalidAmount, value)
+	}
+	digits = strings.ReplaceAll(intPart, group, "")
+	if hasFraction {
+		digits += "." + fracPart
+	}
+
+	amount, err := money.Parse(digits)
+	if err != nil {
+		return amount, err
+	}
+	if negative {
+		amount = amount.Neg()
+	}
+	return amount, nil
+}
@@ -0,0 +1,188 @@
+// Package importer turns bank statement files into statements ready to post to Xero.
+package importer
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"usdw/internal/domain"
+	"usdw/pkg/money"
+)
+
+type Format string
+
+const (
+	FormatOFX     Format = "ofx"
+	FormatQIF     Format = "qif"
+	FormatCAMT053 Format = "camt053"
+	FormatMT940   Format = "mt940"
+)
+
+const (
+	isoDateLayout   = "2006-01-02"
+	indicatorCredit = "CREDIT"
+	indicatorDebit  = "DEBIT"
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown statement file format")
+	ErrNoStatements  = errors.New("file contains no statements")
+)
+
+// Options tune parsers for files that do not describe themselves fully
+type Options struct {
+	// DayFirst reads ambiguous QIF dates as DD/MM/YYYY instead of MM/DD/YYYY
+	DayFirst bool
+	// StartBalance is used by formats without balances (QIF, CSV); zero when nil
+	StartBalance *domain.Balance
+	// Profile maps the columns of a CSV file
+	Profile *domain.CSVProfile
+}
+
+// Parse reads every statement in data. FeedConnectionID is left empty for the caller to fill in.
+func Parse(format Format, data []byte, options Options) ([]domain.PostStatementItem, error) {
+	var (
+		items []domain.PostStatementItem
+		err   error
+	)
+	switch format {
+	case FormatOFX:
+		items, err = parseOFX(data)
+	case FormatQIF:
+		items, err = parseQIF(data, options)
+	case FormatCAMT053:
+		items, err = parseCAMT053(data)
+	case FormatMT940:
+		items, err = parseMT940(data)
+	case FormatCSV:
+		items, err = parseCSV(data, options)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s file: %w", format, err)
+	}
+	if len(items) == 0 {
+		return nil, ErrNoStatements
+	}
+	return items, nil
+}
+
+// DetectFormat guesses the format from the file name and, failing that, the content
+func DetectFormat(fileName string, data []byte) (Format, error) {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".ofx", ".qfx":
+		return FormatOFX, nil
+	case ".qif":
+		return FormatQIF, nil
+	case ".sta", ".mt940", ".940":
+		return FormatMT940, nil
+	case ".csv":
+		return FormatCSV, nil
+	}
+
+	head := data
+	if len(head) > 4096 {
+		head = head[:4096]
+	}
+	switch {
+	case bytes.Contains(head, []byte("OFXHEADER")) || bytes.Contains(head, []byte("<OFX>")):
+		return FormatOFX, nil
+	case bytes.Contains(head, []byte("camt.053")) || bytes.Contains(head, []byte("BkToCstmrStmt")):
+		return FormatCAMT053, nil
+	case bytes.HasPrefix(bytes.TrimSpace(head), []byte("!Type:")) || bytes.HasPrefix(bytes.TrimSpace(head), []byte("!Account")):
+		return FormatQIF, nil
+	case bytes.Contains(head, []byte(":20:")) && bytes.Contains(head, []byte(":60")):
+		return FormatMT940, nil
+	}
+	return "", ErrUnknownFormat
+}
+
+// fromSigned splits a signed amount into the positive amount and credit/debit indicator Xero expects
+func fromSigned(amount money.Amount) (money.Amount, string) {
+	if amount.IsNegative() {
+		return amount.Neg(), indicatorDebit
+	}
+	return amount, indicatorCredit
+}
+
+func signed(amount money.Amount, indicator string) money.Amount {
+	if indicator == indicatorDebit {
+		return amount.Neg()
+	}
+	return amount
+}
+
+// parseAmount reads a dot decimal amount as QIF, OFX and CAMT.053 files carry them.
+// Commas may only group thousands, e.g. "1,234.56"; anything else, like "1,5" or
+// "1.234,5", is ambiguous and rejected rather than guessed.
+func parseAmount(value string) (money.Amount, error) {
+	value = strings.TrimSpace(value)
+	intPart, fracPart, _ := strings.Cut(value, ".")
+	if strings.Contains(fracPart, ",") || !thousandsGrouped(strings.TrimLeft(intPart, "+-"), ",") {
+		return money.Amount{}, fmt.Errorf("%w: ambiguous separators in %q", money.ErrInvalidAmount, value)
+	}
+	return money.Parse(strings.ReplaceAll(value, ",", ""))
+}
+
+// thousandsGrouped reports whether the separators of an integer split it into groups of three digits
+func thousandsGrouped(value, separator string) bool {
+	groups := strings.Split(value, separator)
+	if len(groups) == 1 {
+		return true
+	}
+	if len(groups[0]) == 0 || len(groups[0]) > 3 {
+		return false
+	}
+	for _, group := range groups[1:] {
+		if len(group) != 3 {
+			return false
+		}
+	}
+	return true
+}
+
+// transactionID derives a stable ID for lines the bank did not give one, so a
+// re-imported file produces the same IDs
+func transactionID(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "|")))
+	return hex.EncodeToString(sum[:10])
+}
+
+// completeItem fills in whichever of the balances and dates the file did not provide
+// and widens the date range to cover every line
+func completeItem(item *domain.PostStatementItem, hasStart, hasEnd bool) {
+	total := money.Amount{}
+	for _, line := range item.StatementLines {
+		total = total.Add(signed(line.Amount, line.CreditDebitIndicator))
+
+		if item.StartDate == "" || line.PostedDate < item.StartDate {
+			item.StartDate = line.PostedDate
+		}
+		if item.EndDate == "" || line.PostedDate > item.EndDate {
+			item.EndDate = line.PostedDate
+		}
+	}
+	if item.StartDate == "" {
+		item.StartDate = item.EndDate
+	}
+	if item.EndDate == "" {
+		item.EndDate = item.StartDate
+	}
+
+	switch {
+	case hasEnd && !hasStart:
+		end := signed(item.EndBalance.Amount, item.EndBalance.CreditDebitIndicator)
+		item.StartBalance.Amount, item.StartBalance.CreditDebitIndicator = fromSigned(end.Sub(total))
+	case hasStart && !hasEnd:
+		start := signed(item.StartBalance.Amount, item.StartBalance.CreditDebitIndicator)
+		item.EndBalance.Amount, item.EndBalance.CreditDebitIndicator = fromSigned(start.Add(total))
+	case !hasStart && !hasEnd:
+		item.StartBalance = domain.Balance{CreditDebitIndicator: indicatorCredit}
+		item.EndBalance.Amount, item.EndBalance.CreditDebitIndicator = fromSigned(total)
+	}
+}
@@ -0,0 +1,373 @@
+package importer
+
+import (
+	"os"
+	"testing"
+	"usdw/internal/domain"
+	"usdw/pkg/money"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readFile(t *testing.T, name string) []byte {
+	data, err := os.ReadFile("testdata/" + name)
+	require.NoError(t, err)
+	return data
+}
+
+// assertBalanced checks the statement adds up the way the service validates it
+func assertBalanced(t *testing.T, item domain.PostStatementItem) {
+	total := signed(item.StartBalance.Amount, item.StartBalance.CreditDebitIndicator)
+	for _, line := range item.StatementLines {
+		assert.False(t, line.Amount.IsNegative())
+		assert.NotEmpty(t, line.TransactionID)
+		assert.NotEmpty(t, line.Description)
+		assert.GreaterOrEqual(t, line.PostedDate, item.StartDate)
+		assert.LessOrEqual(t, line.PostedDate, item.EndDate)
+		total = total.Add(signed(line.Amount, line.CreditDebitIndicator))
+	}
+	assert.Equal(t, signed(item.EndBalance.Amount, item.EndBalance.CreditDebitIndicator).String(), total.String())
+}
+
+func TestParseOFX(t *testing.T) {
+	items, err := Parse(FormatOFX, readFile(t, "statement.ofx"), Options{})
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+
+	item := items[0]
+	assertBalanced(t, item)
+	assert.Equal(t, "2024-01-01", item.StartDate)
+	assert.Equal(t, "2024-01-31", item.EndDate)
+	assert.Equal(t, domain.Balance{Amount: money.MustParse("1000"), CreditDebitIndicator: "CREDIT"}, item.StartBalance)
+	assert.Equal(t, domain.Balance{Amount: money.MustParse("2249.75"), CreditDebitIndicator: "CREDIT"}, item.EndBalance)
+
+	require.Len(t, item.StatementLines, 2)
+	assert.Equal(t, domain.StatementLine{
+		PostedDate:           "2024-01-05",
+		Description:          "Salary January",
+		Amount:               money.MustParse("1500"),
+		CreditDebitIndicator: "CREDIT",
+		TransactionID:        "2024010501",
+		PayeeName:            "ACME PAYROLL",
+		TransactionType:      "CREDIT",
+	}, item.StatementLines[0])
+	assert.Equal(t, domain.StatementLine{
+		PostedDate:           "2024-01-10",
+		Description:          "Landlord & Co",
+		Amount:               money.MustParse("250.25"),
+		CreditDebitIndicator: "DEBIT",
+		TransactionID:        "2024011001",
+		PayeeName:            "Landlord & Co",
+		Reference:            "INV-77",
+		ChequeNumber:         "1042",
+		TransactionType:      "CHECK",
+	}, item.StatementLines[1])
+}
+
+func TestParseQIF(t *testing.T) {
+	items, err := Parse(FormatQIF, readFile(t, "statement.qif"), Options{})
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+
+	item := items[0]
+	assertBalanced(t, item)
+	assert.Equal(t, "2024-01-01", item.StartDate)
+	assert.Equal(t, "2024-01-20", item.EndDate)
+	assert.Equal(t, "1000", item.StartBalance.Amount.String())
+	assert.Equal(t, "1215", item.EndBalance.Amount.String())
+
+	require.Len(t, item.StatementLines, 3)
+	first, second := item.StatementLines[0], item.StatementLines[1]
+	assert.Equal(t, "Coffee Roasters", first.PayeeName)
+	assert.Equal(t, "Beans", first.Description)
+	assert.Equal(t, "1043", first.ChequeNumber)
+	assert.Equal(t, "DEBIT", first.CreditDebitIndicator)
+	assert.NotEqual(t, first.TransactionID, second.TransactionID, "identical lines get distinct IDs")
+	assert.Equal(t, "REF-9", item.StatementLines[2].Reference)
+
+	// IDs are stable across imports
+	again, err := Parse(FormatQIF, readFile(t, "statement.qif"), Options{})
+	require.NoError(t, err)
+	assert.Equal(t, first.TransactionID, again[0].StatementLines[0].TransactionID)
+
+	// An explicit start balance replaces the opening balance entry
+	start := domain.Balance{Amount: money.MustParse("10"), CreditDebitIndicator: "DEBIT"}
+	items, err = Parse(FormatQIF, readFile(t, "statement.qif"), Options{StartBalance: &start})
+	require.NoError(t, err)
+	assertBalanced(t, items[0])
+	assert.Equal(t, start, items[0].StartBalance)
+}
+
+func TestQIFDate(t *testing.T) {
+	tests := []struct {
+		value    string
+		dayFirst bool
+		want     string
+	}{
+		{"1/31/2024", false, "2024-01-31"},
+		{"01/31'24", false, "2024-01-31"},
+		{" 1/ 2/24", false, "2024-01-02"},
+		{"31.01.2024", true, "2024-01-31"},
+		{"2024-01-31", false, "2024-01-31"},
+	}
+	for _, tt := range tests {
+		got, err := qifDate(tt.value, tt.dayFirst)
+		require.NoError(t, err, tt.value)
+		assert.Equal(t, tt.want, got, tt.value)
+	}
+
+	_, err := qifDate("31/01/2024", false)
+	assert.Error(t, err)
+}
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"1500.00", "1500"},
+		{"-250.25", "-250.25"},
+		{"1,500", "1500"},
+		{"1,234.56", "1234.56"},
+		{"-1,234,567.5", "-1234567.5"},
+		{"1.234,5", ""},
+		{"1,5", ""},
+		{"1234,567.00", ""},
+		{",500", ""},
+	}
+	for _, tt := range tests {
+		got, err := parseAmount(tt.value)
+		if tt.want == "" {
+			assert.ErrorIs(t, err, money.ErrInvalidAmount, tt.value)
+			continue
+		}
+		require.NoError(t, err, tt.value)
+		assert.Equal(t, tt.want, got.String(), tt.value)
+	}
+}
+
+func TestMT940Amount(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"1000,00", "1000"},
+		{"899,", "899"},
+		{"1,5", "1.5"},
+		{"1,500", "1.5"},
+		{"1500", ""},
+		{"1,234,5", ""},
+	}
+	for _, tt := range tests {
+		got, err := mt940Amount(tt.value)
+		if tt.want == "" {
+			assert.ErrorIs(t, err, money.ErrInvalidAmount, tt.value)
+			continue
+		}
+		require.NoError(t, err, tt.value)
+		assert.Equal(t, tt.want, got.String(), tt.value)
+	}
+}
+
+func TestParseCAMT053(t *testing.T) {
+	items, err := Parse(FormatCAMT053, readFile(t, "statement.camt053.xml"), Options{})
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+
+	item := items[0]
+	assertBalanced(t, item)
+	assert.Equal(t, "2024-01-01", item.StartDate)
+	assert.Equal(t, "2024-01-31", item.EndDate)
+
+	require.Len(t, item.StatementLines, 2, "pending entries are skipped")
+	assert.Equal(t, domain.StatementLine{
+		PostedDate:           "2024-01-03",
+		Description:          "Invoice 42",
+		Amount:               money.MustParse("200"),
+		CreditDebitIndicator: "DEBIT",
+		TransactionID:        "SVC-001",
+		PayeeName:            "Utility GmbH",
+		Reference:            "E2E-42",
+	}, item.StatementLines[0])
+	assert.Equal(t, "2024-01-15", item.StatementLines[1].PostedDate)
+	assert.Equal(t, "TX-80", item.StatementLines[1].TransactionID)
+	assert.Equal(t, "Jane Customer", item.StatementLines[1].PayeeName)
+	assert.Empty(t, item.StatementLines[1].Reference)
+}
+
+func TestParseMT940(t *testing.T) {
+	items, err := Parse(FormatMT940, readFile(t, "statement.sta"), Options{})
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+
+	item := items[0]
+	assertBalanced(t, item)
+	assert.Equal(t, "2024-01-02", item.StartDate)
+	assert.Equal(t, "2024-01-31", item.EndDate)
+	assert.Equal(t, "1000", item.StartBalance.Amount.String())
+	assert.Equal(t, "899", item.EndBalance.Amount.String())
+
+	require.Len(t, item.StatementLines, 2)
+	assert.Equal(t, domain.StatementLine{
+		PostedDate:           "2024-01-02",
+		Description:          "Rent JanuaryFlat 3",
+		Amount:               money.MustParse("150.50"),
+		CreditDebitIndicator: "DEBIT",
+		TransactionID:        "BANKREF1",
+		PayeeName:            "Property Ltd",
+		TransactionType:      "NTRF",
+	}, item.StatementLines[0])
+	assert.Equal(t, domain.StatementLine{
+		PostedDate:           "2024-01-15",
+		Description:          "Interest payment",
+		Amount:               money.MustParse("49.50"),
+		CreditDebitIndicator: "CREDIT",
+		TransactionID:        "CUSTREF2",
+		Reference:            "CUSTREF2",
+		TransactionType:      "NMSC",
+	}, item.StatementLines[1])
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		file   string
+		format Format
+	}{
+		{"bank.qfx", "statement.ofx", FormatOFX},
+		{"upload", "statement.ofx", FormatOFX},
+		{"upload", "statement.qif", FormatQIF},
+		{"upload.xml", "statement.camt053.xml", FormatCAMT053},
+		{"upload.txt", "statement.sta", FormatMT940},
+	}
+	for _, tt := range tests {
+		format, err := DetectFormat(tt.name, readFile(t, tt.file))
+		require.NoError(t, err, tt.file)
+		assert.Equal(t, tt.format, format, tt.file)
+	}
+
+	_, err := DetectFormat("notes.txt", []byte("hello"))
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+}
+
+func TestParseCSV(t *testing.T) {
+	profile := domain.CSVProfile{
+		Name:         "nz-bank",
+		Delimiter:    ";",
+		HasHeader:    true,
+		SkipRows:     1,
+		DateFormat:   "DD/MM/YYYY",
+		DecimalComma: true,
+		NewestFirst:  true,
+		Columns: domain.CSVColumns{
+			Date:        "Date",
+			Description: "Details",
+			PayeeName:   "Payee",
+			Debit:       "Paid out",
+			Credit:      "Paid in",
+			Balance:     "Balance",
+		},
+	}
+	require.NoError(t, ValidateCSVProfile(profile))
+
+	items, err := Parse(FormatCSV, readFile(t, "statement.csv"), Options{Profile: &profile})
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+
+	item := items[0]
+	assertBalanced(t, item)
+	assert.Equal(t, "2024-01-02", item.StartDate)
+	assert.Equal(t, "2024-01-31", item.EndDate)
+	assert.Equal(t, "9020", item.StartBalance.Amount.String())
+	assert.Equal(t, "8765.5", item.EndBalance.Amount.String())
+
+	require.Len(t, item.StatementLines, 4)
+	first, second := item.StatementLines[0], item.StatementLines[1]
+	assert.Equal(t, "2024-01-02", first.PostedDate)
+	assert.Equal(t, "10", first.Amount.String())
+	assert.Equal(t, "DEBIT", first.CreditDebitIndicator)
+	assert.NotEqual(t, first.TransactionID, second.TransactionID)
+	assert.Equal(t, "CREDIT", item.StatementLines[2].CreditDebitIndicator)
+	assert.Equal(t, "Corner Shop; Ltd", item.StatementLines[3].PayeeName)
+	assert.Equal(t, "1234.5", item.StatementLines[3].Amount.String())
+}
+
+func TestParseCSVSignedAmount(t *testing.T) {
+	profile := domain.CSVProfile{
+		Name:          "card",
+		DateFormat:    "M/D/YY",
+		DebitPositive: true,
+		Columns:       domain.CSVColumns{Date: "1", Amount: "2", Description: "3", TransactionID: "4"},
+	}
+	require.NoError(t, ValidateCSVProfile(profile))
+
+	data := []byte("1/5/24,$42.00,Coffee,tx-1\n1/9/24,(10.00),Refund,tx-2\n")
+	start := domain.Balance{Amount: money.MustParse("100"), CreditDebitIndicator: "CREDIT"}
+	items, err := Parse(FormatCSV, data, Options{Profile: &profile, StartBalance: &start})
+	require.NoError(t, err)
+
+	item := items[0]
+	assertBalanced(t, item)
+	assert.Equal(t, "68", item.EndBalance.Amount.String())
+	assert.Equal(t, "DEBIT", item.StatementLines[0].CreditDebitIndicator)
+	assert.Equal(t, "CREDIT", item.StatementLines[1].CreditDebitIndicator)
+	assert.Equal(t, "tx-2", item.StatementLines[1].TransactionID)
+}
+
+func TestCSVAmount(t *testing.T) {
+	tests := []struct {
+		value        string
+		decimalComma bool
+		want         string
+	}{
+		{"$1,234.50", false, "1234.5"},
+		{"(12.00)", false, "-12"},
+		{"1,500", false, "1500"},
+		{"", false, "0"},
+		{"-1.234,56", true, "-1234.56"},
+		{"1,5", true, "1.5"},
+		{"1,5", false, ""},
+		{"12,34.00", false, ""},
+		{"1.5", true, ""},
+		{"1.234.5", true, ""},
+	}
+	for _, tt := range tests {
+		got, err := csvAmount(tt.value, tt.decimalComma)
+		if tt.want == "" {
+			assert.ErrorIs(t, err, money.ErrInvalidAmount, tt.value)
+			continue
+		}
+		require.NoError(t, err, tt.value)
+		assert.Equal(t, tt.want, got.String(), tt.value)
+	}
+}
+
+func TestParseCSVMissingColumns(t *testing.T) {
+	profile := domain.CSVProfile{
+		Name:      "bank",
+		HasHeader: true,
+		Columns:   domain.CSVColumns{Date: "Date", Amount: "Amount", Balance: "Balance"},
+	}
+
+	_, err := Parse(FormatCSV, []byte("Date,Amount,Running balance\n2024-01-05,10.00,110.00\n"), Options{Profile: &profile})
+	assert.ErrorContains(t, err, `column "Balance" not found in header`)
+
+	_, err = Parse(FormatCSV, []byte("Date,Amount,Balance\n2024-01-05,10.00,\n"), Options{Profile: &profile})
+	assert.ErrorContains(t, err, "row 2: balance is empty")
+
+	profile.HasHeader = false
+	profile.Columns = domain.CSVColumns{Date: "1", Amount: "2", Balance: "4"}
+	_, err = Parse(FormatCSV, []byte("2024-01-05,10.00,110.00\n"), Options{Profile: &profile})
+	assert.ErrorContains(t, err, "column 4 not found, the widest row has 3 columns")
+}
+
+func TestValidateCSVProfile(t *testing.T) {
+	assert.ErrorIs(t, ValidateCSVProfile(domain.CSVProfile{Columns: domain.CSVColumns{Date: "1", Amount: "2"}}), ErrInvalidProfile)
+	assert.ErrorIs(t, ValidateCSVProfile(domain.CSVProfile{Name: "x", Columns: domain.CSVColumns{Date: "1", Debit: "2"}}), ErrInvalidProfile)
+	assert.ErrorIs(t, ValidateCSVProfile(domain.CSVProfile{Name: "x", Columns: domain.CSVColumns{Date: "Date", Amount: "Amount"}}), ErrInvalidProfile)
+	assert.NoError(t, ValidateCSVProfile(domain.CSVProfile{Name: "x", HasHeader: true, Columns: domain.CSVColumns{Date: "Date", Amount: "Amount"}}))
+
+	_, err := Parse(FormatCSV, []byte("a,b"), Options{})
+	assert.ErrorIs(t, err, ErrProfileRequired)
+}
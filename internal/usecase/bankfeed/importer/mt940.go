@@ -0,0 +1,203 @@
+package importer
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"usdw/internal/domain"
+	"usdw/pkg/money"
+)
+
+var (
+	// :61: value date, optional entry date, mark, optional funds code, amount, type, references
+	mt940LinePattern = regexp.MustCompile(`^(\d{6})(\d{4})?(RC|RD|C|D)([A-Z])?([\d,]+)([NFS][A-Z0-9]{3})([^/\n]*)(?://([^\n]*))?(?:\n([\s\S]*))?$`)
+	// :60F:, :62F: and friends: mark, date, currency, amount
+	mt940BalancePattern = regexp.MustCompile(`^([CD])(\d{6})([A-Z]{3})([\d,]+)`)
+	// Structured :86: fields used by German banks, e.g. ?20purpose?32name
+	mt940SubfieldPattern = regexp.MustCompile(`\?(\d{2})`)
+)
+
+type mt940Field struct {
+	tag   string
+	value string
+}
+
+// splitMT940 returns the tagged fields of the text block, joining continuation lines
+func splitMT940(data []byte) []mt940Field {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	// Drop the SWIFT envelope around block 4, if any
+	if start := strings.Index(text, "{4:"); start >= 0 {
+		text = text[start+3:]
+	}
+
+	var fields []mt940Field
+	for _, line := range strings.Split(text, "\n") {
+		switch {
+		case strings.HasPrefix(line, ":") && strings.Count(line, ":") >= 2:
+			end := strings.Index(line[1:], ":") + 1
+			fields = append(fields, mt940Field{tag: line[1:end], value: line[end+1:]})
+		case strings.HasPrefix(line, "-}") || strings.TrimSpace(line) == "-":
+			fields = append(fields, mt940Field{tag: "-"})
+		case len(fields) > 0 && fields[len(fields)-1].tag != "-":
+			fields[len(fields)-1].value += "\n" + line
+		}
+	}
+	return fields
+}
+
+func parseMT940(data []byte) ([]domain.PostStatementItem, error) {
+	var (
+		items    []domain.PostStatementItem
+		item     *domain.PostStatementItem
+		hasStart bool
+		hasEnd   bool
+		closing  string
+		ref      string
+	)
+
+	finish := func() {
+		if item == nil {
+			return
+		}
+		if item.EndDate == "" || (closing != "" && closing > item.EndDate) {
+			item.EndDate = closing
+		}
+		completeItem(item, hasStart, hasEnd)
+		items = append(items, *item)
+		item = nil
+	}
+
+	for _, field := range splitMT940(data) {
+		value := strings.TrimRight(field.value, "\n ")
+		var err error
+
+		switch field.tag {
+		case "20":
+			finish()
+			item = &domain.PostStatementItem{}
+			hasStart, hasEnd, closing, ref = false, false, "", strings.TrimSpace(value)
+		case "-":
+			finish()
+		case "60F", "60M":
+			if item != nil {
+				item.StartBalance, _, err = mt940Balance(value)
+				hasStart = err == nil
+			}
+		case "62F", "62M":
+			if item != nil {
+				item.EndBalance, closing, err = mt940Balance(value)
+				hasEnd = err == nil
+			}
+		case "61":
+			if item != nil {
+				var line domain.StatementLine
+				line, err = mt940Line(value)
+				if err == nil {
+					if line.TransactionID == "" {
+						line.TransactionID = transactionID("mt940", ref, strconv.Itoa(len(item.StatementLines)), line.PostedDate, line.Amount.String())
+					}
+					item.StatementLines = append(item.StatementLines, line)
+				}
+			}
+		case "86":
+			if item != nil && len(item.StatementLines) > 0 {
+				mt940Details(&item.StatementLines[len(item.StatementLines)-1], value)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("statement %s, field :%s:: %w", ref, field.tag, err)
+		}
+	}
+	finish()
+
+	return items, nil
+}
+
+// mt940Amount reads an MT940 amount, which always has a decimal comma, e.g. "1234,56"
+func mt940Amount(value string) (money.Amount, error) {
+	if strings.Count(value, ",") != 1 {
+		return money.Amount{}, fmt.Errorf("%w: %q must have one decimal comma", money.ErrInvalidAmount, value)
+	}
+	return money.Parse(strings.Replace(value, ",", ".", 1))
+}
+
+func mt940Balance(value string) (domain.Balance, string, error) {
+	match := mt940BalancePattern.FindStringSubmatch(value)
+	if match == nil {
+		return domain.Balance{}, "", fmt.Errorf("invalid balance %q", value)
+	}
+	amount, err := mt940Amount(match[4])
+	if err != nil {
+		return domain.Balance{}, "", err
+	}
+
+	indicator := indicatorCredit
+	if match[1] == "D" {
+		indicator = indicatorDebit
+	}
+	return domain.Balance{Amount: amount, CreditDebitIndicator: indicator}, mt940Date(match[2]), nil
+}
+
+func mt940Line(value string) (domain.StatementLine, error) {
+	match := mt940LinePattern.FindStringSubmatch(value)
+	if match == nil {
+		return domain.StatementLine{}, fmt.Errorf("invalid statement line %q", value)
+	}
+	amount, err := mt940Amount(match[5])
+	if err != nil {
+		return domain.StatementLine{}, err
+	}
+
+	// RC and RD reverse an earlier debit or credit
+	indicator := indicatorCredit
+	if match[3] == "D" || match[3] == "RC" {
+		indicator = indicatorDebit
+	}
+
+	line := domain.StatementLine{
+		PostedDate:           mt940Date(match[1]),
+		Amount:               amount,
+		CreditDebitIndicator: indicator,
+		TransactionType:      match[6],
+		Description:          strings.TrimSpace(match[9]),
+	}
+	if reference := strings.TrimSpace(match[7]); reference != "NONREF" {
+		line.Reference = reference
+	}
+	line.TransactionID = firstNonEmpty(match[8], line.Reference)
+	return line, nil
+}
+
+// mt940Details fills the line from its :86: information field
+func mt940Details(line *domain.StatementLine, value string) {
+	value = strings.ReplaceAll(value, "\n", "")
+	if !mt940SubfieldPattern.MatchString(value) {
+		line.Description = firstNonEmpty(value, line.Description)
+		return
+	}
+
+	var purpose, payee []string
+	indexes := mt940SubfieldPattern.FindAllStringSubmatchIndex(value, -1)
+	for i, index := range indexes {
+		end := len(value)
+		if i+1 < len(indexes) {
+			end = indexes[i+1][0]
+		}
+		code, _ := strconv.Atoi(value[index[2]:index[3]])
+		text := value[index[1]:end]
+		switch {
+		case code >= 20 && code <= 29, code >= 60 && code <= 63:
+			purpose = append(purpose, text)
+		case code == 32 || code == 33:
+			payee = append(payee, text)
+		}
+	}
+	line.Description = firstNonEmpty(strings.Join(purpose, ""), line.Description)
+	line.PayeeName = firstNonEmpty(strings.Join(payee, ""), line.PayeeName)
+}
+
+// mt940Date converts YYMMDD, SWIFT dates are always in this century
+func mt940Date(value string) string {
+	return "20" + value[0:2] + "-" + value[2:4] + "-" + value[4:6]
+}
@@ -0,0 +1,160 @@
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"usdw/internal/domain"
+)
+
+// ofxToken is an element of an OFX file. OFX 1.x is SGML where leaf elements are
+// not closed, so the parser only relies on aggregates being closed.
+type ofxToken struct {
+	tag   string
+	value string
+	close bool
+}
+
+func tokenizeOFX(data []byte) []ofxToken {
+	start := bytes.Index(bytes.ToUpper(data), []byte("<OFX>"))
+	if start < 0 {
+		return nil
+	}
+	data = data[start:]
+
+	var tokens []ofxToken
+	for len(data) > 0 {
+		open := bytes.IndexByte(data, '<')
+		if open < 0 {
+			break
+		}
+		end := bytes.IndexByte(data[open:], '>')
+		if end < 0 {
+			break
+		}
+		tag := strings.ToUpper(strings.TrimSpace(string(data[open+1 : open+end])))
+		data = data[open+end+1:]
+
+		next := bytes.IndexByte(data, '<')
+		if next < 0 {
+			next = len(data)
+		}
+		value := strings.TrimSpace(string(data[:next]))
+
+		if strings.HasPrefix(tag, "/") {
+			tokens = append(tokens, ofxToken{tag: tag[1:], close: true})
+		} else if !strings.HasPrefix(tag, "?") && !strings.HasPrefix(tag, "!") {
+			tokens = append(tokens, ofxToken{tag: tag, value: unescapeOFX(value)})
+		}
+	}
+	return tokens
+}
+
+func unescapeOFX(value string) string {
+	return strings.NewReplacer("&amp;", "&", "&lt;", "<", "&gt;", ">", "&quot;", `"`, "&apos;", "'").Replace(value)
+}
+
+// ofxDate keeps the YYYYMMDD part of an OFX datetime such as 20240131120000.000[-5:EST]
+func ofxDate(value string) (string, error) {
+	if len(value) < 8 {
+		return "", fmt.Errorf("invalid OFX date %q", value)
+	}
+	return value[0:4] + "-" + value[4:6] + "-" + value[6:8], nil
+}
+
+func parseOFX(data []byte) ([]domain.PostStatementItem, error) {
+	tokens := tokenizeOFX(data)
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("no <OFX> element found")
+	}
+
+	var (
+		items     []domain.PostStatementItem
+		item      *domain.PostStatementItem
+		line      *domain.StatementLine
+		inLedger  bool
+		hasEnd    bool
+		statement int
+	)
+
+	for _, token := range tokens {
+		if token.close {
+			switch token.tag {
+			case "STMTRS", "CCSTMTRS":
+				if item != nil {
+					completeItem(item, false, hasEnd)
+					items = append(items, *item)
+					item = nil
+				}
+			case "STMTTRN":
+				if item != nil && line != nil {
+					if line.Description == "" {
+						line.Description = line.PayeeName
+					}
+					if line.TransactionID == "" {
+						line.TransactionID = transactionID("ofx", line.PostedDate, line.Amount.String(), line.Description, fmt.Sprint(len(item.StatementLines)))
+					}
+					item.StatementLines = append(item.StatementLines, *line)
+				}
+				line = nil
+			case "LEDGERBAL":
+				inLedger = false
+			}
+			continue
+		}
+
+		var err error
+		switch token.tag {
+		case "STMTRS", "CCSTMTRS":
+			statement++
+			item = &domain.PostStatementItem{}
+			hasEnd = false
+		case "STMTTRN":
+			line = &domain.StatementLine{}
+		case "LEDGERBAL":
+			inLedger = true
+		}
+		if item == nil {
+			continue
+		}
+
+		switch {
+		case line != nil:
+			switch token.tag {
+			case "TRNTYPE":
+				line.TransactionType = token.value
+			case "DTPOSTED":
+				line.PostedDate, err = ofxDate(token.value)
+			case "TRNAMT":
+				amount, parseErr := parseAmount(token.value)
+				err = parseErr
+				line.Amount, line.CreditDebitIndicator = fromSigned(amount)
+			case "FITID":
+				line.TransactionID = token.value
+			case "NAME":
+				// <NAME> is either the payee itself or part of a <PAYEE> aggregate
+				line.PayeeName = token.value
+			case "MEMO":
+				line.Description = token.value
+			case "CHECKNUM":
+				line.ChequeNumber = token.value
+			case "REFNUM":
+				line.Reference = token.value
+			}
+		case inLedger && token.tag == "BALAMT":
+			amount, parseErr := parseAmount(token.value)
+			err = parseErr
+			item.EndBalance.Amount, item.EndBalance.CreditDebitIndicator = fromSigned(amount)
+			hasEnd = true
+		case token.tag == "DTSTART":
+			item.StartDate, err = ofxDate(token.value)
+		case token.tag == "DTEND":
+			item.EndDate, err = ofxDate(token.value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("statement %d: %w", statement, err)
+		}
+	}
+
+	return items, nil
+}
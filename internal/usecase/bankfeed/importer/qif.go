@@ -0,0 +1,160 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"usdw/internal/domain"
+)
+
+// parseQIF reads a Quicken Interchange Format file. QIF has no balances or transaction
+// IDs: the start balance comes from an "Opening Balance" entry or Options.StartBalance,
+// and IDs are derived from the line contents.
+func parseQIF(data []byte, options Options) ([]domain.PostStatementItem, error) {
+	item := domain.PostStatementItem{StartBalance: domain.Balance{CreditDebitIndicator: indicatorCredit}}
+	hasStart := false
+	if options.StartBalance != nil {
+		item.StartBalance = *options.StartBalance
+		hasStart = true
+	}
+
+	var (
+		record     map[byte]string
+		recordLine int
+		inBank     bool
+	)
+	seen := make(map[string]int)
+
+	flush := func() error {
+		defer func() { record = nil }()
+		if record == nil || !inBank {
+			return nil
+		}
+
+		date, err := qifDate(record['D'], options.DayFirst)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", recordLine, err)
+		}
+		value := record['T']
+		if value == "" {
+			value = record['U']
+		}
+		amount, err := parseAmount(value)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", recordLine, err)
+		}
+
+		// Quicken writes the opening balance as the first transaction
+		if strings.EqualFold(record['P'], "Opening Balance") && len(item.StatementLines) == 0 {
+			if options.StartBalance == nil {
+				item.StartBalance.Amount, item.StartBalance.CreditDebitIndicator = fromSigned(amount)
+				hasStart = true
+			}
+			item.StartDate = date
+			return nil
+		}
+
+		line := domain.StatementLine{
+			PostedDate:  date,
+			Description: record['M'],
+			PayeeName:   record['P'],
+		}
+		line.Amount, line.CreditDebitIndicator = fromSigned(amount)
+		if line.Description == "" {
+			line.Description = line.PayeeName
+		}
+		if number := record['N']; number != "" {
+			if _, err := strconv.Atoi(number); err == nil {
+				line.ChequeNumber = number
+			} else {
+				line.Reference = number
+			}
+		}
+
+		// Identical lines on the same day are told apart by their occurrence
+		key := strings.Join([]string{date, amount.String(), line.PayeeName, line.Description, record['N']}, "|")
+		seen[key]++
+		line.TransactionID = transactionID("qif", key, strconv.Itoa(seen[key]))
+
+		item.StatementLines = append(item.StatementLines, line)
+		return nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(text, "!"):
+			header := strings.ToLower(strings.TrimSpace(text))
+			inBank = strings.HasPrefix(header, "!type:bank") || strings.HasPrefix(header, "!type:ccard") || strings.HasPrefix(header, "!type:cash")
+			record = nil
+		case text == "^":
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		default:
+			if record == nil {
+				record = make(map[byte]string)
+				recordLine = lineNumber
+			}
+			// Split transactions (S, E, $) repeat codes; the first value describes the whole line
+			if _, ok := record[text[0]]; !ok {
+				record[text[0]] = strings.TrimSpace(text[1:])
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	if len(item.StatementLines) == 0 && !hasStart {
+		return nil, nil
+	}
+	// Without an opening balance the statement starts from zero
+	completeItem(&item, true, false)
+	return []domain.PostStatementItem{item}, nil
+}
+
+// qifDate reads the many date spellings found in QIF files, e.g. 1/31/2024, 01/31'24 and 31.01.2024
+func qifDate(value string, dayFirst bool) (string, error) {
+	normalized := strings.NewReplacer("'", "/", "-", "/", ".", "/", " ", "").Replace(value)
+	parts := strings.Split(normalized, "/")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("invalid QIF date %q", value)
+	}
+
+	numbers := make([]int, 3)
+	for i, part := range parts {
+		number, err := strconv.Atoi(part)
+		if err != nil {
+			return "", fmt.Errorf("invalid QIF date %q", value)
+		}
+		numbers[i] = number
+	}
+
+	month, day, year := numbers[0], numbers[1], numbers[2]
+	switch {
+	case len(parts[0]) == 4:
+		year, month, day = numbers[0], numbers[1], numbers[2]
+	case dayFirst:
+		day, month = numbers[0], numbers[1]
+	}
+	if year < 100 {
+		year += 2000
+	}
+	if month < 1 || month > 12 || day < 1 || day > 31 {
+		return "", fmt.Errorf("invalid QIF date %q", value)
+	}
+	return fmt.Sprintf("%04d-%02d-%02d", year, month, day), nil
+}
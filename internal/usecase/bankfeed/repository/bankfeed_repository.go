@@ -7,46 +7,78 @@ import (
 	"usdw/config"
 	"usdw/internal/domain"
 	"usdw/internal/domain/entity"
+	"usdw/pkg/ratelimit"
 	"usdw/pkg/xero"
 
 	"github.com/go-resty/resty/v2"
+	"github.com/google/uuid"
 )
 
 const bankFeedsBaseURL = "https://api.xero.com/bankfeeds.xro/1.0"
 
 type bankFeedRepository struct {
-	Config *config.Configuration
-	Client *resty.Client
+	Config  *config.Configuration
+	Client  *resty.Client
+	Tokens  *xero.TokenManager
+	Tenants *xero.TenantResolver
+	Limiter ratelimit.Limiter
 }
 
-func NewBankFeedRepository(client *resty.Client, config *config.Configuration) domain.BankFeedRepository {
+func NewBankFeedRepository(client *resty.Client, config *config.Configuration, tokens *xero.TokenManager, tenants *xero.TenantResolver, limiter ratelimit.Limiter) domain.BankFeedRepository {
 	return &bankFeedRepository{
-		Config: config,
-		Client: client,
+		Config:  config,
+		Client:  client,
+		Tokens:  tokens,
+		Tenants: tenants,
+		Limiter: limiter,
 	}
 }
 
+func (r *bankFeedRepository) TenantID(ctx context.Context) (string, error) {
+	return r.Tenants.ResolveTenantID(ctx)
+}
+
 func (r *bankFeedRepository) authenticatedRequest(ctx context.Context) (*resty.Request, error) {
-	accessToken, err := xero.GetAccessToken(ctx)
+	tenantID, err := r.Tenants.ResolveTenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := r.Tokens.GetAccessToken(ctx, tenantID)
 	if err != nil {
 		return nil, err
 	}
 
-	tenantID, err := xero.GetXeroTenantID(ctx)
+	// Pace calls per tenant so a burst on one replica cannot get the tenant throttled
+	_, err = r.Limiter.Wait(ctx, tenantID)
 	if err != nil {
 		return nil, err
 	}
 
 	return r.Client.R().
+		SetContext(ctx).
 		SetHeader("Authorization", "Bearer "+accessToken).
 		SetHeader("xero-tenant-id", tenantID).
 		SetHeader("Content-Type", "application/json"), nil
 }
 
+// idempotentRequest tags a POST with an Idempotency-Key so Xero ignores repeats,
+// which makes it safe for the client to retry. This relies on Xero honouring
+// Idempotency-Key on the Bank Feeds endpoints as it does on the Accounting API; if it
+// does not, a retried POST after a lost response can create a duplicate.
+func (r *bankFeedRepository) idempotentRequest(ctx context.Context) (*resty.Request, error) {
+	req, err := r.authenticatedRequest(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return req.SetHeader(xero.IdempotencyKeyHeader, uuid.NewString()), nil
+}
+
 func (r *bankFeedRepository) CreateConnections(ctx context.Context, request entity.FeedConnectionRequest) (*entity.FeedConnectionResponse, error) {
 	url := fmt.Sprintf("%s/FeedConnections", bankFeedsBaseURL)
 
-	req, err := r.authenticatedRequest(ctx)
+	req, err := r.idempotentRequest(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -57,7 +89,7 @@ func (r *bankFeedRepository) CreateConnections(ctx context.Context, request enti
 	}
 
 	if resp.StatusCode() >= 400 {
-		return nil, fmt.Errorf("Xero API error [%d]: %s", resp.StatusCode(), resp.String())
+		return nil, xero.NewAPIError(resp, "")
 	}
 
 	var response entity.FeedConnectionResponse
@@ -83,7 +115,7 @@ func (r *bankFeedRepository) FetchConnections(ctx context.Context, page, pageSiz
 	}
 
 	if resp.StatusCode() >= 400 {
-		return nil, fmt.Errorf("Xero API error [%d]: %s", resp.StatusCode(), resp.String())
+		return nil, xero.NewAPIError(resp, "")
 	}
 
 	var response entity.FetchConnectionsResponse
@@ -108,12 +140,8 @@ func (r *bankFeedRepository) FetchConnectionByID(ctx context.Context, feedConnec
 		return nil, fmt.Errorf("failed to call Xero API: %w", err)
 	}
 
-	if resp.StatusCode() == 404 {
-		return nil, fmt.Errorf("feed connection not found")
-	}
-
 	if resp.StatusCode() >= 400 {
-		return nil, fmt.Errorf("Xero API error [%d]: %s", resp.StatusCode(), resp.String())
+		return nil, xero.NewAPIError(resp, "feed connection not found")
 	}
 
 	var feedConnection entity.FeedConnection
@@ -128,7 +156,7 @@ func (r *bankFeedRepository) FetchConnectionByID(ctx context.Context, feedConnec
 func (r *bankFeedRepository) DeleteConnection(ctx context.Context, request entity.DeleteRequest) (*entity.DeleteResponse, error) {
 	url := fmt.Sprintf("%s/FeedConnections/DeleteRequests", bankFeedsBaseURL)
 
-	req, err := r.authenticatedRequest(ctx)
+	req, err := r.idempotentRequest(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -139,7 +167,7 @@ func (r *bankFeedRepository) DeleteConnection(ctx context.Context, request entit
 	}
 
 	if resp.StatusCode() >= 400 {
-		return nil, fmt.Errorf("Xero API error [%d]: %s", resp.StatusCode(), resp.String())
+		return nil, xero.NewAPIError(resp, "")
 	}
 
 	var response entity.DeleteResponse
@@ -154,7 +182,7 @@ func (r *bankFeedRepository) DeleteConnection(ctx context.Context, request entit
 func (r *bankFeedRepository) PostStatements(ctx context.Context, request entity.StatementRequest) (*entity.StatementResponse, error) {
 	url := fmt.Sprintf("%s/Statements", bankFeedsBaseURL)
 
-	req, err := r.authenticatedRequest(ctx)
+	req, err := r.idempotentRequest(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -165,7 +193,7 @@ func (r *bankFeedRepository) PostStatements(ctx context.Context, request entity.
 	}
 
 	if resp.StatusCode() >= 400 {
-		return nil, fmt.Errorf("Xero API error [%d]: %s", resp.StatusCode(), resp.String())
+		return nil, xero.NewAPIError(resp, "")
 	}
 
 	var response entity.StatementResponse
@@ -187,7 +215,7 @@ func (r *bankFeedRepository) GetStatements(ctx context.Context, page, pageSize i
 	}
 
 	if resp.StatusCode() >= 400 {
-		return nil, fmt.Errorf("Xero API error [%d]: %s", resp.StatusCode(), resp.String())
+		return nil, xero.NewAPIError(resp, "")
 	}
 
 	var response entity.StatementResponse
@@ -208,12 +236,8 @@ func (r *bankFeedRepository) GetStatementByID(ctx context.Context, statementID s
 		return nil, fmt.Errorf("failed to call Xero API: %w", err)
 	}
 
-	if resp.StatusCode() == 404 {
-		return nil, fmt.Errorf("statement not found")
-	}
-
 	if resp.StatusCode() >= 400 {
-		return nil, fmt.Errorf("Xero API error [%d]: %s", resp.StatusCode(), resp.String())
+		return nil, xero.NewAPIError(resp, "statement not found")
 	}
 
 	var statement entity.StatementResult
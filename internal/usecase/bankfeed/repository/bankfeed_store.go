@@ -0,0 +1,225 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"time"
+	"usdw/internal/domain"
+	"usdw/internal/domain/entity"
+	"usdw/pkg/db"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// postedTransactionBatchSize keeps IN lists and multi-row inserts within database parameter limits
+const postedTransactionBatchSize = 500
+
+type bankFeedStore struct {
+	DB *db.DB
+}
+
+func NewBankFeedStore(db *db.DB) domain.BankFeedStore {
+	return &bankFeedStore{
+		DB: db,
+	}
+}
+
+func (s *bankFeedStore) SaveConnections(ctx context.Context, records []entity.FeedConnectionRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	return s.DB.WithContext(ctx).Create(&records).Error
+}
+
+// MarkConnectionDeleted soft deletes the connection so it stays in the history
+func (s *bankFeedStore) MarkConnectionDeleted(ctx context.Context, tenantID, feedConnectionID string) error {
+	return s.DB.WithContext(ctx).
+		Where("tenant_id = ? AND xero_id = ?", tenantID, feedConnectionID).
+		Delete(&entity.FeedConnectionRecord{}).Error
+}
+
+// ListConnections returns the live connections created in Xero, newest first, and their total count
+func (s *bankFeedStore) ListConnections(ctx context.Context, tenantID string, page, pageSize int) ([]entity.FeedConnectionRecord, int, error) {
+	query := s.DB.WithContext(ctx).
+		Model(&entity.FeedConnectionRecord{}).
+		Where("tenant_id = ? AND xero_id <> ''", tenantID).
+		Session(&gorm.Session{})
+
+	var count int64
+	err := query.Count(&count).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var records []entity.FeedConnectionRecord
+	err = query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&records).Error
+	return records, int(count), err
+}
+
+func (s *bankFeedStore) SaveStatements(ctx context.Context, records []entity.StatementRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	return s.DB.WithContext(ctx).Create(&records).Error
+}
+
+// ListStatements returns the statements posted for the tenant that match the filter, including rejected ones, newest first
+func (s *bankFeedStore) ListStatements(ctx context.Context, tenantID string, filter domain.StatementFilter, page, pageSize int) ([]entity.StatementRecord, int, error) {
+	query := s.DB.WithContext(ctx).
+		Model(&entity.StatementRecord{}).
+		Where("tenant_id = ?", tenantID)
+	if filter.FeedConnectionID != "" {
+		query = query.Where("feed_connection_id = ?", filter.FeedConnectionID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.StartDate != "" {
+		query = query.Where("end_date >= ?", filter.StartDate)
+	}
+	if filter.EndDate != "" {
+		query = query.Where("start_date <= ?", filter.EndDate)
+	}
+	if filter.MinLines > 0 {
+		query = query.Where("statement_line_count >= ?", filter.MinLines)
+	}
+	if filter.MaxLines > 0 {
+		query = query.Where("statement_line_count <= ?", filter.MaxLines)
+	}
+	query = query.Session(&gorm.Session{})
+
+	var count int64
+	err := query.Count(&count).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var records []entity.StatementRecord
+	err = query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&records).Error
+	return records, int(count), err
+}
+
+func (s *bankFeedStore) GetStatement(ctx context.Context, tenantID, statementID string) (*entity.StatementRecord, error) {
+	var record entity.StatementRecord
+	err := s.DB.WithContext(ctx).
+		Where("tenant_id = ? AND xero_id = ?", tenantID, statementID).
+		Take(&record).Error
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// ClaimDueStatements uses next_poll_at as the lease. A statement whose replica dies
+// while checking it becomes due again once the lease ends.
+func (s *bankFeedStore) ClaimDueStatements(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]entity.StatementRecord, error) {
+	var records []entity.StatementRecord
+	err := s.DB.WithContext(ctx).
+		Where("status = ? AND xero_id <> ''", domain.StatementStatusPending).
+		Where("next_poll_at IS NULL OR next_poll_at <= ?", now).
+		Order("next_poll_at").
+		Limit(limit).
+		Find(&records).Error
+	if err != nil {
+		return nil, err
+	}
+
+	leaseUntil := now.Add(lease)
+	claimed := records[:0]
+	for _, record := range records {
+		// Only the first replica to update a statement still finds it due
+		result := s.DB.WithContext(ctx).
+			Model(&entity.StatementRecord{}).
+			Where("id = ? AND status = ?", record.ID, domain.StatementStatusPending).
+			Where("next_poll_at IS NULL OR next_poll_at <= ?", now).
+			Update("next_poll_at", leaseUntil)
+		if result.Error != nil {
+			return claimed, result.Error
+		}
+		if result.RowsAffected == 1 {
+			record.NextPollAt = &leaseUntil
+			claimed = append(claimed, record)
+		}
+	}
+	return claimed, nil
+}
+
+func (s *bankFeedStore) UpdateStatement(ctx context.Context, record *entity.StatementRecord) error {
+	return s.DB.WithContext(ctx).Save(record).Error
+}
+
+func (s *bankFeedStore) FindOverlappingStatements(ctx context.Context, tenantID, feedConnectionID, startDate, endDate string) ([]entity.StatementRecord, error) {
+	var records []entity.StatementRecord
+	err := s.DB.WithContext(ctx).
+		Where("tenant_id = ? AND feed_connection_id = ? AND xero_id <> '' AND status <> ?", tenantID, feedConnectionID, domain.StatementStatusRejected).
+		Where("start_date <= ? AND end_date >= ?", endDate, startDate).
+		Order("start_date").
+		Find(&records).Error
+	return records, err
+}
+
+// SavePostedTransactions points transactions posted again, after a rejection, at their new statement
+func (s *bankFeedStore) SavePostedTransactions(ctx context.Context, records []entity.PostedTransactionRecord) error {
+	records = uniquePostedTransactions(records)
+	if len(records) == 0 {
+		return nil
+	}
+	return s.DB.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "tenant_id"}, {Name: "feed_connection_id"}, {Name: "transaction_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"statement_id", "posted_date"}),
+		}).
+		CreateInBatches(&records, postedTransactionBatchSize).Error
+}
+
+// uniquePostedTransactions keeps the last record of each transaction. Postgres refuses an
+// upsert that would update the same row twice, which one request can ask for by repeating
+// a transaction ID on a connection.
+func uniquePostedTransactions(records []entity.PostedTransactionRecord) []entity.PostedTransactionRecord {
+	type key struct{ tenantID, feedConnectionID, transactionID string }
+	positions := make(map[key]int, len(records))
+	unique := make([]entity.PostedTransactionRecord, 0, len(records))
+	for _, record := range records {
+		k := key{record.TenantID, record.FeedConnectionID, record.TransactionID}
+		if i, ok := positions[k]; ok {
+			unique[i] = record
+			continue
+		}
+		positions[k] = len(unique)
+		unique = append(unique, record)
+	}
+	return unique
+}
+
+func (s *bankFeedStore) FindPostedTransactions(ctx context.Context, tenantID, feedConnectionID string, transactionIDs []string) ([]string, error) {
+	var posted []string
+	for batch := range slices.Chunk(transactionIDs, postedTransactionBatchSize) {
+		var found []string
+		err := s.DB.WithContext(ctx).
+			Model(&entity.PostedTransactionRecord{}).
+			Where("tenant_id = ? AND feed_connection_id = ? AND transaction_id IN ?", tenantID, feedConnectionID, batch).
+			Pluck("transaction_id", &found).Error
+		if err != nil {
+			return nil, err
+		}
+		posted = append(posted, found...)
+	}
+	return posted, nil
+}
+
+func (s *bankFeedStore) DeletePostedTransactions(ctx context.Context, statementID uint) error {
+	return s.DB.WithContext(ctx).
+		Where("statement_id = ?", statementID).
+		Delete(&entity.PostedTransactionRecord{}).Error
+}
+
+func (s *bankFeedStore) ListConnectionStatements(ctx context.Context, tenantID, feedConnectionID string) ([]entity.StatementRecord, error) {
+	var records []entity.StatementRecord
+	err := s.DB.WithContext(ctx).
+		Omit("statement_lines").
+		Where("tenant_id = ? AND feed_connection_id = ? AND xero_id <> '' AND status <> ?", tenantID, feedConnectionID, domain.StatementStatusRejected).
+		Order("start_date, end_date").
+		Find(&records).Error
+	return records, err
+}
@@ -0,0 +1,198 @@
+package repository
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+	"usdw/config"
+	"usdw/internal/domain"
+	"usdw/internal/domain/entity"
+	"usdw/pkg/db"
+	"usdw/pkg/logger"
+	"usdw/pkg/money"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStore(t *testing.T) *bankFeedStore {
+	conf := &config.Configuration{Database: config.DatabaseConfig{Driver: "sqlite", SQLitePath: filepath.Join(t.TempDir(), "usdw.db")}}
+	database, err := db.NewDB(conf, logger.NewLogger())
+	require.NoError(t, err)
+	t.Cleanup(func() { database.Close() })
+	require.NoError(t, database.AutoMigrate(&entity.FeedConnectionRecord{}, &entity.StatementRecord{}, &entity.PostedTransactionRecord{}))
+	return &bankFeedStore{DB: database}
+}
+
+func TestBankFeedStoreConnections(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+
+	err := store.SaveConnections(ctx, []entity.FeedConnectionRecord{
+		{TenantID: "tenant-a", XeroID: "conn-1", AccountToken: "token-1", Status: "PENDING"},
+		{TenantID: "tenant-a", AccountToken: "token-2", Status: "REJECTED", Error: `{"type":"invalid-request"}`},
+		{TenantID: "tenant-a", XeroID: "conn-3", AccountToken: "token-3", Status: "PENDING"},
+		{TenantID: "tenant-b", XeroID: "conn-4", AccountToken: "token-4", Status: "PENDING"},
+	})
+	require.NoError(t, err)
+
+	records, count, err := store.ListConnections(ctx, "tenant-a", 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.Equal(t, "conn-3", records[0].XeroID)
+	assert.Equal(t, "conn-1", records[1].XeroID)
+
+	require.NoError(t, store.MarkConnectionDeleted(ctx, "tenant-a", "conn-3"))
+
+	records, count, err = store.ListConnections(ctx, "tenant-a", 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, "conn-1", records[0].XeroID)
+
+	// Deleted connections stay in the history
+	var all int64
+	require.NoError(t, store.DB.Unscoped().Model(&entity.FeedConnectionRecord{}).Count(&all).Error)
+	assert.Equal(t, int64(4), all)
+}
+
+func TestBankFeedStoreStatements(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+
+	err := store.SaveStatements(ctx, []entity.StatementRecord{
+		{TenantID: "tenant-a", XeroID: "stmt-1", Status: "PENDING", StartBalance: money.MustParse("100.10"), EndBalance: money.MustParse("0.0001")},
+		{TenantID: "tenant-a", XeroID: "stmt-2", Status: "REJECTED"},
+		{TenantID: "tenant-a", XeroID: "stmt-3", Status: "PENDING"},
+	})
+	require.NoError(t, err)
+
+	records, count, err := store.ListStatements(ctx, "tenant-a", domain.StatementFilter{}, 2, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+	require.Len(t, records, 1)
+	assert.Equal(t, "stmt-1", records[0].XeroID)
+	assert.True(t, money.MustParse("100.10").Equal(records[0].StartBalance))
+	assert.True(t, money.MustParse("0.0001").Equal(records[0].EndBalance))
+}
+
+func TestBankFeedStoreFilterStatements(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+
+	err := store.SaveStatements(ctx, []entity.StatementRecord{
+		{TenantID: "tenant-a", XeroID: "stmt-1", FeedConnectionID: "conn-1", Status: "REJECTED", StartDate: "2023-01-01", EndDate: "2023-01-07", StatementLineCount: 5},
+		{TenantID: "tenant-a", XeroID: "stmt-2", FeedConnectionID: "conn-1", Status: "REJECTED", StartDate: "2023-01-08", EndDate: "2023-01-14", StatementLineCount: 50},
+		{TenantID: "tenant-a", XeroID: "stmt-3", FeedConnectionID: "conn-1", Status: "DELIVERED", StartDate: "2023-01-08", EndDate: "2023-01-14", StatementLineCount: 5},
+		{TenantID: "tenant-a", XeroID: "stmt-4", FeedConnectionID: "conn-2", Status: "REJECTED", StartDate: "2023-01-08", EndDate: "2023-01-14", StatementLineCount: 5},
+		{TenantID: "tenant-b", XeroID: "stmt-5", FeedConnectionID: "conn-1", Status: "REJECTED", StartDate: "2023-01-08", EndDate: "2023-01-14", StatementLineCount: 5},
+	})
+	require.NoError(t, err)
+
+	records, count, err := store.ListStatements(ctx, "tenant-a", domain.StatementFilter{
+		FeedConnectionID: "conn-1",
+		Status:           "REJECTED",
+		StartDate:        "2023-01-07",
+		EndDate:          "2023-01-13",
+	}, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.Equal(t, []string{"stmt-2", "stmt-1"}, []string{records[0].XeroID, records[1].XeroID})
+
+	records, count, err = store.ListStatements(ctx, "tenant-a", domain.StatementFilter{StartDate: "2023-01-08", MinLines: 10, MaxLines: 100}, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, "stmt-2", records[0].XeroID)
+}
+
+func TestBankFeedStoreDedup(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+
+	statements := []entity.StatementRecord{
+		{TenantID: "tenant-a", XeroID: "stmt-1", FeedConnectionID: "conn-1", Status: "DELIVERED", StartDate: "2023-01-01", EndDate: "2023-01-31"},
+		{TenantID: "tenant-a", XeroID: "stmt-2", FeedConnectionID: "conn-1", Status: "REJECTED", StartDate: "2023-02-01", EndDate: "2023-02-28"},
+		{TenantID: "tenant-a", XeroID: "stmt-3", FeedConnectionID: "conn-2", Status: "PENDING", StartDate: "2023-02-01", EndDate: "2023-02-28"},
+	}
+	require.NoError(t, store.SaveStatements(ctx, statements))
+
+	overlaps, err := store.FindOverlappingStatements(ctx, "tenant-a", "conn-1", "2023-01-31", "2023-02-15")
+	require.NoError(t, err)
+	require.Len(t, overlaps, 1)
+	assert.Equal(t, "stmt-1", overlaps[0].XeroID)
+
+	overlaps, err = store.FindOverlappingStatements(ctx, "tenant-a", "conn-1", "2023-02-01", "2023-02-15")
+	require.NoError(t, err)
+	assert.Empty(t, overlaps)
+
+	require.NoError(t, store.SavePostedTransactions(ctx, []entity.PostedTransactionRecord{
+		{TenantID: "tenant-a", FeedConnectionID: "conn-1", TransactionID: "txn-1", StatementID: statements[0].ID},
+		{TenantID: "tenant-a", FeedConnectionID: "conn-1", TransactionID: "txn-2", StatementID: statements[0].ID},
+		{TenantID: "tenant-a", FeedConnectionID: "conn-2", TransactionID: "txn-3", StatementID: statements[2].ID},
+	}))
+	// Posting a transaction again moves it to the new statement
+	require.NoError(t, store.SavePostedTransactions(ctx, []entity.PostedTransactionRecord{
+		{TenantID: "tenant-a", FeedConnectionID: "conn-2", TransactionID: "txn-3", StatementID: statements[1].ID},
+	}))
+
+	posted, err := store.FindPostedTransactions(ctx, "tenant-a", "conn-1", []string{"txn-1", "txn-3", "txn-4"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"txn-1"}, posted)
+
+	require.NoError(t, store.DeletePostedTransactions(ctx, statements[1].ID))
+	posted, err = store.FindPostedTransactions(ctx, "tenant-a", "conn-2", []string{"txn-3"})
+	require.NoError(t, err)
+	assert.Empty(t, posted)
+}
+
+func TestSavePostedTransactionsRepeatedID(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+
+	// Two statements of one request share a transaction on the same connection
+	records := []entity.PostedTransactionRecord{
+		{TenantID: "tenant-a", FeedConnectionID: "conn-1", TransactionID: "txn-1", StatementID: 1},
+		{TenantID: "tenant-a", FeedConnectionID: "conn-2", TransactionID: "txn-1", StatementID: 1},
+		{TenantID: "tenant-a", FeedConnectionID: "conn-1", TransactionID: "txn-1", StatementID: 2},
+	}
+	unique := uniquePostedTransactions(records)
+	require.Len(t, unique, 2)
+	assert.Equal(t, uint(2), unique[0].StatementID)
+	assert.Equal(t, "conn-2", unique[1].FeedConnectionID)
+
+	require.NoError(t, store.SavePostedTransactions(ctx, records))
+	var saved []entity.PostedTransactionRecord
+	require.NoError(t, store.DB.Order("feed_connection_id").Find(&saved).Error)
+	require.Len(t, saved, 2)
+	assert.Equal(t, uint(2), saved[0].StatementID)
+}
+
+func TestBankFeedStoreClaimDueStatements(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+
+	now := time.Now()
+	past := now.Add(-time.Minute)
+	require.NoError(t, store.SaveStatements(ctx, []entity.StatementRecord{
+		{TenantID: "tenant-a", XeroID: "stmt-1", Status: domain.StatementStatusPending},
+		{TenantID: "tenant-a", XeroID: "stmt-2", Status: domain.StatementStatusPending, NextPollAt: &past},
+		{TenantID: "tenant-a", XeroID: "stmt-3", Status: domain.StatementStatusDelivered},
+	}))
+
+	claimed, err := store.ClaimDueStatements(ctx, now, time.Minute, 10)
+	require.NoError(t, err)
+	assert.Len(t, claimed, 2)
+	for _, record := range claimed {
+		require.NotNil(t, record.NextPollAt)
+		assert.True(t, record.NextPollAt.After(now))
+	}
+
+	// Claimed statements are left alone until the lease ends
+	claimed, err = store.ClaimDueStatements(ctx, now, time.Minute, 10)
+	require.NoError(t, err)
+	assert.Empty(t, claimed)
+
+	claimed, err = store.ClaimDueStatements(ctx, now.Add(2*time.Minute), time.Minute, 10)
+	require.NoError(t, err)
+	assert.Len(t, claimed, 2)
+}
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"usdw/internal/domain"
+	"usdw/internal/domain/entity"
+	"usdw/pkg/db"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type importProfileStore struct {
+	DB *db.DB
+	// configured holds the read-only profiles from the profiles file
+	configured map[string]domain.CSVProfile
+}
+
+// NewImportProfileStore serves profiles stored in the database, falling back to the
+// profiles loaded from the configured profiles file
+func NewImportProfileStore(db *db.DB, configured []domain.CSVProfile) domain.ImportProfileStore {
+	store := &importProfileStore{
+		DB:         db,
+		configured: make(map[string]domain.CSVProfile, len(configured)),
+	}
+	for _, profile := range configured {
+		store.configured[profile.Name] = profile
+	}
+	return store
+}
+
+// SaveProfile creates or replaces the stored profile with the same name
+func (s *importProfileStore) SaveProfile(ctx context.Context, profile domain.CSVProfile) error {
+	profile.Source = ""
+	definition, err := json.Marshal(profile)
+	if err != nil {
+		return err
+	}
+
+	record := entity.ImportProfileRecord{Name: profile.Name, Definition: string(definition)}
+	return s.DB.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "name"}},
+			DoUpdates: clause.AssignmentColumns([]string{"definition", "updated_at"}),
+		}).
+		Create(&record).Error
+}
+
+func (s *importProfileStore) GetProfile(ctx context.Context, name string) (*domain.CSVProfile, error) {
+	var record entity.ImportProfileRecord
+	err := s.DB.WithContext(ctx).Where("name = ?", name).Take(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if profile, ok := s.configured[name]; ok {
+			return &profile, nil
+		}
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	profile, err := decodeProfile(record)
+	if err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// ListProfiles returns the stored profiles and the configured ones they do not override
+func (s *importProfileStore) ListProfiles(ctx context.Context) ([]domain.CSVProfile, error) {
+	var records []entity.ImportProfileRecord
+	err := s.DB.WithContext(ctx).Order("name").Find(&records).Error
+	if err != nil {
+		return nil, err
+	}
+
+	profiles := make([]domain.CSVProfile, 0, len(records)+len(s.configured))
+	stored := make(map[string]bool, len(records))
+	for _, record := range records {
+		profile, err := decodeProfile(record)
+		if err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, profile)
+		stored[profile.Name] = true
+	}
+	for name, profile := range s.configured {
+		if !stored[name] {
+			profiles = append(profiles, profile)
+		}
+	}
+	slices.SortFunc(profiles, func(a, b domain.CSVProfile) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return profiles, nil
+}
+
+// DeleteProfile removes a stored profile; configured profiles cannot be deleted
+func (s *importProfileStore) DeleteProfile(ctx context.Context, name string) error {
+	result := s.DB.WithContext(ctx).Where("name = ?", name).Delete(&entity.ImportProfileRecord{})
+	if result.Error == nil && result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return result.Error
+}
+
+func decodeProfile(record entity.ImportProfileRecord) (domain.CSVProfile, error) {
+	var profile domain.CSVProfile
+	err := json.Unmarshal([]byte(record.Definition), &profile)
+	profile.Source = "db"
+	return profile, err
+}
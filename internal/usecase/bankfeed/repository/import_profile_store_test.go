@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"usdw/internal/domain"
+	"usdw/internal/domain/entity"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestImportProfileStore(t *testing.T) {
+	ctx := context.Background()
+	database := newTestStore(t).DB
+	require.NoError(t, database.AutoMigrate(&entity.ImportProfileRecord{}))
+
+	store := NewImportProfileStore(database, []domain.CSVProfile{
+		{Name: "bank-a", DateFormat: "DD/MM/YYYY", Source: "config"},
+		{Name: "bank-b", Source: "config"},
+	})
+
+	require.NoError(t, store.SaveProfile(ctx, domain.CSVProfile{Name: "bank-c", HasHeader: true}))
+	require.NoError(t, store.SaveProfile(ctx, domain.CSVProfile{Name: "bank-b", DateFormat: "MM/DD/YYYY"}))
+	require.NoError(t, store.SaveProfile(ctx, domain.CSVProfile{Name: "bank-b", DateFormat: "YYYY-MM-DD"}))
+
+	profile, err := store.GetProfile(ctx, "bank-a")
+	require.NoError(t, err)
+	assert.Equal(t, "config", profile.Source)
+
+	// A stored profile overrides the configured one with the same name
+	profile, err = store.GetProfile(ctx, "bank-b")
+	require.NoError(t, err)
+	assert.Equal(t, "db", profile.Source)
+	assert.Equal(t, "YYYY-MM-DD", profile.DateFormat)
+
+	profile, err = store.GetProfile(ctx, "missing")
+	require.NoError(t, err)
+	assert.Nil(t, profile)
+
+	profiles, err := store.ListProfiles(ctx)
+	require.NoError(t, err)
+	require.Len(t, profiles, 3)
+	assert.Equal(t, []string{"bank-a", "bank-b", "bank-c"}, []string{profiles[0].Name, profiles[1].Name, profiles[2].Name})
+	assert.Equal(t, "db", profiles[1].Source)
+
+	require.NoError(t, store.DeleteProfile(ctx, "bank-b"))
+	profile, err = store.GetProfile(ctx, "bank-b")
+	require.NoError(t, err)
+	assert.Equal(t, "config", profile.Source)
+
+	assert.ErrorIs(t, store.DeleteProfile(ctx, "bank-a"), gorm.ErrRecordNotFound)
+}
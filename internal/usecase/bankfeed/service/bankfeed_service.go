@@ -13,12 +13,22 @@ import (
 type bankFeedService struct {
 	domain.BankFeedRepository
 	*config.Configuration
+	Store    domain.BankFeedStore
+	Profiles domain.ImportProfileStore
+	Webhooks domain.WebhookPublisher
+	cache    serviceCache
+	logger   logger.Logger
 }
 
-func NewBankFeedService(bankFeedRepository domain.BankFeedRepository, config *config.Configuration, cache cache.Engine, logger logger.Logger) domain.BankFeedService {
+func NewBankFeedService(bankFeedRepository domain.BankFeedRepository, store domain.BankFeedStore, profiles domain.ImportProfileStore, webhooks domain.WebhookPublisher, config *config.Configuration, cache cache.Engine, logger logger.Logger) domain.BankFeedService {
 	return &bankFeedService{
 		BankFeedRepository: bankFeedRepository,
 		Configuration:      config,
+		Store:              store,
+		Profiles:           profiles,
+		Webhooks:           webhooks,
+		cache:              newServiceCache(cache, logger),
+		logger:             logger,
 	}
 }
 
@@ -38,6 +48,9 @@ func (s *bankFeedService) CreateConnections(ctx context.Context, request domain.
 		return nil, err
 	}
 
+	s.recordConnections(ctx, request, entityResponse)
+	s.invalidateConnections(ctx)
+
 	// Convert entity response to domain response
 	domainResponse := &domain.CreateConnectionsResponse{
 		Items: make([]domain.CreateConnectionResult, len(entityResponse.Items)),
@@ -50,12 +63,26 @@ func (s *bankFeedService) CreateConnections(ctx context.Context, request domain.
 			Status:       item.Status,
 			Error:        (*domain.FeedError)(item.Error),
 		}
+		if item.Error == nil {
+			s.publish(ctx, domain.EventConnectionCreated, domainResponse.Items[i])
+		}
 	}
 
 	return domainResponse, nil
 }
 
 func (s *bankFeedService) GetConnections(ctx context.Context, page, pageSize int) (*domain.ConnectionsResponse, error) {
+	tenantID, err := s.BankFeedRepository.TenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return readThrough(ctx, s.cache.connections, connectionsCacheKey(tenantID, page, pageSize), s.Xero.ConnectionsCacheTTL, func(ctx context.Context) (*domain.ConnectionsResponse, error) {
+		return s.fetchConnections(ctx, page, pageSize)
+	})
+}
+
+func (s *bankFeedService) fetchConnections(ctx context.Context, page, pageSize int) (*domain.ConnectionsResponse, error) {
 	// Fetch from repository (returns entity struct)
 	entityResponse, err := s.BankFeedRepository.FetchConnections(ctx, page, pageSize)
 	if err != nil {
@@ -65,15 +92,7 @@ func (s *bankFeedService) GetConnections(ctx context.Context, page, pageSize int
 	// Map entity struct to domain struct
 	domainConnections := make([]domain.Connection, len(entityResponse.Items))
 	for i, conn := range entityResponse.Items {
-		domainConnections[i] = domain.Connection{
-			ID:            conn.ID,
-			AccountToken:  conn.AccountToken,
-			AccountType:   conn.AccountType,
-			AccountNumber: conn.AccountNumber,
-			AccountName:   conn.AccountName,
-			AccountID:     conn.AccountID,
-			Currency:      conn.Currency,
-		}
+		domainConnections[i] = mapConnection(conn)
 	}
 
 	return &domain.ConnectionsResponse{
@@ -88,105 +107,166 @@ func (s *bankFeedService) GetConnections(ctx context.Context, page, pageSize int
 }
 
 func (s *bankFeedService) GetConnectionByID(ctx context.Context, feedConnectionID string) (*domain.Connection, error) {
+	tenantID, err := s.BankFeedRepository.TenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return readThrough(ctx, s.cache.connection, connectionCacheKey(tenantID, feedConnectionID), s.Xero.ConnectionCacheTTL, func(ctx context.Context) (*domain.Connection, error) {
+		return s.fetchConnectionByID(ctx, feedConnectionID)
+	})
+}
+
+func (s *bankFeedService) fetchConnectionByID(ctx context.Context, feedConnectionID string) (*domain.Connection, error) {
 	entityConnection, err := s.BankFeedRepository.FetchConnectionByID(ctx, feedConnectionID)
 	if err != nil {
 		return nil, err
 	}
 
 	// Map entity struct to domain struct
-	domainConnection := &domain.Connection{
-		ID:            entityConnection.ID,
-		AccountToken:  entityConnection.AccountToken,
-		AccountType:   entityConnection.AccountType,
-		AccountNumber: entityConnection.AccountNumber,
-		AccountName:   entityConnection.AccountName,
-		AccountID:     entityConnection.AccountID,
-		Currency:      entityConnection.Currency,
-	}
+	domainConnection := mapConnection(*entityConnection)
 
-	return domainConnection, nil
+	return &domainConnection, nil
 }
 
 func (s *bankFeedService) DeleteConnection(ctx context.Context, feedConnectionID string) (*domain.DeleteResult, error) {
-	// Convert request to entity format
-	entityRequest := entity.DeleteRequest{
-		Items: []entity.DeleteItem{
-			{ID: feedConnectionID},
-		},
-	}
-
-	// Call repository
-	entityResponse, err := s.BankFeedRepository.DeleteConnection(ctx, entityRequest)
+	results, err := s.deleteConnections(ctx, []entity.DeleteItem{{ID: feedConnectionID}})
 	if err != nil {
 		return nil, err
 	}
 
 	// Ensure we received a valid response
-	if len(entityResponse.Items) == 0 {
+	if len(results) == 0 {
 		return nil, fmt.Errorf("no response received for feed connection ID: %s", feedConnectionID)
 	}
 
-	// Extract the first (and only) item
-	item := entityResponse.Items[0]
+	return &results[0], nil
+}
+
+// deleteConnections asks Xero to delete the connections and records the ones it accepted
+func (s *bankFeedService) deleteConnections(ctx context.Context, items []entity.DeleteItem) ([]domain.DeleteResult, error) {
+	// Call repository
+	entityResponse, err := s.BankFeedRepository.DeleteConnection(ctx, entity.DeleteRequest{Items: items})
+	if err != nil {
+		return nil, err
+	}
 
 	// Convert entity response to domain response
-	domainResponse := &domain.DeleteResult{
-		ID:           item.ID,
-		AccountToken: item.AccountToken,
-		Status:       item.Status,
-		Error:        (*domain.FeedError)(item.Error),
+	results := make([]domain.DeleteResult, len(entityResponse.Items))
+	var deleted []string
+	for i, item := range entityResponse.Items {
+		results[i] = domain.DeleteResult{
+			ID:           item.ID,
+			AccountToken: item.AccountToken,
+			Status:       item.Status,
+			Error:        (*domain.FeedError)(item.Error),
+		}
+		if results[i].ID == "" && i < len(items) {
+			results[i].ID = items[i].ID
+		}
+		if item.Error == nil {
+			if results[i].ID != "" {
+				s.recordConnectionDeleted(ctx, results[i].ID)
+				deleted = append(deleted, results[i].ID)
+			}
+			s.publish(ctx, domain.EventConnectionDeleted, results[i])
+		}
 	}
+	s.invalidateConnections(ctx, deleted...)
 
-	return domainResponse, nil
+	return results, nil
 }
 
 func (s *bankFeedService) PostStatements(ctx context.Context, request domain.PostStatementRequest) (*domain.PostStatementResponse, error) {
-	// Convert domain request to entity request
-	entityRequest := entity.StatementRequest{
-		Items: make([]entity.StatementItem, len(request.Items)),
+	currencies, err := s.connectionCurrencies(ctx, request)
+	if err != nil {
+		return nil, err
 	}
 
-	for i, item := range request.Items {
-		entityRequest.Items[i] = entity.StatementItem{
-			FeedConnectionID: item.FeedConnectionID,
-			StartDate:        item.StartDate,
-			EndDate:          item.EndDate,
-			StartBalance: entity.Balance{
-				Amount:               item.StartBalance.Amount,
-				CreditDebitIndicator: item.StartBalance.CreditDebitIndicator,
-			},
-			EndBalance: entity.Balance{
-				Amount:               item.EndBalance.Amount,
-				CreditDebitIndicator: item.EndBalance.CreditDebitIndicator,
-			},
-			StatementLines: mapStatementLines(item.StatementLines),
-		}
+	// Catch problems Xero would only report asynchronously
+	err = validateStatements(request, s.Xero.MaxStatementLines, currencies)
+	if err != nil {
+		return nil, err
 	}
 
-	// Call repository
-	entityResponse, err := s.BankFeedRepository.PostStatements(ctx, entityRequest)
+	// Leave out or reject what was already posted to the connections
+	dedup, err := s.dedupStatements(ctx, request)
 	if err != nil {
 		return nil, err
 	}
 
-	// Convert entity response to domain response
+	// Oversized statements go out as consecutive parts, one request at a time
+	chunks := splitStatements(dedup.request, s.Xero.MaxStatementLines, s.Xero.MaxStatementBytes)
 	domainResponse := &domain.PostStatementResponse{
-		Items: make([]domain.StatementResult, len(entityResponse.Items)),
+		Items:    make([]domain.StatementResult, len(chunks)),
+		Stripped: dedup.stripped,
 	}
 
-	for i, item := range entityResponse.Items {
-		domainResponse.Items[i] = domain.StatementResult{
-			ID:               item.ID,
-			FeedConnectionID: item.FeedConnectionID,
-			Status:           item.Status,
-			Errors:           mapErrors(item.Errors),
+	for n, batch := range batchChunks(chunks, s.Xero.MaxStatementBytes) {
+		batchRequest := domain.PostStatementRequest{Items: make([]domain.PostStatementItem, len(batch))}
+		for i, index := range batch {
+			batchRequest.Items[i] = chunks[index].statement
+		}
+
+		entityResponse, err := s.BankFeedRepository.PostStatements(ctx, mapStatementRequest(batchRequest))
+		if err != nil {
+			if n == 0 {
+				return nil, err
+			}
+			// Earlier batches are already with Xero, so report the rest instead of failing
+			s.logger.Errorf("Failed to post statement batch %d: %s", n+1, err)
+			notPosted(domainResponse, chunks, err)
+			break
+		}
+
+		s.recordStatements(ctx, batchRequest, entityResponse)
+		s.invalidateStatementPages(ctx)
+
+		for i, item := range entityResponse.Items {
+			if i >= len(batch) {
+				break
+			}
+			chunk := chunks[batch[i]].statement
+			domainResponse.Items[batch[i]] = domain.StatementResult{
+				ID:               item.ID,
+				FeedConnectionID: item.FeedConnectionID,
+				Status:           item.Status,
+				StartDate:        chunk.StartDate,
+				EndDate:          chunk.EndDate,
+				Errors:           mapErrors(item.Errors),
+			}
+			// Xero can reject a statement straight away, e.g. as a duplicate
+			if event, ok := statementEvent(item.Status); ok {
+				s.publish(ctx, event, domainResponse.Items[batch[i]])
+			}
 		}
 	}
+	domainResponse.Items = append(domainResponse.Items, dedup.skipped...)
 
 	return domainResponse, nil
 }
 
-func (s *bankFeedService) GetStatements(ctx context.Context, page, pageSize int) (*domain.GetStatementsResponse, error) {
+// GetStatements returns one page from Xero. Xero cannot filter statements, so a filtered
+// request reads every page, through the cache, and returns one page of the matches.
+func (s *bankFeedService) GetStatements(ctx context.Context, filter domain.StatementFilter, page, pageSize int) (*domain.GetStatementsResponse, error) {
+	if !filter.IsZero() {
+		return s.filterStatements(ctx, filter, page, pageSize)
+	}
+	return s.statementsPage(ctx, page, pageSize)
+}
+
+func (s *bankFeedService) statementsPage(ctx context.Context, page, pageSize int) (*domain.GetStatementsResponse, error) {
+	tenantID, err := s.BankFeedRepository.TenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return readThrough(ctx, s.cache.statements, statementsCacheKey(tenantID, page, pageSize), s.Xero.StatementsCacheTTL, func(ctx context.Context) (*domain.GetStatementsResponse, error) {
+		return s.fetchStatements(ctx, page, pageSize)
+	})
+}
+
+func (s *bankFeedService) fetchStatements(ctx context.Context, page, pageSize int) (*domain.GetStatementsResponse, error) {
 	entityResponse, err := s.BankFeedRepository.GetStatements(ctx, page, pageSize)
 	if err != nil {
 		return nil, err
@@ -201,6 +281,17 @@ func (s *bankFeedService) GetStatements(ctx context.Context, page, pageSize int)
 }
 
 func (s *bankFeedService) GetStatementByID(ctx context.Context, statementID string) (*domain.StatementResult, error) {
+	tenantID, err := s.BankFeedRepository.TenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return readThrough(ctx, s.cache.statement, statementCacheKey(tenantID, statementID), s.Xero.StatementCacheTTL, func(ctx context.Context) (*domain.StatementResult, error) {
+		return s.fetchStatementByID(ctx, statementID)
+	})
+}
+
+func (s *bankFeedService) fetchStatementByID(ctx context.Context, statementID string) (*domain.StatementResult, error) {
 	entityResponse, err := s.BankFeedRepository.GetStatementByID(ctx, statementID)
 	if err != nil {
 		return nil, err
@@ -217,3 +308,21 @@ func (s *bankFeedService) GetStatementByID(ctx context.Context, statementID stri
 		StatementLineCount: entityResponse.StatementLineCount,
 	}, nil
 }
+
+// connectionCurrencies looks up the currency of every feed connection in the request
+// so amounts can be checked against its minor units
+func (s *bankFeedService) connectionCurrencies(ctx context.Context, request domain.PostStatementRequest) (map[string]string, error) {
+	currencies := make(map[string]string)
+	for _, item := range request.Items {
+		if _, ok := currencies[item.FeedConnectionID]; ok || item.FeedConnectionID == "" {
+			continue
+		}
+
+		connection, err := s.GetConnectionByID(ctx, item.FeedConnectionID)
+		if err != nil {
+			return nil, err
+		}
+		currencies[item.FeedConnectionID] = connection.Currency
+	}
+	return currencies, nil
+}
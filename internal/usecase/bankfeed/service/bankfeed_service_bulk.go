@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"usdw/internal/domain"
+	"usdw/internal/domain/entity"
+	"usdw/pkg/common/exception"
+)
+
+// bulkBatchSize is the number of connections sent to Xero per call
+const bulkBatchSize = 50
+
+// BulkCreateConnections creates the connections in batches. A failed batch marks its
+// items as failed and the remaining batches are still sent.
+func (s *bankFeedService) BulkCreateConnections(ctx context.Context, request domain.BulkCreateConnectionsRequest) (*domain.BulkResponse, error) {
+	if len(request.Items) == 0 {
+		return nil, exception.BadRequestError{Message: "items must not be empty"}
+	}
+
+	response := &domain.BulkResponse{Items: make([]domain.BulkItemResult, 0, len(request.Items))}
+	for batch := range slices.Chunk(request.Items, bulkBatchSize) {
+		created, err := s.CreateConnections(ctx, domain.CreateConnectionsRequest{Items: batch})
+		if err != nil {
+			status, feedError := bulkError(err)
+			for _, item := range batch {
+				response.Items = append(response.Items, domain.BulkItemResult{AccountToken: item.AccountToken, Status: status, Error: feedError})
+			}
+			continue
+		}
+
+		for _, item := range created.Items {
+			response.Items = append(response.Items, domain.BulkItemResult{
+				ID:           item.ID,
+				AccountToken: item.AccountToken,
+				Status:       itemStatus(item.Error, http.StatusCreated),
+				XeroStatus:   item.Status,
+				Error:        item.Error,
+			})
+		}
+	}
+	response.Summary = summarize(response.Items)
+
+	if request.Reconcile {
+		tokens := make([]string, len(request.Items))
+		for i, item := range request.Items {
+			tokens[i] = item.AccountToken
+		}
+		reconciliation, err := s.reconcileConnections(ctx, tokens, nil)
+		if err != nil {
+			return nil, err
+		}
+		response.Reconciliation = reconciliation
+	}
+
+	return response, nil
+}
+
+// BulkDeleteConnections deletes the connections in batches, by ID first and then by account token
+func (s *bankFeedService) BulkDeleteConnections(ctx context.Context, request domain.BulkDeleteConnectionsRequest) (*domain.BulkResponse, error) {
+	items := make([]entity.DeleteItem, 0, len(request.IDs)+len(request.AccountTokens))
+	for _, id := range request.IDs {
+		items = append(items, entity.DeleteItem{ID: id})
+	}
+	for _, token := range request.AccountTokens {
+		items = append(items, entity.DeleteItem{AccountToken: token})
+	}
+	if len(items) == 0 {
+		return nil, exception.BadRequestError{Message: "ids or accountTokens must not be empty"}
+	}
+
+	response := &domain.BulkResponse{Items: make([]domain.BulkItemResult, 0, len(items))}
+	for batch := range slices.Chunk(items, bulkBatchSize) {
+		deleted, err := s.deleteConnections(ctx, batch)
+		if err != nil {
+			status, feedError := bulkError(err)
+			for _, item := range batch {
+				response.Items = append(response.Items, domain.BulkItemResult{ID: item.ID, AccountToken: item.AccountToken, Status: status, Error: feedError})
+			}
+			continue
+		}
+
+		for _, item := range deleted {
+			response.Items = append(response.Items, domain.BulkItemResult{
+				ID:           item.ID,
+				AccountToken: item.AccountToken,
+				Status:       itemStatus(item.Error, http.StatusOK),
+				XeroStatus:   item.Status,
+				Error:        item.Error,
+			})
+		}
+	}
+	response.Summary = summarize(response.Items)
+
+	if request.Reconcile {
+		reconciliation, err := s.reconcileConnections(ctx, nil, items)
+		if err != nil {
+			return nil, err
+		}
+		response.Reconciliation = reconciliation
+	}
+
+	return response, nil
+}
+
+// reconcileConnections lists every connection of the tenant and reports the account
+// tokens that should exist but do not, and the deleted connections that still exist
+func (s *bankFeedService) reconcileConnections(ctx context.Context, wanted []string, deleted []entity.DeleteItem) (*domain.Reconciliation, error) {
+	connections, err := s.allConnections(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]bool, len(connections))
+	tokens := make(map[string]bool, len(connections))
+	for _, connection := range connections {
+		ids[connection.ID] = true
+		tokens[connection.AccountToken] = true
+	}
+
+	reconciliation := &domain.Reconciliation{Missing: []string{}, Remaining: []string{}}
+	for _, token := range wanted {
+		if !tokens[token] {
+			reconciliation.Missing = append(reconciliation.Missing, token)
+		}
+	}
+	for _, item := range deleted {
+		switch {
+		case item.ID != "" && ids[item.ID]:
+			reconciliation.Remaining = append(reconciliation.Remaining, item.ID)
+		case item.ID == "" && tokens[item.AccountToken]:
+			reconciliation.Remaining = append(reconciliation.Remaining, item.AccountToken)
+		}
+	}
+	reconciliation.InSync = len(reconciliation.Missing) == 0 && len(reconciliation.Remaining) == 0
+	return reconciliation, nil
+}
+
+// itemStatus is the HTTP status of a single item, taken from its error when it failed
+func itemStatus(feedError *domain.FeedError, success int) int {
+	switch {
+	case feedError == nil:
+		return success
+	case feedError.Status >= http.StatusBadRequest:
+		return feedError.Status
+	}
+	return http.StatusBadRequest
+}
+
+// bulkError describes a batch that failed as a whole, e.g. when Xero was unavailable
+func bulkError(err error) (int, *domain.FeedError) {
+	problem := exception.NewProblemDetails(err)
+	return problem.Status, &domain.FeedError{Type: problem.Type, Title: problem.Title, Status: problem.Status, Detail: problem.Detail}
+}
+
+func summarize(items []domain.BulkItemResult) domain.BulkSummary {
+	summary := domain.BulkSummary{Total: len(items)}
+	for _, item := range items {
+		if item.Error == nil {
+			summary.Succeeded++
+		} else {
+			summary.Failed++
+		}
+	}
+	return summary
+}
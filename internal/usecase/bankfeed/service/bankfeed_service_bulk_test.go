@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"usdw/internal/domain"
+	"usdw/internal/domain/entity"
+	"usdw/pkg/logger"
+	"usdw/pkg/xero"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// bulkRepository rejects the token "bad" and fails every call containing "down"
+type bulkRepository struct {
+	tenantRepository
+	connections []entity.FeedConnection
+}
+
+func (r *bulkRepository) CreateConnections(ctx context.Context, request entity.FeedConnectionRequest) (*entity.FeedConnectionResponse, error) {
+	for _, item := range request.Items {
+		if item.AccountToken == "down" {
+			return nil, &xero.APIError{StatusCode: http.StatusServiceUnavailable, Problem: xero.Problem{Title: "Service Unavailable"}}
+		}
+	}
+
+	response := &entity.FeedConnectionResponse{}
+	for _, item := range request.Items {
+		if item.AccountToken == "bad" {
+			response.Items = append(response.Items, entity.FeedConnectionResult{AccountToken: item.AccountToken, Status: "REJECTED",
+				Error: &entity.FeedError{Type: "invalid-currency", Title: "Invalid Currency", Status: http.StatusBadRequest}})
+			continue
+		}
+		response.Items = append(response.Items, entity.FeedConnectionResult{ID: "id-" + item.AccountToken, AccountToken: item.AccountToken, Status: "PENDING"})
+		r.connections = append(r.connections, entity.FeedConnection{ID: "id-" + item.AccountToken, AccountToken: item.AccountToken})
+	}
+	return response, nil
+}
+
+func (r *bulkRepository) DeleteConnection(ctx context.Context, request entity.DeleteRequest) (*entity.DeleteResponse, error) {
+	response := &entity.DeleteResponse{}
+	for _, item := range request.Items {
+		if item.ID == "id-missing" {
+			response.Items = append(response.Items, entity.DeleteResult{ID: item.ID, Status: "REJECTED",
+				Error: &entity.FeedError{Type: "invalid-feed-connection", Title: "Invalid Feed Connection", Status: http.StatusNotFound}})
+			continue
+		}
+		response.Items = append(response.Items, entity.DeleteResult{ID: item.ID, AccountToken: item.AccountToken, Status: "DELETED"})
+	}
+	return response, nil
+}
+
+func (r *bulkRepository) FetchConnections(ctx context.Context, page, pageSize int) (*entity.FetchConnectionsResponse, error) {
+	return &entity.FetchConnectionsResponse{Pagination: entity.Pagination{Page: 1, PageCount: 1}, Items: r.connections}, nil
+}
+
+type nopStore struct {
+	domain.BankFeedStore
+}
+
+func (nopStore) SaveConnections(ctx context.Context, records []entity.FeedConnectionRecord) error {
+	return nil
+}
+
+func (nopStore) MarkConnectionDeleted(ctx context.Context, tenantID, feedConnectionID string) error {
+	return nil
+}
+
+func (nopStore) SaveStatements(ctx context.Context, records []entity.StatementRecord) error {
+	return nil
+}
+
+func (nopStore) SavePostedTransactions(ctx context.Context, records []entity.PostedTransactionRecord) error {
+	return nil
+}
+
+type nopPublisher struct{}
+
+func (nopPublisher) Publish(ctx context.Context, tenantID, eventType string, data interface{}) error {
+	return nil
+}
+
+func newBulkService(repository *bulkRepository) *bankFeedService {
+	return &bankFeedService{
+		BankFeedRepository: repository,
+		Store:              nopStore{},
+		Webhooks:           nopPublisher{},
+		logger:             logger.NewLogger(),
+	}
+}
+
+func TestBulkCreateConnections(t *testing.T) {
+	service := newBulkService(&bulkRepository{})
+
+	items := []domain.CreateConnectionItem{{AccountToken: "bad"}}
+	for i := 0; i < bulkBatchSize; i++ {
+		items = append(items, domain.CreateConnectionItem{AccountToken: fmt.Sprintf("token-%d", i)})
+	}
+	// The second batch fails as a whole
+	items = append(items, domain.CreateConnectionItem{AccountToken: "down"})
+
+	response, err := service.BulkCreateConnections(context.Background(), domain.BulkCreateConnectionsRequest{Items: items, Reconcile: true})
+	require.NoError(t, err)
+
+	assert.Equal(t, domain.BulkSummary{Total: bulkBatchSize + 2, Succeeded: bulkBatchSize - 1, Failed: 3}, response.Summary)
+	assert.Equal(t, http.StatusBadRequest, response.Items[0].Status)
+	assert.Equal(t, http.StatusCreated, response.Items[1].Status)
+	assert.Equal(t, "PENDING", response.Items[1].XeroStatus)
+	assert.Equal(t, http.StatusBadGateway, response.Items[bulkBatchSize].Status)
+	assert.Equal(t, http.StatusBadGateway, response.Items[bulkBatchSize+1].Status)
+
+	require.NotNil(t, response.Reconciliation)
+	assert.False(t, response.Reconciliation.InSync)
+	assert.Equal(t, []string{"bad", items[bulkBatchSize].AccountToken, "down"}, response.Reconciliation.Missing)
+}
+
+func TestBulkDeleteConnections(t *testing.T) {
+	repository := &bulkRepository{connections: []entity.FeedConnection{{ID: "id-1", AccountToken: "token-1"}}}
+	service := newBulkService(repository)
+
+	response, err := service.BulkDeleteConnections(context.Background(), domain.BulkDeleteConnectionsRequest{
+		IDs:           []string{"id-1", "id-missing"},
+		AccountTokens: []string{"token-2"},
+		Reconcile:     true,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, domain.BulkSummary{Total: 3, Succeeded: 2, Failed: 1}, response.Summary)
+	assert.Equal(t, []int{http.StatusOK, http.StatusNotFound, http.StatusOK},
+		[]int{response.Items[0].Status, response.Items[1].Status, response.Items[2].Status})
+	// The fake never removes connections, so the deleted one is reported as remaining
+	assert.Equal(t, []string{"id-1"}, response.Reconciliation.Remaining)
+
+	_, err = service.BulkDeleteConnections(context.Background(), domain.BulkDeleteConnectionsRequest{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"usdw/internal/domain"
+	"usdw/pkg/cache"
+	"usdw/pkg/logger"
+)
+
+const (
+	// connectionsCachePrefix starts the keys of every cached page of the connection list
+	connectionsCachePrefix = "xero:connections:"
+	// statementsCachePrefix starts the keys of every cached page of the statement list
+	statementsCachePrefix = "xero:statements:"
+)
+
+// serviceCache holds Xero GET responses in the tenant's cache namespace. The zero value
+// caches nothing.
+type serviceCache struct {
+	engine      cache.Engine
+	connection  *cache.JSON[domain.Connection]
+	connections *cache.JSON[domain.ConnectionsResponse]
+	statement   *cache.JSON[domain.StatementResult]
+	statements  *cache.JSON[domain.GetStatementsResponse]
+}
+
+func newServiceCache(engine cache.Engine, logger logger.Logger) serviceCache {
+	return serviceCache{
+		engine:      engine,
+		connection:  cache.NewJSON[domain.Connection](engine, logger),
+		connections: cache.NewJSON[domain.ConnectionsResponse](engine, logger),
+		statement:   cache.NewJSON[domain.StatementResult](engine, logger),
+		statements:  cache.NewJSON[domain.GetStatementsResponse](engine, logger),
+	}
+}
+
+func connectionCacheKey(tenantID, feedConnectionID string) string {
+	return cache.TenantPrefix(tenantID) + "xero:connection:" + feedConnectionID
+}
+
+func connectionsCacheKey(tenantID string, page, pageSize int) string {
+	return cache.TenantPrefix(tenantID) + connectionsCachePrefix + fmt.Sprintf("%d:%d", page, pageSize)
+}
+
+func statementCacheKey(tenantID, statementID string) string {
+	return cache.TenantPrefix(tenantID) + "xero:statement:" + statementID
+}
+
+func statementsCacheKey(tenantID string, page, pageSize int) string {
+	return cache.TenantPrefix(tenantID) + statementsCachePrefix + fmt.Sprintf("%d:%d", page, pageSize)
+}
+
+// readThrough returns the response cached under key, or loads it and caches it for ttl.
+// Concurrent misses share one call to Xero. A zero ttl disables caching.
+func readThrough[T any](ctx context.Context, store *cache.JSON[T], key string, ttl time.Duration, load func(ctx context.Context) (*T, error)) (*T, error) {
+	if store == nil || ttl <= 0 {
+		return load(ctx)
+	}
+
+	value, err := store.GetOrSet(ctx, key, ttl, func(ctx context.Context) (T, error) {
+		loaded, err := load(ctx)
+		if err != nil {
+			var zero T
+			return zero, err
+		}
+		return *loaded, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &value, nil
+}
+
+// invalidateConnections drops the cached connection list and the given connections
+func (s *bankFeedService) invalidateConnections(ctx context.Context, feedConnectionIDs ...string) {
+	if s.cache.engine == nil {
+		return
+	}
+	tenantID, err := s.BankFeedRepository.TenantID(ctx)
+	if err != nil {
+		s.logger.Errorf("Failed to invalidate cached connections: %s", err)
+		return
+	}
+
+	tenantCache := cache.ForTenant(s.cache.engine, tenantID)
+	err = tenantCache.DeletePrefix(ctx, connectionsCachePrefix)
+	if err != nil {
+		s.logger.Errorf("Failed to invalidate cached connections of tenant %s: %s", tenantID, err)
+	}
+
+	keys := make([]string, len(feedConnectionIDs))
+	for i, feedConnectionID := range feedConnectionIDs {
+		keys[i] = connectionCacheKey(tenantID, feedConnectionID)
+	}
+	s.invalidate(ctx, keys)
+}
+
+// invalidateStatementPages drops the cached statement list
+func (s *bankFeedService) invalidateStatementPages(ctx context.Context) {
+	if s.cache.engine == nil {
+		return
+	}
+	tenantID, err := s.BankFeedRepository.TenantID(ctx)
+	if err != nil {
+		s.logger.Errorf("Failed to invalidate cached statements: %s", err)
+		return
+	}
+
+	tenantCache := cache.ForTenant(s.cache.engine, tenantID)
+	err = tenantCache.DeletePrefix(ctx, statementsCachePrefix)
+	if err != nil {
+		s.logger.Errorf("Failed to invalidate cached statements of tenant %s: %s", tenantID, err)
+	}
+}
+
+func (s *bankFeedService) invalidate(ctx context.Context, keys []string) {
+	if len(keys) == 0 {
+		return
+	}
+	err := s.cache.engine.Delete(ctx, keys...)
+	if err != nil {
+		s.logger.Errorf("Failed to remove %v from cache: %s", keys, err)
+	}
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+	"usdw/config"
+	"usdw/internal/domain"
+	"usdw/internal/domain/entity"
+	"usdw/pkg/cache/inmem"
+	"usdw/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingRepository counts the reads that reach Xero
+type countingRepository struct {
+	bulkRepository
+	connectionReads int
+	listReads       int
+	statementReads  int
+	pageReads       int
+}
+
+func (r *countingRepository) FetchConnectionByID(ctx context.Context, feedConnectionID string) (*entity.FeedConnection, error) {
+	r.connectionReads++
+	return &entity.FeedConnection{ID: feedConnectionID, AccountToken: "token-1", Currency: "NZD"}, nil
+}
+
+func (r *countingRepository) FetchConnections(ctx context.Context, page, pageSize int) (*entity.FetchConnectionsResponse, error) {
+	r.listReads++
+	return r.bulkRepository.FetchConnections(ctx, page, pageSize)
+}
+
+func (r *countingRepository) GetStatementByID(ctx context.Context, statementID string) (*entity.StatementResult, error) {
+	r.statementReads++
+	return &entity.StatementResult{ID: statementID, Status: domain.StatementStatusPending}, nil
+}
+
+func (r *countingRepository) GetStatements(ctx context.Context, page, pageSize int) (*entity.StatementResponse, error) {
+	r.pageReads++
+	return &entity.StatementResponse{
+		Pagination: &entity.Pagination{Page: page, PageSize: pageSize, PageCount: 1},
+		Items:      []entity.StatementResult{{ID: "stmt-1", FeedConnectionID: "conn-123", Status: domain.StatementStatusPending}},
+	}, nil
+}
+
+func (r *countingRepository) PostStatements(ctx context.Context, request entity.StatementRequest) (*entity.StatementResponse, error) {
+	return &entity.StatementResponse{Items: []entity.StatementResult{{ID: "stmt-2", FeedConnectionID: "conn-123", Status: domain.StatementStatusPending}}}, nil
+}
+
+func newCachedService(repository *countingRepository) *bankFeedService {
+	conf := &config.Configuration{}
+	conf.Xero.ConnectionCacheTTL = time.Minute
+	conf.Xero.ConnectionsCacheTTL = time.Minute
+	conf.Xero.StatementCacheTTL = time.Minute
+	conf.Xero.StatementsCacheTTL = time.Minute
+
+	return &bankFeedService{
+		BankFeedRepository: repository,
+		Configuration:      conf,
+		Store:              nopStore{},
+		Webhooks:           nopPublisher{},
+		cache:              newServiceCache(inmem.NewInMemoryCache(0, 0), logger.NewLogger()),
+		logger:             logger.NewLogger(),
+	}
+}
+
+func TestGetConnectionByIDReadsThrough(t *testing.T) {
+	repository := &countingRepository{}
+	service := newCachedService(repository)
+	ctx := context.Background()
+
+	for range 2 {
+		connection, err := service.GetConnectionByID(ctx, "id-1")
+		require.NoError(t, err)
+		assert.Equal(t, "NZD", connection.Currency)
+	}
+	assert.Equal(t, 1, repository.connectionReads)
+
+	_, err := service.DeleteConnection(ctx, "id-1")
+	require.NoError(t, err)
+
+	_, err = service.GetConnectionByID(ctx, "id-1")
+	require.NoError(t, err)
+	assert.Equal(t, 2, repository.connectionReads)
+}
+
+func TestGetConnectionsInvalidatedOnCreate(t *testing.T) {
+	repository := &countingRepository{}
+	service := newCachedService(repository)
+	ctx := context.Background()
+
+	for range 2 {
+		response, err := service.GetConnections(ctx, 1, 20)
+		require.NoError(t, err)
+		assert.Empty(t, response.Items)
+	}
+	assert.Equal(t, 1, repository.listReads)
+
+	_, err := service.CreateConnections(ctx, domain.CreateConnectionsRequest{Items: []domain.CreateConnectionItem{{AccountToken: "token-1"}}})
+	require.NoError(t, err)
+
+	response, err := service.GetConnections(ctx, 1, 20)
+	require.NoError(t, err)
+	assert.Len(t, response.Items, 1)
+	assert.Equal(t, 2, repository.listReads)
+
+	// Other page sizes are cached separately
+	_, err = service.GetConnections(ctx, 1, 50)
+	require.NoError(t, err)
+	assert.Equal(t, 3, repository.listReads)
+}
+
+func TestGetStatementByIDCachingDisabled(t *testing.T) {
+	repository := &countingRepository{}
+	service := newCachedService(repository)
+	service.Xero.StatementCacheTTL = 0
+
+	for range 2 {
+		_, err := service.GetStatementByID(context.Background(), "stmt-1")
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 2, repository.statementReads)
+}
+
+func TestFilteredStatementsReadCachedPages(t *testing.T) {
+	repository := &countingRepository{}
+	service := newCachedService(repository)
+	ctx := context.Background()
+	filter := domain.StatementFilter{Status: domain.StatementStatusPending}
+
+	for range 2 {
+		response, err := service.GetStatements(ctx, filter, 1, 20)
+		require.NoError(t, err)
+		assert.Len(t, response.Items, 1)
+	}
+	assert.Equal(t, 1, repository.pageReads)
+
+	// Posting a statement changes the list
+	_, err := service.PostStatements(ctx, domain.PostStatementRequest{Items: []domain.PostStatementItem{validStatement()}, Dedup: domain.DedupOff})
+	require.NoError(t, err)
+
+	_, err = service.GetStatements(ctx, filter, 1, 20)
+	require.NoError(t, err)
+	assert.Equal(t, 2, repository.pageReads)
+}
@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+	"usdw/internal/domain"
+)
+
+// continuityStatement is the part of a statement the continuity check needs
+type continuityStatement struct {
+	ID           string
+	StartDate    string
+	EndDate      string
+	StartBalance *domain.Balance
+	EndBalance   *domain.Balance
+}
+
+func (s *bankFeedService) GetConnectionContinuity(ctx context.Context, request domain.ContinuityRequest) (*domain.ContinuityReport, error) {
+	var statements []continuityStatement
+	var err error
+	if request.FromLocal {
+		statements, err = s.localContinuityStatements(ctx, request.FeedConnectionID)
+	} else {
+		statements, err = s.xeroContinuityStatements(ctx, request.FeedConnectionID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	report := checkContinuity(statements, request.StartDate, request.EndDate)
+	report.FeedConnectionID = request.FeedConnectionID
+	return report, nil
+}
+
+func (s *bankFeedService) localContinuityStatements(ctx context.Context, feedConnectionID string) ([]continuityStatement, error) {
+	tenantID, err := s.BankFeedRepository.TenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := s.Store.ListConnectionStatements(ctx, tenantID, feedConnectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	statements := make([]continuityStatement, len(records))
+	for i, record := range records {
+		statements[i] = continuityStatement{
+			ID:           record.XeroID,
+			StartDate:    record.StartDate,
+			EndDate:      record.EndDate,
+			StartBalance: &domain.Balance{Amount: record.StartBalance, CreditDebitIndicator: record.StartIndicator},
+			EndBalance:   &domain.Balance{Amount: record.EndBalance, CreditDebitIndicator: record.EndIndicator},
+		}
+	}
+	return statements, nil
+}
+
+// xeroContinuityStatements pages through every statement of the tenant, as Xero
+// cannot filter them by feed connection
+func (s *bankFeedService) xeroContinuityStatements(ctx context.Context, feedConnectionID string) ([]continuityStatement, error) {
+	// Fails with a 404 for unknown connections
+	_, err := s.GetConnectionByID(ctx, feedConnectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	var statements []continuityStatement
+	for item, err := range s.AllStatements(ctx, domain.StatementFilter{FeedConnectionID: feedConnectionID}) {
+		if err != nil {
+			return nil, err
+		}
+		if item.Status == domain.StatementStatusRejected {
+			continue
+		}
+		statements = append(statements, continuityStatement{
+			ID:           item.ID,
+			StartDate:    item.StartDate,
+			EndDate:      item.EndDate,
+			StartBalance: item.StartBalance,
+			EndBalance:   item.EndBalance,
+		})
+	}
+	return statements, nil
+}
+
+// checkContinuity walks the statements in date order, optionally within startDate to
+// endDate, and reports the days no statement covers, the days two statements cover and
+// the opening balances that do not follow on from the statement before
+func checkContinuity(statements []continuityStatement, startDate, endDate string) *domain.ContinuityReport {
+	report := &domain.ContinuityReport{
+		StartDate:     startDate,
+		EndDate:       endDate,
+		Gaps:          []domain.DateGap{},
+		Overlaps:      []domain.StatementOverlap{},
+		BalanceBreaks: []domain.BalanceBreak{},
+	}
+
+	statements = slices.DeleteFunc(slices.Clone(statements), func(statement continuityStatement) bool {
+		return startDate != "" && statement.EndDate < startDate || endDate != "" && statement.StartDate > endDate
+	})
+	slices.SortStableFunc(statements, func(a, b continuityStatement) int {
+		if c := strings.Compare(a.StartDate, b.StartDate); c != 0 {
+			return c
+		}
+		return strings.Compare(a.EndDate, b.EndDate)
+	})
+	report.StatementCount = len(statements)
+
+	// covered is the statement reaching furthest so far
+	var covered *continuityStatement
+	for i := range statements {
+		statement := &statements[i]
+
+		switch {
+		case covered == nil:
+			if startDate != "" && statement.StartDate > startDate {
+				report.Gaps = append(report.Gaps, newGap(startDate, dayBefore(statement.StartDate), "", statement.ID))
+			}
+		case statement.StartDate > dayAfter(covered.EndDate):
+			report.Gaps = append(report.Gaps, newGap(dayAfter(covered.EndDate), dayBefore(statement.StartDate), covered.ID, statement.ID))
+		case statement.StartDate <= covered.EndDate:
+			report.Overlaps = append(report.Overlaps, domain.StatementOverlap{
+				StartDate:   statement.StartDate,
+				EndDate:     min(covered.EndDate, statement.EndDate),
+				StatementID: statement.ID,
+				OtherID:     covered.ID,
+			})
+		}
+
+		// Overlapping statements repeat days, so their balances are not expected to follow on
+		if covered != nil && statement.StartDate > covered.EndDate {
+			if balanceBreak, ok := newBalanceBreak(covered, statement); ok {
+				report.BalanceBreaks = append(report.BalanceBreaks, balanceBreak)
+			}
+		}
+
+		if covered == nil || statement.EndDate > covered.EndDate {
+			covered = statement
+		}
+	}
+
+	switch {
+	case covered == nil && startDate != "" && endDate != "":
+		report.Gaps = append(report.Gaps, newGap(startDate, endDate, "", ""))
+	case covered != nil && endDate != "" && covered.EndDate < endDate:
+		report.Gaps = append(report.Gaps, newGap(dayAfter(covered.EndDate), endDate, covered.ID, ""))
+	}
+
+	report.Continuous = len(report.Gaps) == 0 && len(report.Overlaps) == 0 && len(report.BalanceBreaks) == 0
+	return report
+}
+
+func newGap(startDate, endDate, previousID, nextID string) domain.DateGap {
+	gap := domain.DateGap{
+		StartDate:           startDate,
+		EndDate:             endDate,
+		PreviousStatementID: previousID,
+		NextStatementID:     nextID,
+	}
+	start, startErr := time.Parse(isoDateLayout, startDate)
+	end, endErr := time.Parse(isoDateLayout, endDate)
+	if startErr == nil && endErr == nil {
+		gap.Days = int(end.Sub(start).Hours()/24) + 1
+	}
+	return gap
+}
+
+func newBalanceBreak(previous, statement *continuityStatement) (domain.BalanceBreak, bool) {
+	if previous.EndBalance == nil || statement.StartBalance == nil {
+		return domain.BalanceBreak{}, false
+	}
+
+	end := signedAmount(previous.EndBalance.Amount, previous.EndBalance.CreditDebitIndicator)
+	start := signedAmount(statement.StartBalance.Amount, statement.StartBalance.CreditDebitIndicator)
+	if start.Equal(end) {
+		return domain.BalanceBreak{}, false
+	}
+
+	return domain.BalanceBreak{
+		PreviousStatementID: previous.ID,
+		StatementID:         statement.ID,
+		PreviousEndBalance:  *previous.EndBalance,
+		StartBalance:        *statement.StartBalance,
+		Difference:          start.Sub(end),
+	}, true
+}
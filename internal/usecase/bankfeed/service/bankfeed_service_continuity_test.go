@@ -0,0 +1,60 @@
+package service
+
+import (
+	"testing"
+	"usdw/internal/domain"
+	"usdw/pkg/money"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func continuity(id, startDate, endDate, startBalance, endBalance string) continuityStatement {
+	balance := func(value string) *domain.Balance {
+		return &domain.Balance{Amount: money.MustParse(value), CreditDebitIndicator: indicatorCredit}
+	}
+	return continuityStatement{ID: id, StartDate: startDate, EndDate: endDate, StartBalance: balance(startBalance), EndBalance: balance(endBalance)}
+}
+
+func TestCheckContinuityContinuous(t *testing.T) {
+	report := checkContinuity([]continuityStatement{
+		continuity("stmt-2", "2023-02-01", "2023-02-28", "150", "90"),
+		continuity("stmt-1", "2023-01-01", "2023-01-31", "100", "150"),
+	}, "", "")
+
+	assert.True(t, report.Continuous)
+	assert.Equal(t, 2, report.StatementCount)
+	assert.Empty(t, report.Gaps)
+	assert.Empty(t, report.Overlaps)
+	assert.Empty(t, report.BalanceBreaks)
+}
+
+func TestCheckContinuityProblems(t *testing.T) {
+	report := checkContinuity([]continuityStatement{
+		continuity("stmt-1", "2023-01-01", "2023-01-10", "100", "150"),
+		continuity("stmt-2", "2023-01-11", "2023-01-20", "140", "160"),
+		continuity("stmt-3", "2023-01-18", "2023-01-22", "160", "170"),
+		continuity("stmt-4", "2023-01-26", "2023-01-27", "170", "180"),
+		continuity("stmt-5", "2023-03-01", "2023-03-31", "180", "190"),
+	}, "2023-01-05", "2023-01-31")
+
+	assert.False(t, report.Continuous)
+	assert.Equal(t, 4, report.StatementCount)
+	assert.Equal(t, []domain.DateGap{
+		{StartDate: "2023-01-23", EndDate: "2023-01-25", Days: 3, PreviousStatementID: "stmt-3", NextStatementID: "stmt-4"},
+		{StartDate: "2023-01-28", EndDate: "2023-01-31", Days: 4, PreviousStatementID: "stmt-4"},
+	}, report.Gaps)
+	assert.Equal(t, []domain.StatementOverlap{
+		{StartDate: "2023-01-18", EndDate: "2023-01-20", StatementID: "stmt-3", OtherID: "stmt-2"},
+	}, report.Overlaps)
+	if assert.Len(t, report.BalanceBreaks, 1) {
+		assert.Equal(t, "stmt-2", report.BalanceBreaks[0].StatementID)
+		assert.Equal(t, "-10", report.BalanceBreaks[0].Difference.String())
+	}
+}
+
+func TestCheckContinuityEmptyPeriod(t *testing.T) {
+	report := checkContinuity(nil, "2023-02-01", "2023-02-28")
+
+	assert.False(t, report.Continuous)
+	assert.Equal(t, []domain.DateGap{{StartDate: "2023-02-01", EndDate: "2023-02-28", Days: 28}}, report.Gaps)
+}
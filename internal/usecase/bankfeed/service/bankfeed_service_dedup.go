@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"usdw/internal/domain"
+	"usdw/internal/domain/entity"
+	"usdw/pkg/common/exception"
+)
+
+// dedupResult is the request left to post after deduplication
+type dedupResult struct {
+	request  domain.PostStatementRequest
+	skipped  []domain.StatementResult
+	stripped []domain.StrippedLines
+}
+
+// dedupStatements compares the request with the transactions and date ranges already
+// posted to each feed connection. In flag mode repeats fail validation; in strip mode
+// posted lines are left out and the statement starts after the posted ranges.
+func (s *bankFeedService) dedupStatements(ctx context.Context, request domain.PostStatementRequest) (*dedupResult, error) {
+	mode := request.Dedup
+	if mode == "" {
+		mode = s.Xero.DedupMode
+	}
+	switch mode {
+	case domain.DedupOff:
+		return &dedupResult{request: request}, nil
+	case domain.DedupFlag, domain.DedupStrip:
+	default:
+		return nil, exception.BadRequestError{Message: "Unsupported dedup mode " + mode + ", expected off, flag or strip"}
+	}
+
+	tenantID, err := s.BankFeedRepository.TenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &dedupResult{request: domain.PostStatementRequest{Items: make([]domain.PostStatementItem, 0, len(request.Items))}}
+	results := make([]domain.StatementResult, len(request.Items))
+	invalid := false
+
+	for i, item := range request.Items {
+		v := &statementValidator{}
+		results[i] = domain.StatementResult{FeedConnectionID: item.FeedConnectionID, Status: domain.StatementStatusValid}
+
+		posted, err := s.postedTransactions(ctx, tenantID, item)
+		if err != nil {
+			return nil, err
+		}
+
+		if mode == domain.DedupStrip {
+			var strippedIDs []string
+			item, strippedIDs = stripPostedLines(item, posted)
+			if len(strippedIDs) > 0 {
+				result.stripped = append(result.stripped, domain.StrippedLines{FeedConnectionID: item.FeedConnectionID, TransactionIDs: strippedIDs})
+			}
+			if len(item.StatementLines) == 0 {
+				results[i].Status = domain.StatementStatusSkipped
+				result.skipped = append(result.skipped, domain.StatementResult{
+					FeedConnectionID: item.FeedConnectionID,
+					Status:           domain.StatementStatusSkipped,
+					StartDate:        item.StartDate,
+					EndDate:          item.EndDate,
+				})
+				continue
+			}
+		} else {
+			for j, line := range item.StatementLines {
+				if posted[line.TransactionID] {
+					v.add("duplicate-transaction", "Duplicate Transaction",
+						fmt.Sprintf("statementLines[%d].transactionId %q was already posted to this feed connection", j, line.TransactionID))
+				}
+			}
+		}
+
+		overlaps, err := s.Store.FindOverlappingStatements(ctx, tenantID, item.FeedConnectionID, item.StartDate, item.EndDate)
+		if err != nil {
+			return nil, err
+		}
+		if mode == domain.DedupStrip {
+			item, overlaps = startAfter(item, overlaps)
+		}
+		for _, overlap := range overlaps {
+			v.add("overlapping-statement", "Overlapping Statement",
+				fmt.Sprintf("%s to %s overlaps statement %s posted for %s to %s", item.StartDate, item.EndDate, overlap.XeroID, overlap.StartDate, overlap.EndDate))
+		}
+
+		if len(v.errors) > 0 {
+			invalid = true
+			results[i].Status = domain.StatementStatusRejected
+			results[i].Errors = &v.errors
+		}
+		result.request.Items = append(result.request.Items, item)
+	}
+
+	if invalid {
+		return nil, &domain.StatementValidationError{Items: results}
+	}
+	return result, nil
+}
+
+// postedTransactions returns the transaction IDs of the statement already posted to its connection
+func (s *bankFeedService) postedTransactions(ctx context.Context, tenantID string, item domain.PostStatementItem) (map[string]bool, error) {
+	transactionIDs := make([]string, len(item.StatementLines))
+	for i, line := range item.StatementLines {
+		transactionIDs[i] = line.TransactionID
+	}
+
+	found, err := s.Store.FindPostedTransactions(ctx, tenantID, item.FeedConnectionID, transactionIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	posted := make(map[string]bool, len(found))
+	for _, transactionID := range found {
+		posted[transactionID] = true
+	}
+	return posted, nil
+}
+
+// stripPostedLines leaves out the posted lines and adds them to the opening balance,
+// so the balances still add up
+func stripPostedLines(item domain.PostStatementItem, posted map[string]bool) (domain.PostStatementItem, []string) {
+	if len(posted) == 0 {
+		return item, nil
+	}
+
+	var strippedIDs []string
+	lines := make([]domain.StatementLine, 0, len(item.StatementLines))
+	balance := signedAmount(item.StartBalance.Amount, item.StartBalance.CreditDebitIndicator)
+	for _, line := range item.StatementLines {
+		if posted[line.TransactionID] {
+			strippedIDs = append(strippedIDs, line.TransactionID)
+			balance = balance.Add(signedAmount(line.Amount, line.CreditDebitIndicator))
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	item.StatementLines = lines
+	item.StartBalance = balanceOf(balance)
+	return item, strippedIDs
+}
+
+// startAfter moves the start date past the posted statements when every remaining line
+// comes after them. It returns the overlaps that could not be avoided.
+func startAfter(item domain.PostStatementItem, overlaps []entity.StatementRecord) (domain.PostStatementItem, []entity.StatementRecord) {
+	if len(overlaps) == 0 {
+		return item, nil
+	}
+
+	postedEnd := overlaps[0].EndDate
+	for _, overlap := range overlaps {
+		postedEnd = max(postedEnd, overlap.EndDate)
+	}
+	firstLine := slices.MinFunc(item.StatementLines, func(a, b domain.StatementLine) int {
+		return strings.Compare(a.PostedDate, b.PostedDate)
+	}).PostedDate
+
+	if postedEnd >= item.EndDate || firstLine <= postedEnd {
+		return item, overlaps
+	}
+	item.StartDate = dayAfter(postedEnd)
+	return item, nil
+}
+
+func dayAfter(date string) string {
+	day, err := time.Parse(isoDateLayout, date)
+	if err != nil {
+		return date
+	}
+	return day.AddDate(0, 0, 1).Format(isoDateLayout)
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"usdw/config"
+	"usdw/internal/domain"
+	"usdw/internal/domain/entity"
+	"usdw/internal/usecase/bankfeed/repository"
+	"usdw/pkg/common/exception"
+	"usdw/pkg/db"
+	"usdw/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStripPostedLines(t *testing.T) {
+	item, stripped := stripPostedLines(validStatement(), map[string]bool{"txn-001": true})
+
+	assert.Equal(t, []string{"txn-001"}, stripped)
+	assert.Len(t, item.StatementLines, 1)
+	assert.Equal(t, "170.3", item.StartBalance.Amount.String())
+	assert.Equal(t, "CREDIT", item.StartBalance.CreditDebitIndicator)
+	assert.Empty(t, validateStatement(item, 0, "USD"))
+
+	unchanged, stripped := stripPostedLines(validStatement(), nil)
+	assert.Empty(t, stripped)
+	assert.Equal(t, validStatement(), unchanged)
+}
+
+func TestStartAfter(t *testing.T) {
+	posted := []entity.StatementRecord{
+		{XeroID: "stmt-1", StartDate: "2022-12-01", EndDate: "2023-01-05"},
+		{XeroID: "stmt-2", StartDate: "2023-01-06", EndDate: "2023-01-09"},
+	}
+
+	// Every line is after the posted statements, so the start moves past them
+	item, overlaps := startAfter(validStatement(), posted)
+	assert.Empty(t, overlaps)
+	assert.Equal(t, "2023-01-10", item.StartDate)
+	assert.Empty(t, validateStatement(item, 0, ""))
+
+	// A posted statement covering a line cannot be avoided
+	posted[1].EndDate = "2023-01-10"
+	item, overlaps = startAfter(validStatement(), posted)
+	assert.Len(t, overlaps, 2)
+	assert.Equal(t, "2023-01-01", item.StartDate)
+
+	item, overlaps = startAfter(validStatement(), nil)
+	assert.Empty(t, overlaps)
+	assert.Equal(t, validStatement(), item)
+}
+
+// tenantRepository answers TenantID and nothing else
+type tenantRepository struct {
+	domain.BankFeedRepository
+}
+
+func (tenantRepository) TenantID(ctx context.Context) (string, error) {
+	return "tenant-a", nil
+}
+
+func newDedupService(t *testing.T, mode string) *bankFeedService {
+	conf := &config.Configuration{Database: config.DatabaseConfig{Driver: "sqlite", SQLitePath: filepath.Join(t.TempDir(), "usdw.db")}}
+	conf.Xero.DedupMode = mode
+	database, err := db.NewDB(conf, logger.NewLogger())
+	require.NoError(t, err)
+	t.Cleanup(func() { database.Close() })
+	require.NoError(t, database.AutoMigrate(&entity.StatementRecord{}, &entity.PostedTransactionRecord{}))
+
+	store := repository.NewBankFeedStore(database)
+	statements := []entity.StatementRecord{
+		{TenantID: "tenant-a", XeroID: "stmt-1", FeedConnectionID: "conn-123", Status: "DELIVERED", StartDate: "2022-12-01", EndDate: "2023-01-10"},
+	}
+	require.NoError(t, store.SaveStatements(context.Background(), statements))
+	require.NoError(t, store.SavePostedTransactions(context.Background(), []entity.PostedTransactionRecord{
+		{TenantID: "tenant-a", FeedConnectionID: "conn-123", TransactionID: "txn-001", StatementID: statements[0].ID},
+	}))
+
+	return &bankFeedService{
+		BankFeedRepository: tenantRepository{},
+		Configuration:      conf,
+		Store:              store,
+	}
+}
+
+func TestDedupStatementsFlag(t *testing.T) {
+	service := newDedupService(t, domain.DedupFlag)
+
+	_, err := service.dedupStatements(context.Background(), domain.PostStatementRequest{Items: []domain.PostStatementItem{validStatement()}})
+
+	var validationErr *domain.StatementValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, []string{"duplicate-transaction", "overlapping-statement"}, errorTypes(validationErr.Items[0]))
+
+	// The request can still choose to post everything
+	result, err := service.dedupStatements(context.Background(), domain.PostStatementRequest{Items: []domain.PostStatementItem{validStatement()}, Dedup: domain.DedupOff})
+	require.NoError(t, err)
+	assert.Equal(t, validStatement(), result.request.Items[0])
+}
+
+func TestDedupStatementsStrip(t *testing.T) {
+	service := newDedupService(t, domain.DedupStrip)
+
+	result, err := service.dedupStatements(context.Background(), domain.PostStatementRequest{Items: []domain.PostStatementItem{validStatement()}})
+	require.NoError(t, err)
+
+	require.Len(t, result.request.Items, 1)
+	item := result.request.Items[0]
+	assert.Equal(t, "2023-01-11", item.StartDate)
+	assert.Len(t, item.StatementLines, 1)
+	assert.Empty(t, validateStatement(item, 0, ""))
+	assert.Equal(t, []domain.StrippedLines{{FeedConnectionID: "conn-123", TransactionIDs: []string{"txn-001"}}}, result.stripped)
+	assert.Empty(t, result.skipped)
+
+	_, err = service.dedupStatements(context.Background(), domain.PostStatementRequest{Items: []domain.PostStatementItem{validStatement()}, Dedup: "skip"})
+	assert.ErrorAs(t, err, &exception.BadRequestError{})
+}
@@ -0,0 +1,29 @@
+package service
+
+import (
+	"context"
+	"usdw/internal/domain"
+)
+
+// publish notifies webhook subscribers. Like the local history it is best effort and
+// never fails a request that Xero has already accepted.
+func (s *bankFeedService) publish(ctx context.Context, eventType string, data interface{}) {
+	tenantID, err := s.BankFeedRepository.TenantID(ctx)
+	if err == nil {
+		err = s.Webhooks.Publish(ctx, tenantID, eventType, data)
+	}
+	if err != nil {
+		s.logger.Errorf("Failed to publish %s webhook: %s", eventType, err)
+	}
+}
+
+// statementEvent returns the webhook event for a statement that reached a final status
+func statementEvent(status string) (string, bool) {
+	switch status {
+	case domain.StatementStatusDelivered:
+		return domain.EventStatementDelivered, true
+	case domain.StatementStatusRejected:
+		return domain.EventStatementRejected, true
+	}
+	return "", false
+}
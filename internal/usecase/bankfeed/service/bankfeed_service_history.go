@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+	"usdw/internal/domain"
+	"usdw/internal/domain/entity"
+	"usdw/pkg/common/exception"
+
+	"gorm.io/gorm"
+)
+
+// The local history is best effort: Xero has already accepted the request, so a
+// failure to record it is logged instead of being returned to the caller.
+
+func (s *bankFeedService) recordConnections(ctx context.Context, request domain.CreateConnectionsRequest, response *entity.FeedConnectionResponse) {
+	tenantID, err := s.BankFeedRepository.TenantID(ctx)
+	if err != nil {
+		s.logger.Errorf("Failed to record feed connections: %s", err)
+		return
+	}
+
+	items := make(map[string]domain.CreateConnectionItem, len(request.Items))
+	for _, item := range request.Items {
+		items[item.AccountToken] = item
+	}
+
+	records := make([]entity.FeedConnectionRecord, len(response.Items))
+	for i, result := range response.Items {
+		item := items[result.AccountToken]
+		records[i] = entity.FeedConnectionRecord{
+			TenantID:      tenantID,
+			XeroID:        result.ID,
+			AccountToken:  result.AccountToken,
+			AccountNumber: item.AccountNumber,
+			AccountName:   item.AccountName,
+			AccountType:   item.AccountType,
+			AccountID:     item.AccountID,
+			Currency:      item.Currency,
+			Country:       item.Country,
+			Status:        result.Status,
+			Error:         marshalRecord(result.Error),
+		}
+	}
+
+	err = s.Store.SaveConnections(ctx, records)
+	if err != nil {
+		s.logger.Errorf("Failed to record feed connections: %s", err)
+	}
+}
+
+func (s *bankFeedService) recordConnectionDeleted(ctx context.Context, feedConnectionID string) {
+	tenantID, err := s.BankFeedRepository.TenantID(ctx)
+	if err == nil {
+		err = s.Store.MarkConnectionDeleted(ctx, tenantID, feedConnectionID)
+	}
+	if err != nil {
+		s.logger.Errorf("Failed to record deletion of feed connection %s: %s", feedConnectionID, err)
+	}
+}
+
+// recordStatements stores the posted statements. Xero answers with one result per
+// statement in request order.
+func (s *bankFeedService) recordStatements(ctx context.Context, request domain.PostStatementRequest, response *entity.StatementResponse) {
+	tenantID, err := s.BankFeedRepository.TenantID(ctx)
+	if err != nil {
+		s.logger.Errorf("Failed to record statements: %s", err)
+		return
+	}
+
+	nextPoll := time.Now().Add(s.Xero.StatusPollBackoff)
+	records := make([]entity.StatementRecord, 0, len(request.Items))
+	for i, item := range request.Items {
+		record := entity.StatementRecord{
+			TenantID:           tenantID,
+			FeedConnectionID:   item.FeedConnectionID,
+			StartDate:          item.StartDate,
+			EndDate:            item.EndDate,
+			StartBalance:       item.StartBalance.Amount,
+			StartIndicator:     item.StartBalance.CreditDebitIndicator,
+			EndBalance:         item.EndBalance.Amount,
+			EndIndicator:       item.EndBalance.CreditDebitIndicator,
+			StatementLineCount: len(item.StatementLines),
+			StatementLines:     marshalRecord(item.StatementLines),
+		}
+		if i < len(response.Items) {
+			result := response.Items[i]
+			record.XeroID = result.ID
+			record.Status = result.Status
+			if result.Errors != nil {
+				record.Errors = marshalRecord(result.Errors)
+			}
+			if result.Status == domain.StatementStatusPending {
+				record.NextPollAt = &nextPoll
+			}
+		}
+		records = append(records, record)
+	}
+
+	err = s.Store.SaveStatements(ctx, records)
+	if err != nil {
+		s.logger.Errorf("Failed to record statements: %s", err)
+		return
+	}
+
+	// Remember the transactions Xero accepted so they are not posted twice
+	var transactions []entity.PostedTransactionRecord
+	for i, record := range records {
+		if record.XeroID == "" || record.Status == domain.StatementStatusRejected {
+			continue
+		}
+		for _, line := range request.Items[i].StatementLines {
+			transactions = append(transactions, entity.PostedTransactionRecord{
+				TenantID:         tenantID,
+				FeedConnectionID: record.FeedConnectionID,
+				TransactionID:    line.TransactionID,
+				StatementID:      record.ID,
+				PostedDate:       line.PostedDate,
+			})
+		}
+	}
+
+	err = s.Store.SavePostedTransactions(ctx, transactions)
+	if err != nil {
+		s.logger.Errorf("Failed to record posted transactions: %s", err)
+	}
+}
+
+func (s *bankFeedService) GetLocalConnections(ctx context.Context, page, pageSize int) (*domain.ConnectionsResponse, error) {
+	tenantID, err := s.BankFeedRepository.TenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	records, count, err := s.Store.ListConnections(ctx, tenantID, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]domain.Connection, len(records))
+	for i, record := range records {
+		items[i] = mapConnectionRecord(record)
+	}
+
+	return &domain.ConnectionsResponse{
+		Pagination: newPagination(page, pageSize, count),
+		Items:      items,
+	}, nil
+}
+
+func (s *bankFeedService) GetLocalStatements(ctx context.Context, filter domain.StatementFilter, page, pageSize int) (*domain.GetStatementsResponse, error) {
+	tenantID, err := s.BankFeedRepository.TenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	records, count, err := s.Store.ListStatements(ctx, tenantID, filter, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]domain.StatementResult, len(records))
+	for i, record := range records {
+		items[i] = mapStatementRecord(record)
+	}
+
+	pagination := newPagination(page, pageSize, count)
+	return &domain.GetStatementsResponse{
+		Pagination: &pagination,
+		Items:      items,
+	}, nil
+}
+
+// GetStatementStatus answers from the local store, which the statement poller keeps up to date
+func (s *bankFeedService) GetStatementStatus(ctx context.Context, statementID string) (*domain.StatementStatus, error) {
+	tenantID, err := s.BankFeedRepository.TenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := s.Store.GetStatement(ctx, tenantID, statementID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, exception.NotFoundError{Message: "statement " + statementID + " was not posted through this service"}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	status := &domain.StatementStatus{
+		ID:               record.XeroID,
+		FeedConnectionID: record.FeedConnectionID,
+		Status:           record.Status,
+		Errors:           unmarshalErrors(record.Errors),
+		Attempts:         record.Attempts,
+		LastCheckedAt:    record.LastCheckedAt,
+		PostedAt:         record.CreatedAt,
+	}
+	if record.Status == domain.StatementStatusPending {
+		status.NextCheckAt = record.NextPollAt
+	}
+	return status, nil
+}
+
+// marshalRecord encodes a value for a JSON text column, nil values are stored as ""
+func marshalRecord(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil || string(data) == "null" {
+		return ""
+	}
+	return string(data)
+}
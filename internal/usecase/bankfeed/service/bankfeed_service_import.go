@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"usdw/internal/domain"
+	"usdw/internal/usecase/bankfeed/importer"
+	"usdw/pkg/common/exception"
+
+	"gorm.io/gorm"
+)
+
+// ImportStatements converts a bank statement file and posts it like POST /statements
+func (s *bankFeedService) ImportStatements(ctx context.Context, request domain.ImportStatementsRequest) (*domain.ImportStatementsResponse, error) {
+	if request.FeedConnectionID == "" {
+		return nil, exception.BadRequestError{Message: "feedConnectionId is required"}
+	}
+
+	format := importer.Format(request.Format)
+	if format == "" && request.Profile == "" {
+		var err error
+		format, err = importer.DetectFormat(request.FileName, request.Data)
+		if err != nil {
+			return nil, exception.BadRequestError{Message: "Could not detect the file format, set format to ofx, qif, camt053, mt940 or csv"}
+		}
+	}
+
+	options := importer.Options{
+		DayFirst:     request.DayFirst,
+		StartBalance: request.StartBalance,
+	}
+	if request.Profile != "" {
+		profile, err := s.Profiles.GetProfile(ctx, request.Profile)
+		if err != nil {
+			return nil, err
+		}
+		if profile == nil {
+			return nil, exception.BadRequestError{Message: "Unknown CSV profile " + request.Profile}
+		}
+		options.Profile = profile
+		if request.Format == "" {
+			format = importer.FormatCSV
+		}
+	}
+
+	items, err := importer.Parse(format, request.Data, options)
+	if errors.Is(err, importer.ErrUnknownFormat) {
+		return nil, exception.BadRequestError{Message: "Unsupported format " + request.Format + ", expected ofx, qif, camt053, mt940 or csv"}
+	}
+	if errors.Is(err, importer.ErrProfileRequired) {
+		return nil, exception.BadRequestError{Message: "profile is required to import CSV files"}
+	}
+	if err != nil {
+		return nil, exception.BadRequestError{Message: err.Error()}
+	}
+
+	for i := range items {
+		items[i].FeedConnectionID = request.FeedConnectionID
+	}
+
+	response := &domain.ImportStatementsResponse{Format: string(format)}
+	if request.DryRun {
+		response.Statements = items
+		return response, nil
+	}
+
+	result, err := s.PostStatements(ctx, domain.PostStatementRequest{Items: items, Dedup: request.Dedup})
+	if err != nil {
+		return nil, err
+	}
+	response.Items = result.Items
+	response.Stripped = result.Stripped
+	return response, nil
+}
+
+func (s *bankFeedService) CreateImportProfile(ctx context.Context, profile domain.CSVProfile) (*domain.CSVProfile, error) {
+	err := importer.ValidateCSVProfile(profile)
+	if err != nil {
+		return nil, exception.BadRequestError{Message: err.Error()}
+	}
+
+	err = s.Profiles.SaveProfile(ctx, profile)
+	if err != nil {
+		return nil, err
+	}
+	profile.Source = "db"
+	return &profile, nil
+}
+
+func (s *bankFeedService) GetImportProfiles(ctx context.Context) ([]domain.CSVProfile, error) {
+	return s.Profiles.ListProfiles(ctx)
+}
+
+func (s *bankFeedService) DeleteImportProfile(ctx context.Context, name string) error {
+	err := s.Profiles.DeleteProfile(ctx, name)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return exception.NotFoundError{Message: "no stored CSV profile named " + name}
+	}
+	return err
+}
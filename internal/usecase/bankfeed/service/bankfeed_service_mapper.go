@@ -1,10 +1,37 @@
 package service
 
 import (
+	"encoding/json"
+	"strconv"
 	"usdw/internal/domain"
 	"usdw/internal/domain/entity"
 )
 
+func mapStatementRequest(request domain.PostStatementRequest) entity.StatementRequest {
+	entityRequest := entity.StatementRequest{
+		Items: make([]entity.StatementItem, len(request.Items)),
+	}
+
+	for i, item := range request.Items {
+		entityRequest.Items[i] = entity.StatementItem{
+			FeedConnectionID: item.FeedConnectionID,
+			StartDate:        item.StartDate,
+			EndDate:          item.EndDate,
+			StartBalance: entity.Balance{
+				Amount:               item.StartBalance.Amount,
+				CreditDebitIndicator: item.StartBalance.CreditDebitIndicator,
+			},
+			EndBalance: entity.Balance{
+				Amount:               item.EndBalance.Amount,
+				CreditDebitIndicator: item.EndBalance.CreditDebitIndicator,
+			},
+			StatementLines: mapStatementLines(item.StatementLines),
+		}
+	}
+
+	return entityRequest
+}
+
 func mapStatementLines(lines []domain.StatementLine) []entity.StatementLine {
 	entityLines := make([]entity.StatementLine, len(lines))
 	for i, line := range lines {
@@ -80,3 +107,71 @@ func mapBalance(entityBalance *entity.Balance) *domain.Balance {
 		CreditDebitIndicator: entityBalance.CreditDebitIndicator,
 	}
 }
+
+func mapConnectionRecord(record entity.FeedConnectionRecord) domain.Connection {
+	return domain.Connection{
+		ID:            record.XeroID,
+		AccountToken:  record.AccountToken,
+		AccountType:   record.AccountType,
+		AccountNumber: record.AccountNumber,
+		AccountName:   record.AccountName,
+		AccountID:     record.AccountID,
+		Currency:      record.Currency,
+	}
+}
+
+func mapStatementRecord(record entity.StatementRecord) domain.StatementResult {
+	result := domain.StatementResult{
+		ID:               record.XeroID,
+		FeedConnectionID: record.FeedConnectionID,
+		Status:           record.Status,
+		StartDate:        record.StartDate,
+		EndDate:          record.EndDate,
+		StartBalance: &domain.Balance{
+			Amount:               record.StartBalance,
+			CreditDebitIndicator: record.StartIndicator,
+		},
+		EndBalance: &domain.Balance{
+			Amount:               record.EndBalance,
+			CreditDebitIndicator: record.EndIndicator,
+		},
+		StatementLineCount: strconv.Itoa(record.StatementLineCount),
+	}
+
+	result.Errors = unmarshalErrors(record.Errors)
+
+	return result
+}
+
+// unmarshalErrors decodes the errors column of a record, "" meaning none
+func unmarshalErrors(value string) *[]domain.FeedError {
+	if value == "" {
+		return nil
+	}
+	var errors []domain.FeedError
+	if json.Unmarshal([]byte(value), &errors) != nil {
+		return nil
+	}
+	return &errors
+}
+
+func newPagination(page, pageSize, itemCount int) domain.Pagination {
+	return domain.Pagination{
+		Page:      page,
+		PageSize:  pageSize,
+		PageCount: (itemCount + pageSize - 1) / pageSize,
+		ItemCount: itemCount,
+	}
+}
+
+func mapConnection(connection entity.FeedConnection) domain.Connection {
+	return domain.Connection{
+		ID:            connection.ID,
+		AccountToken:  connection.AccountToken,
+		AccountType:   connection.AccountType,
+		AccountNumber: connection.AccountNumber,
+		AccountName:   connection.AccountName,
+		AccountID:     connection.AccountID,
+		Currency:      connection.Currency,
+	}
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"iter"
+	"usdw/internal/domain"
+)
+
+const (
+	// connectionsPageSize is the largest page Xero returns for GET /FeedConnections
+	connectionsPageSize = 100
+	// statementsPageSize is the largest page Xero returns for GET /Statements
+	statementsPageSize = 100
+)
+
+// fetchPage returns one page of items and the number of pages, or zero when unknown
+type fetchPage[T any] func(page, pageSize int) ([]T, int, error)
+
+// paginate yields the items of every page, fetching the next page only once the
+// previous one is consumed. It stops after the last page when the page count is
+// known, otherwise after a short or empty page, and after the first error.
+func paginate[T any](pageSize int, fetch fetchPage[T]) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for page := 1; ; page++ {
+			items, pageCount, err := fetch(page, pageSize)
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			for _, item := range items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			if len(items) < pageSize || pageCount > 0 && page >= pageCount {
+				return
+			}
+		}
+	}
+}
+
+// AllConnections iterates through every feed connection of the tenant
+func (s *bankFeedService) AllConnections(ctx context.Context) iter.Seq2[domain.Connection, error] {
+	return paginate(connectionsPageSize, func(page, pageSize int) ([]domain.Connection, int, error) {
+		response, err := s.BankFeedRepository.FetchConnections(ctx, page, pageSize)
+		if err != nil {
+			return nil, 0, err
+		}
+		connections := make([]domain.Connection, len(response.Items))
+		for i, connection := range response.Items {
+			connections[i] = mapConnection(connection)
+		}
+		return connections, response.Pagination.PageCount, nil
+	})
+}
+
+// AllStatements iterates through every statement of the tenant that matches the filter.
+// Pages are read through the statement list cache.
+func (s *bankFeedService) AllStatements(ctx context.Context, filter domain.StatementFilter) iter.Seq2[domain.StatementResult, error] {
+	statements := paginate(statementsPageSize, func(page, pageSize int) ([]domain.StatementResult, int, error) {
+		response, err := s.statementsPage(ctx, page, pageSize)
+		if err != nil {
+			return nil, 0, err
+		}
+		pageCount := 0
+		if response.Pagination != nil {
+			pageCount = response.Pagination.PageCount
+		}
+		return response.Items, pageCount, nil
+	})
+
+	return func(yield func(domain.StatementResult, error) bool) {
+		for statement, err := range statements {
+			if err == nil && !filter.Matches(statement) {
+				continue
+			}
+			if !yield(statement, err) {
+				return
+			}
+		}
+	}
+}
+
+// filterStatements returns one page of the statements matching the filter
+func (s *bankFeedService) filterStatements(ctx context.Context, filter domain.StatementFilter, page, pageSize int) (*domain.GetStatementsResponse, error) {
+	items := []domain.StatementResult{}
+	count := 0
+	for statement, err := range s.AllStatements(ctx, filter) {
+		if err != nil {
+			return nil, err
+		}
+		if count >= (page-1)*pageSize && count < page*pageSize {
+			items = append(items, statement)
+		}
+		count++
+	}
+
+	pagination := newPagination(page, pageSize, count)
+	return &domain.GetStatementsResponse{
+		Pagination: &pagination,
+		Items:      items,
+	}, nil
+}
+
+// allConnections collects every feed connection of the tenant
+func (s *bankFeedService) allConnections(ctx context.Context) ([]domain.Connection, error) {
+	var all []domain.Connection
+	for connection, err := range s.AllConnections(ctx) {
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, connection)
+	}
+	return all, nil
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+	"usdw/config"
+	"usdw/internal/domain"
+	"usdw/internal/domain/entity"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pagedRepository serves count statements and records the pages asked for
+type pagedRepository struct {
+	tenantRepository
+	count int
+	fail  int // page that fails, zero for none
+	pages []int
+	// noPagination leaves out the pagination, as Xero sometimes does
+	noPagination bool
+}
+
+func (r *pagedRepository) GetStatements(ctx context.Context, page, pageSize int) (*entity.StatementResponse, error) {
+	r.pages = append(r.pages, page)
+	if page == r.fail {
+		return nil, errors.New("xero is down")
+	}
+
+	response := &entity.StatementResponse{Pagination: &entity.Pagination{Page: page, PageSize: pageSize, PageCount: (r.count + pageSize - 1) / pageSize}}
+	for i := (page - 1) * pageSize; i < min(page*pageSize, r.count); i++ {
+		response.Items = append(response.Items, entity.StatementResult{
+			ID:                 fmt.Sprintf("statement-%d", i),
+			FeedConnectionID:   fmt.Sprintf("conn-%d", i%2),
+			StatementLineCount: strconv.Itoa(i),
+		})
+	}
+	if r.noPagination {
+		response.Pagination = nil
+	}
+	return response, nil
+}
+
+func newPagedService(repository *pagedRepository) *bankFeedService {
+	return &bankFeedService{BankFeedRepository: repository, Configuration: &config.Configuration{}}
+}
+
+func TestAllStatementsReadsEveryPage(t *testing.T) {
+	repository := &pagedRepository{count: 2*statementsPageSize + 1}
+	service := newPagedService(repository)
+
+	count := 0
+	for _, err := range service.AllStatements(context.Background(), domain.StatementFilter{}) {
+		require.NoError(t, err)
+		count++
+	}
+	assert.Equal(t, 2*statementsPageSize+1, count)
+	assert.Equal(t, []int{1, 2, 3}, repository.pages)
+}
+
+func TestAllStatementsWithoutPageCount(t *testing.T) {
+	repository := &pagedRepository{count: 2 * statementsPageSize, noPagination: true}
+	service := newPagedService(repository)
+
+	count := 0
+	for _, err := range service.AllStatements(context.Background(), domain.StatementFilter{}) {
+		require.NoError(t, err)
+		count++
+	}
+	// Without a page count the empty third page ends the iteration
+	assert.Equal(t, 2*statementsPageSize, count)
+	assert.Equal(t, []int{1, 2, 3}, repository.pages)
+}
+
+func TestAllStatementsIsLazy(t *testing.T) {
+	repository := &pagedRepository{count: 3 * statementsPageSize}
+	service := newPagedService(repository)
+
+	count := 0
+	for range service.AllStatements(context.Background(), domain.StatementFilter{}) {
+		count++
+		if count == statementsPageSize+1 {
+			break
+		}
+	}
+	// The third page is never fetched
+	assert.Equal(t, []int{1, 2}, repository.pages)
+}
+
+func TestAllStatementsStopsOnError(t *testing.T) {
+	repository := &pagedRepository{count: 3 * statementsPageSize, fail: 2}
+	service := newPagedService(repository)
+
+	count := 0
+	var lastErr error
+	for _, err := range service.AllStatements(context.Background(), domain.StatementFilter{}) {
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		count++
+	}
+	assert.Equal(t, statementsPageSize, count)
+	assert.EqualError(t, lastErr, "xero is down")
+	assert.Equal(t, []int{1, 2}, repository.pages)
+}
+
+func TestGetStatementsFiltersEveryPage(t *testing.T) {
+	repository := &pagedRepository{count: 2*statementsPageSize + 1}
+	service := newPagedService(repository)
+
+	// conn-1 has the odd statements, 50 of them have 100 or more lines
+	filter := domain.StatementFilter{FeedConnectionID: "conn-1", MinLines: statementsPageSize}
+	response, err := service.GetStatements(context.Background(), filter, 2, 20)
+	require.NoError(t, err)
+
+	assert.Equal(t, domain.Pagination{Page: 2, PageSize: 20, PageCount: 3, ItemCount: 50}, *response.Pagination)
+	require.Len(t, response.Items, 20)
+	assert.Equal(t, "statement-141", response.Items[0].ID)
+	assert.Equal(t, []int{1, 2, 3}, repository.pages)
+}
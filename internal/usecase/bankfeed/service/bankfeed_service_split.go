@@ -0,0 +1,159 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+	"usdw/internal/domain"
+	"usdw/pkg/money"
+)
+
+// statementChunk is a statement, or one part of a split statement, ready to be posted
+type statementChunk struct {
+	statement domain.PostStatementItem
+	size      int // bytes of JSON
+}
+
+// splitStatements breaks every statement of the request into consecutive parts of at
+// most maxLines lines and maxBytes of JSON; zero disables a limit. Lines posted on the
+// same day stay together so the date ranges of the parts do not overlap.
+func splitStatements(request domain.PostStatementRequest, maxLines, maxBytes int) []statementChunk {
+	var chunks []statementChunk
+	for _, item := range request.Items {
+		chunks = append(chunks, splitStatement(item, maxLines, maxBytes)...)
+	}
+	return chunks
+}
+
+func splitStatement(item domain.PostStatementItem, maxLines, maxBytes int) []statementChunk {
+	lines := slices.Clone(item.StatementLines)
+	slices.SortStableFunc(lines, func(a, b domain.StatementLine) int {
+		return strings.Compare(a.PostedDate, b.PostedDate)
+	})
+
+	header := item
+	header.StatementLines = nil
+	headerSize := jsonSize(mapStatementRequest(domain.PostStatementRequest{Items: []domain.PostStatementItem{header}}).Items[0])
+
+	// Cut before the first day that would overflow the current part
+	var parts [][]domain.StatementLine
+	var sizes []int
+	start, size := 0, headerSize
+	for day := 0; day < len(lines); {
+		end, daySize := day, 0
+		for end < len(lines) && lines[end].PostedDate == lines[day].PostedDate {
+			daySize += jsonSize(mapStatementLines(lines[end : end+1])[0]) + 1
+			end++
+		}
+		tooLong := maxLines > 0 && end-start > maxLines
+		tooLarge := maxBytes > 0 && size+daySize > maxBytes
+		if day > start && (tooLong || tooLarge) {
+			parts = append(parts, lines[start:day])
+			sizes = append(sizes, size)
+			start, size = day, headerSize
+		}
+		size += daySize
+		day = end
+	}
+
+	if len(parts) == 0 {
+		return []statementChunk{{statement: item, size: size}}
+	}
+	parts = append(parts, lines[start:])
+	sizes = append(sizes, size)
+
+	chunks := make([]statementChunk, len(parts))
+	balance := signedAmount(item.StartBalance.Amount, item.StartBalance.CreditDebitIndicator)
+	for i, part := range parts {
+		chunk := item
+		chunk.StatementLines = part
+		chunk.StartBalance = balanceOf(balance)
+		for _, line := range part {
+			balance = balance.Add(signedAmount(line.Amount, line.CreditDebitIndicator))
+		}
+		chunk.EndBalance = balanceOf(balance)
+
+		// Each part runs up to the day before the next one starts
+		if i > 0 {
+			chunk.StartDate = part[0].PostedDate
+		} else {
+			chunk.StartBalance = item.StartBalance
+		}
+		if i < len(parts)-1 {
+			chunk.EndDate = dayBefore(parts[i+1][0].PostedDate)
+		} else {
+			chunk.EndBalance = item.EndBalance
+		}
+
+		chunks[i] = statementChunk{statement: chunk, size: sizes[i]}
+	}
+	return chunks
+}
+
+// batchChunks groups chunks into requests of at most maxBytes and returns the chunk
+// indexes per request. The parts of a feed connection go into successive requests, so
+// Xero always has the previous closing balance when the next part arrives.
+func batchChunks(chunks []statementChunk, maxBytes int) [][]int {
+	var batches [][]int
+	var sizes []int
+	next := make(map[string]int) // first batch the next chunk of a connection may join
+
+	for i, chunk := range chunks {
+		b := next[chunk.statement.FeedConnectionID]
+		for b < len(batches) && maxBytes > 0 && sizes[b]+chunk.size > maxBytes {
+			b++
+		}
+		if b == len(batches) {
+			batches = append(batches, nil)
+			sizes = append(sizes, 0)
+		}
+		batches[b] = append(batches[b], i)
+		sizes[b] += chunk.size
+		next[chunk.statement.FeedConnectionID] = b + 1
+	}
+	return batches
+}
+
+// notPosted fills in the results of the chunks that were never sent
+func notPosted(response *domain.PostStatementResponse, chunks []statementChunk, err error) {
+	for i, result := range response.Items {
+		if result.Status != "" {
+			continue
+		}
+		chunk := chunks[i].statement
+		response.Items[i] = domain.StatementResult{
+			FeedConnectionID: chunk.FeedConnectionID,
+			Status:           domain.StatementStatusRejected,
+			StartDate:        chunk.StartDate,
+			EndDate:          chunk.EndDate,
+			Errors: &[]domain.FeedError{{
+				Type:   "not-posted",
+				Title:  "Statement Not Posted",
+				Status: http.StatusBadGateway,
+				Detail: "an earlier part of the statements was posted but this one failed: " + err.Error(),
+			}},
+		}
+	}
+}
+
+func balanceOf(amount money.Amount) domain.Balance {
+	if amount.IsNegative() {
+		return domain.Balance{Amount: amount.Abs(), CreditDebitIndicator: indicatorDebit}
+	}
+	return domain.Balance{Amount: amount, CreditDebitIndicator: indicatorCredit}
+}
+
+func dayBefore(date string) string {
+	day, err := time.Parse(isoDateLayout, date)
+	if err != nil {
+		return date
+	}
+	return day.AddDate(0, 0, -1).Format(isoDateLayout)
+}
+
+func jsonSize(v any) int {
+	data, _ := json.Marshal(v)
+	return len(data)
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+	"usdw/internal/domain"
+	"usdw/pkg/money"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func busyStatement() domain.PostStatementItem {
+	item := domain.PostStatementItem{
+		FeedConnectionID: "conn-123",
+		StartDate:        "2023-01-01",
+		EndDate:          "2023-01-31",
+		StartBalance:     domain.Balance{Amount: money.MustParse("10.00"), CreditDebitIndicator: "CREDIT"},
+		EndBalance:       domain.Balance{Amount: money.MustParse("2.00"), CreditDebitIndicator: "DEBIT"},
+	}
+	// Two debits of 2.00 on each of the 3rd, 5th and 9th, listed out of order
+	for i, day := range []string{"2023-01-09", "2023-01-03", "2023-01-05", "2023-01-03", "2023-01-05", "2023-01-09"} {
+		item.StatementLines = append(item.StatementLines, domain.StatementLine{
+			PostedDate:           day,
+			Amount:               money.MustParse("2.00"),
+			CreditDebitIndicator: "DEBIT",
+			TransactionID:        fmt.Sprintf("txn-%d", i),
+		})
+	}
+	return item
+}
+
+func TestSplitStatementWithinLimits(t *testing.T) {
+	item := busyStatement()
+	chunks := splitStatement(item, 6, 0)
+
+	require.Len(t, chunks, 1)
+	assert.Equal(t, item, chunks[0].statement)
+}
+
+func TestSplitStatementByLines(t *testing.T) {
+	// Three lines fit, but the two lines of a day are never separated
+	chunks := splitStatement(busyStatement(), 3, 0)
+	require.Len(t, chunks, 3)
+
+	expected := []struct {
+		startDate, endDate, startBalance, endBalance string
+	}{
+		{"2023-01-01", "2023-01-04", "10 CREDIT", "6 CREDIT"},
+		{"2023-01-05", "2023-01-08", "6 CREDIT", "2 CREDIT"},
+		{"2023-01-09", "2023-01-31", "2 CREDIT", "2 DEBIT"},
+	}
+	for i, chunk := range chunks {
+		statement := chunk.statement
+		assert.Equal(t, expected[i].startDate, statement.StartDate)
+		assert.Equal(t, expected[i].endDate, statement.EndDate)
+		assert.Equal(t, expected[i].startBalance, statement.StartBalance.Amount.String()+" "+statement.StartBalance.CreditDebitIndicator)
+		assert.Equal(t, expected[i].endBalance, statement.EndBalance.Amount.String()+" "+statement.EndBalance.CreditDebitIndicator)
+		assert.Len(t, statement.StatementLines, 2)
+		assert.Equal(t, statement.StatementLines[0].PostedDate, statement.StatementLines[1].PostedDate)
+		assert.Empty(t, validateStatement(statement, 3, "USD"))
+	}
+}
+
+func TestSplitStatementByBytes(t *testing.T) {
+	item := busyStatement()
+	whole := splitStatement(item, 0, 0)[0].size
+
+	chunks := splitStatement(item, 0, whole-1)
+	require.Len(t, chunks, 2)
+	for _, chunk := range chunks {
+		assert.Less(t, chunk.size, whole)
+		assert.Empty(t, validateStatement(chunk.statement, 0, ""))
+	}
+}
+
+func TestBatchChunks(t *testing.T) {
+	chunk := func(connection string, size int) statementChunk {
+		return statementChunk{statement: domain.PostStatementItem{FeedConnectionID: connection}, size: size}
+	}
+	chunks := []statementChunk{
+		chunk("conn-a", 40),
+		chunk("conn-a", 40),
+		chunk("conn-b", 40),
+		chunk("conn-c", 40),
+		chunk("conn-a", 10),
+	}
+
+	// Parts of one connection go into successive requests, others fill the gaps
+	assert.Equal(t, [][]int{{0, 2}, {1, 3}, {4}}, batchChunks(chunks, 100))
+	assert.Equal(t, [][]int{{0, 2, 3}, {1}, {4}}, batchChunks(chunks, 0))
+}
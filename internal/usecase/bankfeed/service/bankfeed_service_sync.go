@@ -0,0 +1,157 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"usdw/internal/domain"
+	"usdw/pkg/common/exception"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SyncConnections makes the tenant's feed connections match a manifest. Missing
+// connections are created; connections the manifest does not list are only deleted
+// when pruning. A dry run returns the plan without calling Xero for changes.
+func (s *bankFeedService) SyncConnections(ctx context.Context, request domain.SyncConnectionsRequest) (*domain.SyncConnectionsResponse, error) {
+	manifest, err := parseManifest(request.Manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	connections, err := s.allConnections(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &domain.SyncConnectionsResponse{
+		DryRun: request.DryRun,
+		Plan:   planSync(manifest, connections, request.Prune),
+	}
+	if request.DryRun {
+		return response, nil
+	}
+
+	if len(response.Plan.Create) > 0 {
+		response.Created, err = s.BulkCreateConnections(ctx, domain.BulkCreateConnectionsRequest{Items: response.Plan.Create})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(response.Plan.Delete) > 0 {
+		ids := make([]string, len(response.Plan.Delete))
+		for i, connection := range response.Plan.Delete {
+			ids[i] = connection.ID
+		}
+		response.Deleted, err = s.BulkDeleteConnections(ctx, domain.BulkDeleteConnectionsRequest{IDs: ids})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return response, nil
+}
+
+// parseManifest reads a YAML manifest, which also covers JSON. Unknown keys and a
+// manifest without connections are rejected: with prune, a typo in the top-level key
+// would otherwise delete every connection of the tenant.
+func parseManifest(data []byte) (*domain.ConnectionManifest, error) {
+	var manifest domain.ConnectionManifest
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	err := decoder.Decode(&manifest)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, exception.BadRequestError{Message: "Invalid manifest: " + err.Error()}
+	}
+	if len(manifest.Connections) == 0 {
+		return nil, exception.BadRequestError{Message: "Invalid manifest: it lists no connections"}
+	}
+
+	var problems []string
+	tokens := make(map[string]bool, len(manifest.Connections))
+	for i, item := range manifest.Connections {
+		switch {
+		case item.AccountToken == "":
+			problems = append(problems, fmt.Sprintf("connections[%d]: accountToken is required", i))
+		case tokens[item.AccountToken]:
+			problems = append(problems, fmt.Sprintf("connections[%d]: accountToken %q is listed twice", i, item.AccountToken))
+		}
+		tokens[item.AccountToken] = true
+		if item.AccountType == "" {
+			problems = append(problems, fmt.Sprintf("connections[%d]: accountType is required", i))
+		}
+		if item.Currency == "" {
+			problems = append(problems, fmt.Sprintf("connections[%d]: currency is required", i))
+		}
+	}
+	if len(problems) > 0 {
+		return nil, exception.BadRequestError{Message: "Invalid manifest: " + strings.Join(problems, "; ")}
+	}
+	return &manifest, nil
+}
+
+// planSync matches the manifest to the existing connections by account token
+func planSync(manifest *domain.ConnectionManifest, connections []domain.Connection, prune bool) domain.SyncPlan {
+	plan := domain.SyncPlan{
+		Create:     []domain.CreateConnectionItem{},
+		Delete:     []domain.Connection{},
+		Extra:      []domain.Connection{},
+		Unchanged:  []string{},
+		Mismatched: []domain.ConnectionMismatch{},
+	}
+
+	existing := make(map[string]domain.Connection, len(connections))
+	for _, connection := range connections {
+		existing[connection.AccountToken] = connection
+	}
+
+	listed := make(map[string]bool, len(manifest.Connections))
+	for _, item := range manifest.Connections {
+		listed[item.AccountToken] = true
+		connection, ok := existing[item.AccountToken]
+		if !ok {
+			plan.Create = append(plan.Create, item)
+			continue
+		}
+		if fields := mismatchedFields(item, connection); len(fields) > 0 {
+			plan.Mismatched = append(plan.Mismatched, domain.ConnectionMismatch{AccountToken: item.AccountToken, ID: connection.ID, Fields: fields})
+			continue
+		}
+		plan.Unchanged = append(plan.Unchanged, item.AccountToken)
+	}
+
+	for _, connection := range connections {
+		if listed[connection.AccountToken] {
+			continue
+		}
+		if prune {
+			plan.Delete = append(plan.Delete, connection)
+		} else {
+			plan.Extra = append(plan.Extra, connection)
+		}
+	}
+	return plan
+}
+
+// mismatchedFields compares the fields the manifest sets; optional ones left out are ignored
+func mismatchedFields(item domain.CreateConnectionItem, connection domain.Connection) []string {
+	var fields []string
+	if !strings.EqualFold(item.AccountType, connection.AccountType) {
+		fields = append(fields, "accountType")
+	}
+	if !strings.EqualFold(item.Currency, connection.Currency) {
+		fields = append(fields, "currency")
+	}
+	if item.AccountNumber != "" && item.AccountNumber != connection.AccountNumber {
+		fields = append(fields, "accountNumber")
+	}
+	if item.AccountName != "" && item.AccountName != connection.AccountName {
+		fields = append(fields, "accountName")
+	}
+	if item.AccountID != "" && item.AccountID != connection.AccountID {
+		fields = append(fields, "accountId")
+	}
+	return fields
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"usdw/internal/domain"
+	"usdw/internal/domain/entity"
+	"usdw/pkg/common/exception"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const syncManifest = `
+connections:
+  - accountToken: token-1
+    accountType: BANK
+    currency: NZD
+  - accountToken: token-2
+    accountType: BANK
+    currency: USD
+  - accountToken: token-3
+    accountType: CREDITCARD
+    currency: NZD
+`
+
+func newSyncRepository() *bulkRepository {
+	return &bulkRepository{connections: []entity.FeedConnection{
+		{ID: "id-1", AccountToken: "token-1", AccountType: "BANK", Currency: "NZD"},
+		{ID: "id-2", AccountToken: "token-2", AccountType: "BANK", Currency: "NZD"},
+		{ID: "id-4", AccountToken: "token-4", AccountType: "BANK", Currency: "NZD"},
+	}}
+}
+
+func TestSyncConnectionsDryRun(t *testing.T) {
+	repository := newSyncRepository()
+	service := newBulkService(repository)
+
+	response, err := service.SyncConnections(context.Background(), domain.SyncConnectionsRequest{Manifest: []byte(syncManifest), DryRun: true})
+	require.NoError(t, err)
+
+	assert.True(t, response.DryRun)
+	assert.Equal(t, []domain.CreateConnectionItem{{AccountToken: "token-3", AccountType: "CREDITCARD", Currency: "NZD"}}, response.Plan.Create)
+	assert.Equal(t, []string{"token-1"}, response.Plan.Unchanged)
+	assert.Equal(t, []domain.ConnectionMismatch{{AccountToken: "token-2", ID: "id-2", Fields: []string{"currency"}}}, response.Plan.Mismatched)
+	assert.Empty(t, response.Plan.Delete)
+	require.Len(t, response.Plan.Extra, 1)
+	assert.Equal(t, "id-4", response.Plan.Extra[0].ID)
+	assert.Nil(t, response.Created)
+	assert.Len(t, repository.connections, 3)
+}
+
+func TestSyncConnectionsApply(t *testing.T) {
+	repository := newSyncRepository()
+	service := newBulkService(repository)
+
+	// JSON is read like YAML
+	manifest := `{"connections":[{"accountToken":"token-1","accountType":"BANK","currency":"NZD"},{"accountToken":"token-3","accountType":"BANK","currency":"NZD"}]}`
+	response, err := service.SyncConnections(context.Background(), domain.SyncConnectionsRequest{Manifest: []byte(manifest), Prune: true})
+	require.NoError(t, err)
+
+	require.NotNil(t, response.Created)
+	assert.Equal(t, domain.BulkSummary{Total: 1, Succeeded: 1}, response.Created.Summary)
+	require.NotNil(t, response.Deleted)
+	assert.Equal(t, []string{"id-2", "id-4"}, []string{response.Deleted.Items[0].ID, response.Deleted.Items[1].ID})
+	assert.Empty(t, response.Plan.Extra)
+}
+
+func TestSyncConnectionsInvalidManifest(t *testing.T) {
+	service := newBulkService(newSyncRepository())
+
+	manifest := `
+connections:
+  - accountToken: token-1
+    accountType: BANK
+  - accountToken: token-1
+    accountType: BANK
+    currency: NZD
+`
+	_, err := service.SyncConnections(context.Background(), domain.SyncConnectionsRequest{Manifest: []byte(manifest)})
+	var badRequest exception.BadRequestError
+	require.ErrorAs(t, err, &badRequest)
+	assert.Contains(t, badRequest.Message, "connections[0]: currency is required")
+	assert.Contains(t, badRequest.Message, `connections[1]: accountToken "token-1" is listed twice`)
+}
+
+func TestSyncConnectionsEmptyManifestDeletesNothing(t *testing.T) {
+	for name, manifest := range map[string]string{
+		"empty body":   "",
+		"empty object": "{}",
+		"typo":         "connection:\n  - accountToken: token-1\n    accountType: BANK\n    currency: NZD\n",
+		"no items":     "connections: []",
+	} {
+		t.Run(name, func(t *testing.T) {
+			repository := newSyncRepository()
+			service := newBulkService(repository)
+
+			_, err := service.SyncConnections(context.Background(), domain.SyncConnectionsRequest{Manifest: []byte(manifest), Prune: true})
+			var badRequest exception.BadRequestError
+			require.ErrorAs(t, err, &badRequest)
+			assert.Len(t, repository.connections, 3)
+		})
+	}
+}
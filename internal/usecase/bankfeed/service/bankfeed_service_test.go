@@ -4,6 +4,7 @@ import (
 	"testing"
 	"usdw/internal/domain"
 	"usdw/internal/domain/entity"
+	"usdw/pkg/money"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -13,7 +14,7 @@ func TestMapStatementLines(t *testing.T) {
 		{
 			PostedDate:           "2023-01-10",
 			Description:          "Test transaction",
-			Amount:               100.50,
+			Amount:               money.MustParse("100.50"),
 			CreditDebitIndicator: "CREDIT",
 			TransactionID:        "txn-001",
 			PayeeName:            "John Doe",
@@ -27,7 +28,7 @@ func TestMapStatementLines(t *testing.T) {
 		{
 			PostedDate:           "2023-01-10",
 			Description:          "Test transaction",
-			Amount:               100.50,
+			Amount:               money.MustParse("100.50"),
 			CreditDebitIndicator: "CREDIT",
 			TransactionID:        "txn-001",
 			PayeeName:            "John Doe",
@@ -98,11 +99,11 @@ func TestMapStatementResults(t *testing.T) {
 			StartDate:        "2023-01-01",
 			EndDate:          "2023-01-31",
 			StartBalance: &entity.Balance{
-				Amount:               500.00,
+				Amount:               money.MustParse("500.00"),
 				CreditDebitIndicator: "DEBIT",
 			},
 			EndBalance: &entity.Balance{
-				Amount:               600.00,
+				Amount:               money.MustParse("600.00"),
 				CreditDebitIndicator: "CREDIT",
 			},
 			StatementLineCount: "10",
@@ -117,11 +118,11 @@ func TestMapStatementResults(t *testing.T) {
 			StartDate:        "2023-01-01",
 			EndDate:          "2023-01-31",
 			StartBalance: &domain.Balance{
-				Amount:               500.00,
+				Amount:               money.MustParse("500.00"),
 				CreditDebitIndicator: "DEBIT",
 			},
 			EndBalance: &domain.Balance{
-				Amount:               600.00,
+				Amount:               money.MustParse("600.00"),
 				CreditDebitIndicator: "CREDIT",
 			},
 			StatementLineCount: "10",
@@ -134,12 +135,12 @@ func TestMapStatementResults(t *testing.T) {
 
 func TestMapBalance(t *testing.T) {
 	input := &entity.Balance{
-		Amount:               100.00,
+		Amount:               money.MustParse("100.00"),
 		CreditDebitIndicator: "DEBIT",
 	}
 
 	expected := &domain.Balance{
-		Amount:               100.00,
+		Amount:               money.MustParse("100.00"),
 		CreditDebitIndicator: "DEBIT",
 	}
 
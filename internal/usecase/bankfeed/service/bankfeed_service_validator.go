@@ -0,0 +1,165 @@
+package service
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"time"
+	"usdw/internal/domain"
+	"usdw/pkg/money"
+)
+
+const (
+	isoDateLayout   = "2006-01-02"
+	indicatorCredit = "CREDIT"
+	indicatorDebit  = "DEBIT"
+)
+
+// statementValidator collects the problems of a single statement
+type statementValidator struct {
+	errors []domain.FeedError
+}
+
+func (v *statementValidator) add(errorType, title, detail string) {
+	v.errors = append(v.errors, domain.FeedError{
+		Type:   errorType,
+		Title:  title,
+		Status: http.StatusBadRequest,
+		Detail: detail,
+	})
+}
+
+// validateStatements checks every statement before it is sent to Xero. currencies maps
+// feed connection IDs to their currency and may be incomplete. It returns a
+// *domain.StatementValidationError listing the problems per statement, or nil.
+func validateStatements(request domain.PostStatementRequest, maxLines int, currencies map[string]string) error {
+	results := make([]domain.StatementResult, len(request.Items))
+	invalid := false
+
+	for i, item := range request.Items {
+		problems := validateStatement(item, maxLines, currencies[item.FeedConnectionID])
+		results[i] = domain.StatementResult{
+			FeedConnectionID: item.FeedConnectionID,
+			Status:           domain.StatementStatusValid,
+		}
+		if len(problems) > 0 {
+			invalid = true
+			results[i].Status = domain.StatementStatusRejected
+			results[i].Errors = &problems
+		}
+	}
+
+	if !invalid {
+		return nil
+	}
+	return &domain.StatementValidationError{Items: results}
+}
+
+func validateStatement(item domain.PostStatementItem, maxLines int, currency string) []domain.FeedError {
+	v := &statementValidator{}
+
+	if item.FeedConnectionID == "" {
+		v.add("missing-feed-connection", "Missing Feed Connection", "feedConnectionId is required")
+	}
+
+	startDate, startOK := v.parseDate("startDate", item.StartDate)
+	endDate, endOK := v.parseDate("endDate", item.EndDate)
+	rangeOK := startOK && endOK && !startDate.After(endDate)
+	if startOK && endOK && !rangeOK {
+		v.add("invalid-date-range", "Invalid Date Range",
+			fmt.Sprintf("startDate %s is after endDate %s", item.StartDate, item.EndDate))
+	}
+
+	v.checkBalance("startBalance", item.StartBalance, currency)
+	v.checkBalance("endBalance", item.EndBalance, currency)
+
+	transactionIDs := make(map[string]int, len(item.StatementLines))
+	linesPerDay := make(map[string]int)
+	total := signedAmount(item.StartBalance.Amount, item.StartBalance.CreditDebitIndicator)
+
+	for i, line := range item.StatementLines {
+		field := fmt.Sprintf("statementLines[%d]", i)
+
+		postedDate, ok := v.parseDate(field+".postedDate", line.PostedDate)
+		if ok {
+			linesPerDay[line.PostedDate]++
+		}
+		if ok && rangeOK && (postedDate.Before(startDate) || postedDate.After(endDate)) {
+			v.add("posted-date-out-of-range", "Posted Date Out Of Range",
+				fmt.Sprintf("%s.postedDate %s is outside %s to %s", field, line.PostedDate, item.StartDate, item.EndDate))
+		}
+
+		v.checkIndicator(field, line.CreditDebitIndicator)
+
+		v.checkAmount(field, line.Amount, currency)
+
+		if line.TransactionID == "" {
+			v.add("missing-transaction-id", "Missing Transaction ID", field+".transactionId is required")
+		} else if first, ok := transactionIDs[line.TransactionID]; ok {
+			v.add("duplicate-transaction-id", "Duplicate Transaction ID",
+				fmt.Sprintf("%s.transactionId %q is already used by statementLines[%d]", field, line.TransactionID, first))
+		} else {
+			transactionIDs[line.TransactionID] = i
+		}
+
+		total = total.Add(signedAmount(line.Amount, line.CreditDebitIndicator))
+	}
+
+	// Longer statements are split by day, so only a single day can be too long
+	for _, day := range slices.Sorted(maps.Keys(linesPerDay)) {
+		if maxLines > 0 && linesPerDay[day] > maxLines {
+			v.add("too-many-lines", "Too Many Statement Lines",
+				fmt.Sprintf("%d lines are posted on %s, the maximum per statement is %d", linesPerDay[day], day, maxLines))
+		}
+	}
+
+	endBalance := signedAmount(item.EndBalance.Amount, item.EndBalance.CreditDebitIndicator)
+	if !total.Equal(endBalance) {
+		v.add("balance-mismatch", "Balance Mismatch",
+			fmt.Sprintf("startBalance plus statement lines is %s but endBalance is %s", total, endBalance))
+	}
+
+	return v.errors
+}
+
+func (v *statementValidator) parseDate(field, value string) (time.Time, bool) {
+	date, err := time.Parse(isoDateLayout, value)
+	if err != nil {
+		v.add("invalid-date", "Invalid Date", fmt.Sprintf("%s %q is not an ISO date (YYYY-MM-DD)", field, value))
+		return time.Time{}, false
+	}
+	return date, true
+}
+
+func (v *statementValidator) checkIndicator(field, indicator string) {
+	if indicator != indicatorCredit && indicator != indicatorDebit {
+		v.add("invalid-credit-debit-indicator", "Invalid Credit Debit Indicator",
+			fmt.Sprintf("%s.creditDebitIndicator %q must be CREDIT or DEBIT", field, indicator))
+	}
+}
+
+func (v *statementValidator) checkBalance(field string, balance domain.Balance, currency string) {
+	v.checkIndicator(field, balance.CreditDebitIndicator)
+	v.checkAmount(field, balance.Amount, currency)
+}
+
+// checkAmount rejects negative amounts and amounts finer than the currency's minor unit
+func (v *statementValidator) checkAmount(field string, amount money.Amount, currency string) {
+	if amount.IsNegative() {
+		v.add("invalid-amount", "Invalid Amount",
+			fmt.Sprintf("%s.amount must not be negative, use creditDebitIndicator for the direction", field))
+	}
+	if currency != "" && !amount.FitsCurrency(currency) {
+		v.add("invalid-amount", "Invalid Amount",
+			fmt.Sprintf("%s.amount %s has more than %d decimal places for %s", field, amount, money.MinorUnits(currency), currency))
+	}
+}
+
+// signedAmount treats credits as money in and debits as money out
+func signedAmount(amount money.Amount, indicator string) money.Amount {
+	if indicator == indicatorDebit {
+		return amount.Neg()
+	}
+	return amount
+}
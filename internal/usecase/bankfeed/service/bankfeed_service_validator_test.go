@@ -0,0 +1,87 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"usdw/internal/domain"
+	"usdw/pkg/money"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func validStatement() domain.PostStatementItem {
+	return domain.PostStatementItem{
+		FeedConnectionID: "conn-123",
+		StartDate:        "2023-01-01",
+		EndDate:          "2023-01-31",
+		StartBalance:     domain.Balance{Amount: money.MustParse("100.10"), CreditDebitIndicator: "CREDIT"},
+		EndBalance:       domain.Balance{Amount: money.MustParse("150.30"), CreditDebitIndicator: "CREDIT"},
+		StatementLines: []domain.StatementLine{
+			{PostedDate: "2023-01-10", Amount: money.MustParse("70.20"), CreditDebitIndicator: "CREDIT", TransactionID: "txn-001"},
+			{PostedDate: "2023-01-20", Amount: money.MustParse("20.00"), CreditDebitIndicator: "DEBIT", TransactionID: "txn-002"},
+		},
+	}
+}
+
+func errorTypes(item domain.StatementResult) []string {
+	var types []string
+	if item.Errors != nil {
+		for _, err := range *item.Errors {
+			types = append(types, err.Type)
+		}
+	}
+	return types
+}
+
+func TestValidateStatementsValid(t *testing.T) {
+	request := domain.PostStatementRequest{Items: []domain.PostStatementItem{validStatement()}}
+	assert.NoError(t, validateStatements(request, 1000, nil))
+}
+
+func TestValidateStatementsInvalid(t *testing.T) {
+	invalid := validStatement()
+	invalid.StartDate = "2023-02-01"
+	invalid.EndBalance.CreditDebitIndicator = "IN"
+	invalid.StatementLines = append(invalid.StatementLines,
+		domain.StatementLine{PostedDate: "01/15/2023", Amount: money.MustParse("1"), CreditDebitIndicator: "CREDIT", TransactionID: "txn-001"},
+		domain.StatementLine{PostedDate: "2023-01-10", Amount: money.MustParse("1"), CreditDebitIndicator: "CREDIT", TransactionID: "txn-003"},
+	)
+
+	request := domain.PostStatementRequest{Items: []domain.PostStatementItem{validStatement(), invalid}}
+	err := validateStatements(request, 1, nil)
+
+	var validationErr *domain.StatementValidationError
+	assert.True(t, errors.As(err, &validationErr))
+	assert.Len(t, validationErr.Items, 2)
+	assert.Equal(t, domain.StatementStatusValid, validationErr.Items[0].Status)
+	assert.Equal(t, domain.StatementStatusRejected, validationErr.Items[1].Status)
+	assert.ElementsMatch(t, []string{
+		"invalid-date-range",
+		"invalid-credit-debit-indicator",
+		"too-many-lines",
+		"invalid-date",
+		"duplicate-transaction-id",
+		"balance-mismatch",
+	}, errorTypes(validationErr.Items[1]))
+}
+
+func TestValidateStatementPostedDateOutOfRange(t *testing.T) {
+	item := validStatement()
+	item.StatementLines[1].PostedDate = "2023-02-01"
+
+	assert.Equal(t, []string{"posted-date-out-of-range"},
+		errorTypes(domain.StatementResult{Errors: ptr(validateStatement(item, 0, ""))}))
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestValidateStatementCurrencyMinorUnits(t *testing.T) {
+	item := validStatement()
+
+	assert.Empty(t, validateStatement(item, 0, "USD"))
+	// 20.00 is a whole amount and fits JPY
+	assert.Equal(t, []string{"invalid-amount", "invalid-amount", "invalid-amount"},
+		errorTypes(domain.StatementResult{Errors: ptr(validateStatement(item, 0, "JPY"))}))
+}
@@ -0,0 +1,155 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+	"usdw/config"
+	"usdw/internal/domain"
+	"usdw/internal/domain/entity"
+	"usdw/pkg/background"
+	"usdw/pkg/logger"
+	"usdw/pkg/xero"
+)
+
+// claimLease is how long a claimed statement is hidden from other replicas while it is checked
+const claimLease = 5 * time.Minute
+
+// StatementPoller follows statements that Xero accepted as PENDING until they are
+// DELIVERED or REJECTED, and records the outcome in the local store. Statements that
+// are still pending, or cannot be checked, maxAge after posting are marked FAILED.
+type StatementPoller struct {
+	*background.Worker
+	Repository domain.BankFeedRepository
+	Store      domain.BankFeedStore
+	Webhooks   domain.WebhookPublisher
+	logger     logger.Logger
+
+	backoff    time.Duration
+	maxBackoff time.Duration
+	maxAge     time.Duration
+	batchSize  int
+}
+
+func NewStatementPoller(repository domain.BankFeedRepository, store domain.BankFeedStore, webhooks domain.WebhookPublisher, config *config.Configuration, logger logger.Logger) *StatementPoller {
+	poller := &StatementPoller{
+		Repository: repository,
+		Store:      store,
+		Webhooks:   webhooks,
+		logger:     logger,
+		backoff:    config.Xero.StatusPollBackoff,
+		maxBackoff: config.Xero.StatusPollMaxBackoff,
+		maxAge:     config.Xero.StatusPollMaxAge,
+		batchSize:  config.Xero.StatusPollBatchSize,
+	}
+	poller.Worker = background.NewWorker("Statement status poller", config.Xero.StatusPollInterval, poller.Poll, logger)
+	return poller
+}
+
+// Poll checks every statement that is due once. Replicas share the table, so each
+// claims its statements first.
+func (p *StatementPoller) Poll(ctx context.Context) {
+	records, err := p.Store.ClaimDueStatements(ctx, time.Now(), claimLease, p.batchSize)
+	if err != nil {
+		p.logger.Errorf("Failed to load pending statements: %s", err)
+		return
+	}
+
+	for i := range records {
+		if ctx.Err() != nil {
+			return
+		}
+		p.check(ctx, &records[i])
+	}
+}
+
+func (p *StatementPoller) check(ctx context.Context, record *entity.StatementRecord) {
+	now := time.Now()
+	record.Attempts++
+	record.LastCheckedAt = &now
+
+	statement, err := p.Repository.GetStatementByID(xero.WithTenantID(ctx, record.TenantID), record.XeroID)
+	if ctx.Err() != nil {
+		return
+	}
+
+	var feedErrors *[]entity.FeedError
+	expired := p.maxAge > 0 && now.Sub(record.CreatedAt) >= p.maxAge
+	switch {
+	case err != nil && expired:
+		// Errors that last this long will not go away, e.g. a purged statement or a disconnected tenant
+		record.Status = domain.StatementStatusFailed
+		feedErrors = &[]entity.FeedError{{Type: "status-check-failed", Title: "Status Check Failed", Detail: err.Error()}}
+		p.logger.Errorf("Giving up on statement %s after %d checks: %s", record.XeroID, record.Attempts, err)
+	case err != nil:
+		p.logger.Warnf("Failed to check status of statement %s (attempt %d): %s", record.XeroID, record.Attempts, err)
+	case statement.Status != domain.StatementStatusPending:
+		record.Status = statement.Status
+		feedErrors = statement.Errors
+		p.logger.Infof("Statement %s is %s after %d checks", record.XeroID, record.Status, record.Attempts)
+	case expired:
+		record.Status = domain.StatementStatusFailed
+		feedErrors = &[]entity.FeedError{{Type: "status-timeout", Title: "Status Timeout", Detail: fmt.Sprintf("Xero still reports the statement as PENDING %s after it was posted", p.maxAge)}}
+		p.logger.Errorf("Giving up on statement %s, still PENDING after %d checks", record.XeroID, record.Attempts)
+	}
+	if feedErrors != nil {
+		data, _ := json.Marshal(feedErrors)
+		record.Errors = string(data)
+	}
+
+	next := now.Add(background.Backoff(p.backoff, p.maxBackoff, record.Attempts))
+	record.NextPollAt = &next
+
+	err = p.Store.UpdateStatement(ctx, record)
+	if err != nil {
+		p.logger.Errorf("Failed to record status of statement %s: %s", record.XeroID, err)
+		return
+	}
+
+	// The lines of a rejected statement may be posted again
+	if record.Status == domain.StatementStatusRejected {
+		err = p.Store.DeletePostedTransactions(ctx, record.ID)
+		if err != nil {
+			p.logger.Errorf("Failed to forget transactions of statement %s: %s", record.XeroID, err)
+		}
+	}
+
+	if record.Status != domain.StatementStatusPending {
+		p.publish(ctx, record, feedErrors)
+	}
+}
+
+func (p *StatementPoller) publish(ctx context.Context, record *entity.StatementRecord, feedErrors *[]entity.FeedError) {
+	event := domain.EventStatementDelivered
+	switch record.Status {
+	case domain.StatementStatusRejected:
+		event = domain.EventStatementRejected
+	case domain.StatementStatusFailed:
+		event = domain.EventStatementFailed
+	}
+
+	result := domain.StatementResult{
+		ID:                 record.XeroID,
+		FeedConnectionID:   record.FeedConnectionID,
+		Status:             record.Status,
+		StartDate:          record.StartDate,
+		EndDate:            record.EndDate,
+		StartBalance:       &domain.Balance{Amount: record.StartBalance, CreditDebitIndicator: record.StartIndicator},
+		EndBalance:         &domain.Balance{Amount: record.EndBalance, CreditDebitIndicator: record.EndIndicator},
+		StatementLineCount: strconv.Itoa(record.StatementLineCount),
+	}
+	if feedErrors != nil {
+		errors := make([]domain.FeedError, len(*feedErrors))
+		for i, feedError := range *feedErrors {
+			errors[i] = domain.FeedError(feedError)
+		}
+		result.Errors = &errors
+	}
+
+	err := p.Webhooks.Publish(ctx, record.TenantID, event, result)
+	if err != nil {
+		p.logger.Errorf("Failed to publish %s webhook for statement %s: %s", event, record.XeroID, err)
+	}
+}
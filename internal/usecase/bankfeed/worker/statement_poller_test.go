@@ -0,0 +1,179 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+	"usdw/config"
+	"usdw/internal/domain"
+	"usdw/internal/domain/entity"
+	"usdw/internal/usecase/bankfeed/repository"
+	"usdw/pkg/db"
+	"usdw/pkg/logger"
+	"usdw/pkg/xero"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubRepository struct {
+	domain.BankFeedRepository
+	statements map[string]*entity.StatementResult
+	tenants    []string
+}
+
+func (r *stubRepository) GetStatementByID(ctx context.Context, statementID string) (*entity.StatementResult, error) {
+	r.tenants = append(r.tenants, xero.TenantIDFromContext(ctx))
+	statement, ok := r.statements[statementID]
+	if !ok {
+		return nil, errors.New("xero unavailable")
+	}
+	return statement, nil
+}
+
+type stubPublisher struct {
+	events []string
+}
+
+func (p *stubPublisher) Publish(_ context.Context, tenantID, eventType string, _ interface{}) error {
+	p.events = append(p.events, tenantID+":"+eventType)
+	return nil
+}
+
+func newTestPoller(t *testing.T, repo *stubRepository) (*StatementPoller, domain.BankFeedStore, *stubPublisher) {
+	conf := &config.Configuration{
+		Database: config.DatabaseConfig{Driver: "sqlite", SQLitePath: filepath.Join(t.TempDir(), "usdw.db")},
+		Xero: config.XeroConfig{
+			StatusPollInterval:   time.Second,
+			StatusPollBackoff:    time.Minute,
+			StatusPollMaxBackoff: 5 * time.Minute,
+			StatusPollBatchSize:  10,
+			StatusPollMaxAge:     72 * time.Hour,
+		},
+	}
+	log := logger.NewLogger()
+	database, err := db.NewDB(conf, log)
+	require.NoError(t, err)
+	t.Cleanup(func() { database.Close() })
+	require.NoError(t, database.AutoMigrate(&entity.StatementRecord{}, &entity.PostedTransactionRecord{}))
+
+	store := repository.NewBankFeedStore(database)
+	publisher := &stubPublisher{}
+	return NewStatementPoller(repo, store, publisher, conf, log), store, publisher
+}
+
+func TestStatementPollerRecordsOutcome(t *testing.T) {
+	ctx := context.Background()
+	repo := &stubRepository{statements: map[string]*entity.StatementResult{
+		"stmt-1": {ID: "stmt-1", Status: domain.StatementStatusDelivered},
+		"stmt-2": {ID: "stmt-2", Status: domain.StatementStatusRejected, Errors: &[]entity.FeedError{{Type: "invalid-end-balance", Title: "Invalid End Balance"}}},
+		"stmt-3": {ID: "stmt-3", Status: domain.StatementStatusPending},
+	}}
+	poller, store, publisher := newTestPoller(t, repo)
+
+	future := time.Now().Add(time.Hour)
+	require.NoError(t, store.SaveStatements(ctx, []entity.StatementRecord{
+		{TenantID: "tenant-a", XeroID: "stmt-1", Status: domain.StatementStatusPending},
+		{TenantID: "tenant-b", XeroID: "stmt-2", Status: domain.StatementStatusPending},
+		{TenantID: "tenant-a", XeroID: "stmt-3", Status: domain.StatementStatusPending},
+		{TenantID: "tenant-a", XeroID: "stmt-4", Status: domain.StatementStatusPending, NextPollAt: &future},
+	}))
+
+	poller.Poll(ctx)
+
+	assert.ElementsMatch(t, []string{"tenant-a", "tenant-b", "tenant-a"}, repo.tenants)
+	assert.ElementsMatch(t, []string{"tenant-a:statement.delivered", "tenant-b:statement.rejected"}, publisher.events)
+
+	delivered, err := store.GetStatement(ctx, "tenant-a", "stmt-1")
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatementStatusDelivered, delivered.Status)
+	assert.Equal(t, 1, delivered.Attempts)
+
+	rejected, err := store.GetStatement(ctx, "tenant-b", "stmt-2")
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatementStatusRejected, rejected.Status)
+	assert.Contains(t, rejected.Errors, "invalid-end-balance")
+
+	pending, err := store.GetStatement(ctx, "tenant-a", "stmt-3")
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatementStatusPending, pending.Status)
+	require.NotNil(t, pending.NextPollAt)
+	assert.True(t, pending.NextPollAt.After(time.Now()))
+
+	// Nothing is due until the backoff has passed
+	repo.tenants = nil
+	poller.Poll(ctx)
+	assert.Empty(t, repo.tenants)
+}
+
+func TestStatementPollerGivesUp(t *testing.T) {
+	ctx := context.Background()
+	repo := &stubRepository{statements: map[string]*entity.StatementResult{
+		"stmt-stuck": {ID: "stmt-stuck", Status: domain.StatementStatusPending},
+	}}
+	poller, store, publisher := newTestPoller(t, repo)
+
+	require.NoError(t, store.SaveStatements(ctx, []entity.StatementRecord{
+		{TenantID: "tenant-a", XeroID: "stmt-recent", Status: domain.StatementStatusPending},
+		{TenantID: "tenant-a", XeroID: "stmt-purged", Status: domain.StatementStatusPending, CreatedAt: time.Now().Add(-73 * time.Hour)},
+		{TenantID: "tenant-a", XeroID: "stmt-stuck", Status: domain.StatementStatusPending, CreatedAt: time.Now().Add(-73 * time.Hour)},
+	}))
+
+	poller.Poll(ctx)
+
+	// A recent statement is retried, one failing past the max age is given up on
+	recent, err := store.GetStatement(ctx, "tenant-a", "stmt-recent")
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatementStatusPending, recent.Status)
+
+	purged, err := store.GetStatement(ctx, "tenant-a", "stmt-purged")
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatementStatusFailed, purged.Status)
+	assert.Contains(t, purged.Errors, "xero unavailable")
+
+	// So is one Xero still reports as pending
+	stuck, err := store.GetStatement(ctx, "tenant-a", "stmt-stuck")
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatementStatusFailed, stuck.Status)
+	assert.Contains(t, stuck.Errors, "status-timeout")
+	assert.Equal(t, []string{"tenant-a:statement.failed", "tenant-a:statement.failed"}, publisher.events)
+}
+
+func TestStatementPollerClaims(t *testing.T) {
+	ctx := context.Background()
+	repo := &stubRepository{statements: map[string]*entity.StatementResult{
+		"stmt-1": {ID: "stmt-1", Status: domain.StatementStatusDelivered},
+	}}
+	poller, store, publisher := newTestPoller(t, repo)
+	require.NoError(t, store.SaveStatements(ctx, []entity.StatementRecord{
+		{TenantID: "tenant-a", XeroID: "stmt-1", Status: domain.StatementStatusPending},
+	}))
+
+	// Another replica claimed the statement first
+	claimed, err := store.ClaimDueStatements(ctx, time.Now(), time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+
+	poller.Poll(ctx)
+	assert.Empty(t, repo.tenants)
+	assert.Empty(t, publisher.events)
+}
+
+func TestStatementPollerStop(t *testing.T) {
+	poller, _, _ := newTestPoller(t, &stubRepository{})
+
+	poller.Start()
+	done := make(chan struct{})
+	go func() {
+		poller.Stop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("poller did not stop")
+	}
+}
@@ -0,0 +1,141 @@
+package http
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"usdw/config"
+	"usdw/internal/domain"
+	"usdw/pkg/common/exception"
+)
+
+type WebhookHandler struct {
+	domain.WebhookService
+	config.Configuration
+}
+
+func NewWebhookHandler(webhookService domain.WebhookService, config *config.Configuration) WebhookHandler {
+	return WebhookHandler{
+		WebhookService: webhookService,
+		Configuration:  *config,
+	}
+}
+
+func (h *WebhookHandler) InitRoute(app fiber.Router) {
+	app.Post("/webhooks/subscriptions", h.CreateSubscription)
+	app.Get("/webhooks/subscriptions", h.GetSubscriptions)
+	app.Delete("/webhooks/subscriptions/:id", h.DeleteSubscription)
+	app.Get("/webhooks/dead-letters", h.GetDeadLetters)
+	app.Post("/webhooks/dead-letters/:id/retry", h.RetryDeadLetter)
+}
+
+// @Summary Subscribe to webhook events
+// @Description Registers a public https URL to receive signed statement and feed connection events. The secret is only returned here.
+// @Tags Webhooks
+// @Accept json
+// @Produce json
+// @Param request body domain.CreateSubscriptionRequest true "Subscription"
+// @Success 201 {object} domain.Subscription
+// @Failure 400 {object} exception.ProblemDetails
+// @Failure 500 {object} exception.ProblemDetails
+// @Router /webhooks/subscriptions [post]
+func (h *WebhookHandler) CreateSubscription(c *fiber.Ctx) error {
+	var request domain.CreateSubscriptionRequest
+	if err := c.BodyParser(&request); err != nil {
+		return exception.BadRequestError{Message: "Invalid request format"}
+	}
+
+	response, err := h.WebhookService.CreateSubscription(c.UserContext(), request)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(response)
+}
+
+// @Summary List webhook subscriptions
+// @Tags Webhooks
+// @Produce json
+// @Success 200 {array} domain.Subscription
+// @Failure 500 {object} exception.ProblemDetails
+// @Router /webhooks/subscriptions [get]
+func (h *WebhookHandler) GetSubscriptions(c *fiber.Ctx) error {
+	response, err := h.WebhookService.GetSubscriptions(c.UserContext())
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response)
+}
+
+// @Summary Delete a webhook subscription
+// @Tags Webhooks
+// @Param id path int true "Subscription ID"
+// @Success 204
+// @Failure 404 {object} exception.ProblemDetails
+// @Failure 500 {object} exception.ProblemDetails
+// @Router /webhooks/subscriptions/{id} [delete]
+func (h *WebhookHandler) DeleteSubscription(c *fiber.Ctx) error {
+	subscriptionID, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return exception.BadRequestError{Message: "Invalid subscription ID"}
+	}
+
+	err = h.WebhookService.DeleteSubscription(c.UserContext(), uint(subscriptionID))
+	if err != nil {
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// @Summary List dead-lettered webhook deliveries
+// @Description Deliveries that failed on every attempt
+// @Tags Webhooks
+// @Produce json
+// @Param page query int false "Page number"
+// @Param pageSize query int false "Number of items per page"
+// @Success 200 {object} domain.DeadLettersResponse
+// @Failure 400 {object} exception.ProblemDetails
+// @Failure 500 {object} exception.ProblemDetails
+// @Router /webhooks/dead-letters [get]
+func (h *WebhookHandler) GetDeadLetters(c *fiber.Ctx) error {
+	page, err := strconv.Atoi(c.Query("page", "1"))
+	if err != nil || page < 1 {
+		return exception.BadRequestError{Message: "Invalid page parameter"}
+	}
+
+	pageSize, err := strconv.Atoi(c.Query("pageSize", "50"))
+	if err != nil || pageSize < 1 {
+		return exception.BadRequestError{Message: "Invalid pageSize parameter"}
+	}
+
+	response, err := h.WebhookService.GetDeadLetters(c.UserContext(), page, pageSize)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(response)
+}
+
+// @Summary Retry a dead-lettered delivery
+// @Description Queues the delivery again with a fresh set of attempts
+// @Tags Webhooks
+// @Param id path int true "Delivery ID"
+// @Success 202
+// @Failure 404 {object} exception.ProblemDetails
+// @Failure 500 {object} exception.ProblemDetails
+// @Router /webhooks/dead-letters/{id}/retry [post]
+func (h *WebhookHandler) RetryDeadLetter(c *fiber.Ctx) error {
+	deliveryID, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return exception.BadRequestError{Message: "Invalid delivery ID"}
+	}
+
+	err = h.WebhookService.RetryDeadLetter(c.UserContext(), uint(deliveryID))
+	if err != nil {
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusAccepted)
+}
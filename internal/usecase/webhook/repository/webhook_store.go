@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"usdw/internal/domain"
+	"usdw/internal/domain/entity"
+	"usdw/pkg/db"
+
+	"gorm.io/gorm"
+)
+
+type webhookStore struct {
+	DB *db.DB
+}
+
+func NewWebhookStore(db *db.DB) domain.WebhookStore {
+	return &webhookStore{
+		DB: db,
+	}
+}
+
+func (s *webhookStore) SaveSubscription(ctx context.Context, record *entity.WebhookSubscriptionRecord) error {
+	return s.DB.WithContext(ctx).Save(record).Error
+}
+
+func (s *webhookStore) ListSubscriptions(ctx context.Context) ([]entity.WebhookSubscriptionRecord, error) {
+	var records []entity.WebhookSubscriptionRecord
+	err := s.DB.WithContext(ctx).Order("id").Find(&records).Error
+	return records, err
+}
+
+func (s *webhookStore) GetSubscription(ctx context.Context, subscriptionID uint) (*entity.WebhookSubscriptionRecord, error) {
+	var record entity.WebhookSubscriptionRecord
+	err := s.DB.WithContext(ctx).Take(&record, subscriptionID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (s *webhookStore) DeleteSubscription(ctx context.Context, subscriptionID uint) error {
+	result := s.DB.WithContext(ctx).Delete(&entity.WebhookSubscriptionRecord{}, subscriptionID)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return result.Error
+}
+
+func (s *webhookStore) SaveDeliveries(ctx context.Context, records []entity.WebhookDeliveryRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	return s.DB.WithContext(ctx).Create(&records).Error
+}
+
+// ClaimDueDeliveries uses next_attempt_at as the lease. A delivery whose replica dies
+// while sending it becomes due again once the lease ends.
+func (s *webhookStore) ClaimDueDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]entity.WebhookDeliveryRecord, error) {
+	var records []entity.WebhookDeliveryRecord
+	err := s.DB.WithContext(ctx).
+		Where("status = ?", domain.DeliveryStatusPending).
+		Where("next_attempt_at IS NULL OR next_attempt_at <= ?", now).
+		Order("id").
+		Limit(limit).
+		Find(&records).Error
+	if err != nil {
+		return nil, err
+	}
+
+	leaseUntil := now.Add(lease)
+	claimed := records[:0]
+	for _, record := range records {
+		// Only the first replica to update a delivery still finds it due
+		result := s.DB.WithContext(ctx).
+			Model(&entity.WebhookDeliveryRecord{}).
+			Where("id = ? AND status = ?", record.ID, domain.DeliveryStatusPending).
+			Where("next_attempt_at IS NULL OR next_attempt_at <= ?", now).
+			Update("next_attempt_at", leaseUntil)
+		if result.Error != nil {
+			return claimed, result.Error
+		}
+		if result.RowsAffected == 1 {
+			record.NextAttemptAt = &leaseUntil
+			claimed = append(claimed, record)
+		}
+	}
+	return claimed, nil
+}
+
+func (s *webhookStore) ListDeadDeliveries(ctx context.Context, page, pageSize int) ([]entity.WebhookDeliveryRecord, int, error) {
+	query := s.DB.WithContext(ctx).
+		Model(&entity.WebhookDeliveryRecord{}).
+		Where("status = ?", domain.DeliveryStatusDead).
+		Session(&gorm.Session{})
+
+	var count int64
+	err := query.Count(&count).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var records []entity.WebhookDeliveryRecord
+	err = query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&records).Error
+	return records, int(count), err
+}
+
+func (s *webhookStore) GetDelivery(ctx context.Context, deliveryID uint) (*entity.WebhookDeliveryRecord, error) {
+	var record entity.WebhookDeliveryRecord
+	err := s.DB.WithContext(ctx).Take(&record, deliveryID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (s *webhookStore) UpdateDelivery(ctx context.Context, record *entity.WebhookDeliveryRecord) error {
+	return s.DB.WithContext(ctx).Save(record).Error
+}
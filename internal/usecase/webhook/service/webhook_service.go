@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+	"usdw/config"
+	"usdw/internal/domain"
+	"usdw/internal/domain/entity"
+	"usdw/pkg/common/exception"
+	"usdw/pkg/logger"
+	"usdw/pkg/netguard"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type webhookService struct {
+	Store domain.WebhookStore
+	*config.Configuration
+	logger logger.Logger
+}
+
+func NewWebhookService(store domain.WebhookStore, config *config.Configuration, logger logger.Logger) domain.WebhookService {
+	return &webhookService{
+		Store:         store,
+		Configuration: config,
+		logger:        logger,
+	}
+}
+
+// Publish queues the event for every matching subscription. Deliveries are sent by
+// the webhook dispatcher so a slow subscriber never holds up the API.
+func (s *webhookService) Publish(ctx context.Context, tenantID, eventType string, data interface{}) error {
+	subscriptions, err := s.Store.ListSubscriptions(ctx)
+	if err != nil {
+		return err
+	}
+
+	event := domain.WebhookEvent{
+		ID:        uuid.NewString(),
+		Type:      eventType,
+		TenantID:  tenantID,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	var deliveries []entity.WebhookDeliveryRecord
+	for _, subscription := range subscriptions {
+		if !subscribed(subscription, tenantID, eventType) {
+			continue
+		}
+		deliveries = append(deliveries, entity.WebhookDeliveryRecord{
+			SubscriptionID: subscription.ID,
+			URL:            subscription.URL,
+			EventID:        event.ID,
+			EventType:      eventType,
+			Payload:        string(payload),
+			Status:         domain.DeliveryStatusPending,
+		})
+	}
+
+	return s.Store.SaveDeliveries(ctx, deliveries)
+}
+
+func (s *webhookService) CreateSubscription(ctx context.Context, request domain.CreateSubscriptionRequest) (*domain.Subscription, error) {
+	target, err := url.Parse(request.URL)
+	insecure := s.Webhook.InsecureURLs && target != nil && target.Scheme == "http"
+	if err != nil || (target.Scheme != "https" && !insecure) || target.Host == "" {
+		return nil, exception.BadRequestError{Message: "url must be an absolute https URL"}
+	}
+	// The dispatcher checks the address again when it connects
+	if !s.Webhook.InsecureURLs {
+		err = netguard.CheckHost(ctx, target.Hostname())
+		if err != nil {
+			return nil, exception.BadRequestError{Message: "url must point to a public address: " + err.Error()}
+		}
+	}
+
+	events := request.Events
+	if len(events) == 0 {
+		events = domain.WebhookEvents
+	}
+	for _, event := range events {
+		if !slices.Contains(domain.WebhookEvents, event) {
+			return nil, exception.BadRequestError{Message: "unknown event " + event + ", expected one of " + strings.Join(domain.WebhookEvents, ", ")}
+		}
+	}
+
+	secret := request.Secret
+	if secret == "" {
+		secret, err = newSecret()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	record := &entity.WebhookSubscriptionRecord{
+		URL:      request.URL,
+		Secret:   secret,
+		Events:   strings.Join(events, ","),
+		TenantID: request.TenantID,
+	}
+	err = s.Store.SaveSubscription(ctx, record)
+	if err != nil {
+		return nil, err
+	}
+
+	subscription := mapSubscription(*record)
+	subscription.Secret = secret
+	return &subscription, nil
+}
+
+func (s *webhookService) GetSubscriptions(ctx context.Context) ([]domain.Subscription, error) {
+	records, err := s.Store.ListSubscriptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	subscriptions := make([]domain.Subscription, len(records))
+	for i, record := range records {
+		subscriptions[i] = mapSubscription(record)
+	}
+	return subscriptions, nil
+}
+
+func (s *webhookService) DeleteSubscription(ctx context.Context, subscriptionID uint) error {
+	err := s.Store.DeleteSubscription(ctx, subscriptionID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return exception.NotFoundError{Message: "webhook subscription not found"}
+	}
+	return err
+}
+
+func (s *webhookService) GetDeadLetters(ctx context.Context, page, pageSize int) (*domain.DeadLettersResponse, error) {
+	records, count, err := s.Store.ListDeadDeliveries(ctx, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]domain.DeadLetter, len(records))
+	for i, record := range records {
+		items[i] = domain.DeadLetter{
+			ID:             record.ID,
+			SubscriptionID: record.SubscriptionID,
+			URL:            record.URL,
+			EventID:        record.EventID,
+			EventType:      record.EventType,
+			Attempts:       record.Attempts,
+			LastError:      record.LastError,
+			LastAttemptAt:  record.LastAttemptAt,
+			CreatedAt:      record.CreatedAt,
+		}
+	}
+
+	return &domain.DeadLettersResponse{
+		Pagination: domain.Pagination{
+			Page:      page,
+			PageSize:  pageSize,
+			PageCount: (count + pageSize - 1) / pageSize,
+			ItemCount: count,
+		},
+		Items: items,
+	}, nil
+}
+
+// RetryDeadLetter puts a dead delivery back in the queue with a fresh set of attempts
+func (s *webhookService) RetryDeadLetter(ctx context.Context, deliveryID uint) error {
+	record, err := s.Store.GetDelivery(ctx, deliveryID)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && record.Status != domain.DeliveryStatusDead) {
+		return exception.NotFoundError{Message: "dead letter not found"}
+	}
+	if err != nil {
+		return err
+	}
+
+	record.Status = domain.DeliveryStatusPending
+	record.Attempts = 0
+	record.NextAttemptAt = nil
+	return s.Store.UpdateDelivery(ctx, record)
+}
+
+func subscribed(subscription entity.WebhookSubscriptionRecord, tenantID, eventType string) bool {
+	if subscription.TenantID != "" && subscription.TenantID != tenantID {
+		return false
+	}
+	return slices.Contains(strings.Split(subscription.Events, ","), eventType)
+}
+
+func mapSubscription(record entity.WebhookSubscriptionRecord) domain.Subscription {
+	return domain.Subscription{
+		ID:        record.ID,
+		URL:       record.URL,
+		Events:    strings.Split(record.Events, ","),
+		TenantID:  record.TenantID,
+		CreatedAt: record.CreatedAt,
+	}
+}
+
+func newSecret() (string, error) {
+	secret := make([]byte, 32)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}
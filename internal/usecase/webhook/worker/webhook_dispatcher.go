@@ -0,0 +1,182 @@
+package worker
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+	"usdw/config"
+	"usdw/internal/domain"
+	"usdw/internal/domain/entity"
+	"usdw/pkg/background"
+	"usdw/pkg/logger"
+	"usdw/pkg/netguard"
+
+	"github.com/go-resty/resty/v2"
+	"gorm.io/gorm"
+)
+
+// Headers sent with every delivery
+const (
+	SignatureHeader = "X-USDW-Signature"
+	TimestampHeader = "X-USDW-Timestamp"
+	EventHeader     = "X-USDW-Event"
+	DeliveryHeader  = "X-USDW-Delivery"
+)
+
+// WebhookDispatcher sends queued deliveries to subscribers, retrying failures with
+// backoff until they succeed or are dead-lettered
+type WebhookDispatcher struct {
+	*background.Worker
+	Store  domain.WebhookStore
+	Client *resty.Client
+	logger logger.Logger
+
+	timeout     time.Duration
+	maxAttempts int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+	batchSize   int
+	concurrency int
+}
+
+func NewWebhookDispatcher(store domain.WebhookStore, config *config.Configuration, logger logger.Logger) *WebhookDispatcher {
+	client := resty.New().SetTimeout(config.Webhook.Timeout)
+	// Subscription URLs come from API callers, so never connect to the internal network
+	if !config.Webhook.InsecureURLs {
+		client.SetTransport(netguard.NewTransport())
+	}
+
+	dispatcher := &WebhookDispatcher{
+		Store:       store,
+		Client:      client,
+		logger:      logger,
+		timeout:     config.Webhook.Timeout,
+		maxAttempts: config.Webhook.MaxAttempts,
+		backoff:     config.Webhook.Backoff,
+		maxBackoff:  config.Webhook.MaxBackoff,
+		batchSize:   config.Webhook.BatchSize,
+		concurrency: max(config.Webhook.Concurrency, 1),
+	}
+	dispatcher.Worker = background.NewWorker("Webhook dispatcher", config.Webhook.DeliveryInterval, dispatcher.Dispatch, logger)
+	return dispatcher
+}
+
+// Dispatch claims the deliveries that are due and attempts each once. Endpoints are sent
+// to in parallel, at most concurrency at a time, and the deliveries of one endpoint in
+// order. After a failure the rest of that endpoint's deliveries wait for their claim to
+// run out, so a dead endpoint holds up neither the batch nor the next run.
+func (d *WebhookDispatcher) Dispatch(ctx context.Context) {
+	// The claim outlasts sending a whole batch to a single slow endpoint
+	lease := max(time.Duration(d.batchSize)*d.timeout, time.Minute)
+	records, err := d.Store.ClaimDueDeliveries(ctx, time.Now(), lease, d.batchSize)
+	if err != nil {
+		d.logger.Errorf("Failed to load webhook deliveries: %s", err)
+		return
+	}
+
+	var urls []string
+	byURL := make(map[string][]*entity.WebhookDeliveryRecord)
+	for i := range records {
+		url := records[i].URL
+		if _, ok := byURL[url]; !ok {
+			urls = append(urls, url)
+		}
+		byURL[url] = append(byURL[url], &records[i])
+	}
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, d.concurrency)
+	for _, url := range urls {
+		wg.Add(1)
+		go func(deliveries []*entity.WebhookDeliveryRecord) {
+			defer wg.Done()
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-slots }()
+
+			for _, record := range deliveries {
+				if ctx.Err() != nil || !d.deliver(ctx, record) {
+					return
+				}
+			}
+		}(byURL[url])
+	}
+	wg.Wait()
+}
+
+// deliver attempts a delivery and reports whether the endpoint can take the next one
+func (d *WebhookDispatcher) deliver(ctx context.Context, record *entity.WebhookDeliveryRecord) bool {
+	now := time.Now()
+	record.Attempts++
+	record.LastAttemptAt = &now
+
+	err := d.send(ctx, record)
+	if ctx.Err() != nil {
+		return false
+	}
+
+	switch {
+	case err == nil:
+		record.Status = domain.DeliveryStatusDelivered
+		record.LastError = ""
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		record.Status = domain.DeliveryStatusDead
+		record.LastError = "subscription was deleted"
+	case record.Attempts >= d.maxAttempts:
+		record.Status = domain.DeliveryStatusDead
+		record.LastError = err.Error()
+		d.logger.Errorf("Webhook %s for %s failed %d times and was dead-lettered: %s", record.EventID, record.URL, record.Attempts, err)
+	default:
+		next := now.Add(background.Backoff(d.backoff, d.maxBackoff, record.Attempts))
+		record.NextAttemptAt = &next
+		record.LastError = err.Error()
+		d.logger.Warnf("Webhook %s for %s failed (attempt %d), retrying at %s: %s", record.EventID, record.URL, record.Attempts, next.Format(time.RFC3339), err)
+	}
+
+	if updateErr := d.Store.UpdateDelivery(ctx, record); updateErr != nil {
+		d.logger.Errorf("Failed to record webhook delivery %d: %s", record.ID, updateErr)
+	}
+	return err == nil || errors.Is(err, gorm.ErrRecordNotFound)
+}
+
+func (d *WebhookDispatcher) send(ctx context.Context, record *entity.WebhookDeliveryRecord) error {
+	subscription, err := d.Store.GetSubscription(ctx, record.SubscriptionID)
+	if err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	resp, err := d.Client.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetHeader(SignatureHeader, Sign(subscription.Secret, timestamp, record.Payload)).
+		SetHeader(TimestampHeader, timestamp).
+		SetHeader(EventHeader, record.EventType).
+		SetHeader(DeliveryHeader, record.EventID).
+		SetBody(record.Payload).
+		Post(subscription.URL)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode() < 200 || resp.StatusCode() >= 300 {
+		return fmt.Errorf("subscriber responded with %s", resp.Status())
+	}
+	return nil
+}
+
+// Sign returns the signature subscribers check: "sha256=" and the hex HMAC-SHA256 of
+// "<timestamp>.<body>" keyed with the subscription secret
+func Sign(secret, timestamp, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
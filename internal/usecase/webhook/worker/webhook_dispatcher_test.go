@@ -0,0 +1,215 @@
+package worker
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+	"usdw/config"
+	"usdw/internal/domain"
+	"usdw/internal/domain/entity"
+	"usdw/internal/usecase/webhook/repository"
+	"usdw/internal/usecase/webhook/service"
+	"usdw/pkg/db"
+	"usdw/pkg/logger"
+	"usdw/pkg/netguard"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDispatcher(t *testing.T) (*WebhookDispatcher, domain.WebhookService, domain.WebhookStore) {
+	conf := &config.Configuration{
+		Database: config.DatabaseConfig{Driver: "sqlite", SQLitePath: filepath.Join(t.TempDir(), "usdw.db")},
+		Webhook: config.WebhookConfig{
+			DeliveryInterval: time.Second,
+			Timeout:          time.Second,
+			MaxAttempts:      2,
+			Backoff:          time.Millisecond,
+			MaxBackoff:       time.Millisecond,
+			BatchSize:        10,
+			// The test servers listen on loopback over http
+			InsecureURLs: true,
+		},
+	}
+	log := logger.NewLogger()
+	database, err := db.NewDB(conf, log)
+	require.NoError(t, err)
+	t.Cleanup(func() { database.Close() })
+	require.NoError(t, database.AutoMigrate(&entity.WebhookSubscriptionRecord{}, &entity.WebhookDeliveryRecord{}))
+
+	store := repository.NewWebhookStore(database)
+	return NewWebhookDispatcher(store, conf, log), service.NewWebhookService(store, conf, log), store
+}
+
+func TestWebhookDispatcherSignsDeliveries(t *testing.T) {
+	ctx := context.Background()
+	dispatcher, webhooks, store := newTestDispatcher(t)
+
+	received := make(chan *http.Request, 1)
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		received <- r
+	}))
+	defer server.Close()
+
+	subscription, err := webhooks.CreateSubscription(ctx, domain.CreateSubscriptionRequest{
+		URL:    server.URL,
+		Events: []string{domain.EventStatementRejected},
+		Secret: "s3cret",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, webhooks.Publish(ctx, "tenant-a", domain.EventStatementDelivered, domain.StatementResult{ID: "stmt-1"}))
+	require.NoError(t, webhooks.Publish(ctx, "tenant-a", domain.EventStatementRejected, domain.StatementResult{ID: "stmt-2"}))
+
+	dispatcher.Dispatch(ctx)
+
+	request := <-received
+	assert.Len(t, received, 0, "only the subscribed event is delivered")
+	assert.Equal(t, domain.EventStatementRejected, request.Header.Get(EventHeader))
+	assert.Equal(t, Sign(subscription.Secret, request.Header.Get(TimestampHeader), body), request.Header.Get(SignatureHeader))
+	assert.Contains(t, body, `"id":"stmt-2"`)
+
+	due, err := store.ClaimDueDeliveries(ctx, time.Now(), time.Minute, 10)
+	require.NoError(t, err)
+	assert.Empty(t, due)
+}
+
+func TestWebhookDispatcherDeadLetters(t *testing.T) {
+	ctx := context.Background()
+	dispatcher, webhooks, _ := newTestDispatcher(t)
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	_, err := webhooks.CreateSubscription(ctx, domain.CreateSubscriptionRequest{URL: server.URL})
+	require.NoError(t, err)
+	require.NoError(t, webhooks.Publish(ctx, "tenant-a", domain.EventConnectionCreated, domain.CreateConnectionResult{ID: "conn-1"}))
+
+	dispatcher.Dispatch(ctx)
+	time.Sleep(5 * time.Millisecond)
+	dispatcher.Dispatch(ctx)
+	dispatcher.Dispatch(ctx)
+	assert.Equal(t, int32(2), calls.Load())
+
+	deadLetters, err := webhooks.GetDeadLetters(ctx, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, deadLetters.Items, 1)
+	assert.Equal(t, 2, deadLetters.Items[0].Attempts)
+	assert.Contains(t, deadLetters.Items[0].LastError, "500")
+
+	require.NoError(t, webhooks.RetryDeadLetter(ctx, deadLetters.Items[0].ID))
+	dispatcher.Dispatch(ctx)
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestWebhookDispatcherSkipsClaimedDeliveries(t *testing.T) {
+	ctx := context.Background()
+	dispatcher, webhooks, store := newTestDispatcher(t)
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer server.Close()
+
+	_, err := webhooks.CreateSubscription(ctx, domain.CreateSubscriptionRequest{URL: server.URL})
+	require.NoError(t, err)
+	require.NoError(t, webhooks.Publish(ctx, "tenant-a", domain.EventConnectionCreated, domain.CreateConnectionResult{ID: "conn-1"}))
+
+	// Another replica claimed the delivery first
+	claimed, err := store.ClaimDueDeliveries(ctx, time.Now(), time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+
+	dispatcher.Dispatch(ctx)
+	assert.Zero(t, calls.Load())
+}
+
+func TestWebhookDispatcherIsolatesEndpoints(t *testing.T) {
+	ctx := context.Background()
+	dispatcher, webhooks, _ := newTestDispatcher(t)
+
+	release := make(chan struct{})
+	var slowCalls atomic.Int32
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		slowCalls.Add(1)
+		<-release
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer slow.Close()
+	defer close(release)
+
+	delivered := make(chan struct{}, 1)
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		delivered <- struct{}{}
+	}))
+	defer fast.Close()
+
+	for _, url := range []string{slow.URL, fast.URL} {
+		_, err := webhooks.CreateSubscription(ctx, domain.CreateSubscriptionRequest{URL: url})
+		require.NoError(t, err)
+	}
+	for i := 0; i < 3; i++ {
+		require.NoError(t, webhooks.Publish(ctx, "tenant-a", domain.EventConnectionCreated, domain.CreateConnectionResult{ID: "conn-1"}))
+	}
+
+	done := make(chan struct{})
+	go func() {
+		dispatcher.Dispatch(ctx)
+		close(done)
+	}()
+
+	// The healthy endpoint is served while the other one hangs
+	for i := 0; i < 3; i++ {
+		select {
+		case <-delivered:
+		case <-time.After(500 * time.Millisecond):
+			t.Fatal("delivery to the healthy endpoint was held up")
+		}
+	}
+
+	// After the first failure the rest of the slow endpoint's deliveries wait
+	<-done
+	assert.Equal(t, int32(1), slowCalls.Load())
+}
+
+func TestWebhookSubscriptionsStayOffInternalNetwork(t *testing.T) {
+	ctx := context.Background()
+	_, _, store := newTestDispatcher(t)
+	conf := &config.Configuration{Webhook: config.WebhookConfig{Timeout: time.Second, MaxAttempts: 2, Backoff: time.Minute, MaxBackoff: time.Minute, BatchSize: 10}}
+	webhooks := service.NewWebhookService(store, conf, logger.NewLogger())
+	dispatcher := NewWebhookDispatcher(store, conf, logger.NewLogger())
+
+	for _, url := range []string{"http://93.184.216.34/hook", "https://127.0.0.1/hook", "https://169.254.169.254/latest", "https://[::1]/hook"} {
+		_, err := webhooks.CreateSubscription(ctx, domain.CreateSubscriptionRequest{URL: url})
+		assert.Error(t, err, url)
+	}
+
+	// A host that resolves to an internal address later is refused when connecting
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer server.Close()
+	subscription := &entity.WebhookSubscriptionRecord{URL: server.URL, Secret: "s3cret", Events: domain.EventConnectionCreated}
+	require.NoError(t, store.SaveSubscription(ctx, subscription))
+	require.NoError(t, webhooks.Publish(ctx, "tenant-a", domain.EventConnectionCreated, domain.CreateConnectionResult{ID: "conn-1"}))
+
+	dispatcher.Dispatch(ctx)
+	assert.Zero(t, calls.Load())
+	delivery, err := store.GetDelivery(ctx, 1)
+	require.NoError(t, err)
+	assert.Contains(t, delivery.LastError, netguard.ErrForbiddenAddress.Error())
+}
@@ -1,42 +1,110 @@
 package http
 
 import (
+	"time"
+
+	"github.com/go-resty/resty/v2"
 	"github.com/gofiber/fiber/v2"
 	"usdw/config"
+	"usdw/pkg/common/exception"
+	"usdw/pkg/xero"
 )
 
+// stateCookie binds the OAuth state to the browser that started the flow
+const stateCookie = "xero_oauth_state"
+
 // XeroAuthHandler handles OAuth 2.0 authentication for Xero
-type XeroAuthHandler struct{}
+type XeroAuthHandler struct {
+	Tokens  *xero.TokenManager
+	Tenants *xero.TenantResolver
+	Client  *resty.Client
+	config.Configuration
+}
 
 // NewXeroAuthHandler initializes Xero auth routes
-
-func NewXeroAuthHandler(app fiber.Router) {
-	handler := &XeroAuthHandler{}
+func NewXeroAuthHandler(app fiber.Router, config *config.Configuration, tokens *xero.TokenManager, tenants *xero.TenantResolver) {
+	handler := &XeroAuthHandler{
+		Tokens:        tokens,
+		Tenants:       tenants,
+		Client:        resty.New(),
+		Configuration: *config,
+	}
+	app.Get("/oauth/connect", handler.HandleOAuthConnect)
 	app.Get("/oauth/callback", handler.HandleOAuthCallback)
 }
 
+// @Summary Connect a Xero organisation
+// @Description Redirects to the Xero consent screen to authorise a new organisation
+// @Tags OAuth
+// @Success 302
+// @Failure 500 {object} exception.ProblemDetails
+// @Router /oauth/connect [get]
+func (h *XeroAuthHandler) HandleOAuthConnect(c *fiber.Ctx) error {
+	state, nonce, err := xero.NewState(h.stateSecret(), h.Xero.StateTTL)
+	if err != nil {
+		return err
+	}
+
+	c.Cookie(&fiber.Cookie{
+		Name:     stateCookie,
+		Value:    nonce,
+		Expires:  time.Now().Add(h.Xero.StateTTL),
+		Secure:   h.Server.SSL,
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+
+	return c.Redirect(config.XeroOAuthConfig.AuthCodeURL(state), fiber.StatusFound)
+}
+
 // HandleOAuthCallback processes the redirect from Xero
 func (h *XeroAuthHandler) HandleOAuthCallback(c *fiber.Ctx) error {
+	// Reject callbacks that were not started by /oauth/connect in this browser
+	nonce, err := xero.VerifyState(h.stateSecret(), c.Query("state"))
+	if err != nil || nonce != c.Cookies(stateCookie) {
+		return exception.BadRequestError{Message: "Invalid OAuth state"}
+	}
+	c.ClearCookie(stateCookie)
+
 	// Get authorization code from query parameters
 	code := c.Query("code")
 	if code == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Authorization code not found"})
+		return exception.BadRequestError{Message: "Authorization code not found"}
 	}
 
 	// Exchange authorization code for access token
-	token, err := config.XeroOAuthConfig.Exchange(c.Context(), code)
+	token, err := config.XeroOAuthConfig.Exchange(c.UserContext(), code)
+	if err != nil {
+		return exception.UnauthorizedError{Message: "Failed to exchange authorization code"}
+	}
+
+	// Find the tenants this consent was granted for
+	connections, err := xero.FetchConnections(c.UserContext(), h.Client, token.AccessToken)
 	if err != nil {
-		//logger.Error("Failed to exchange token:", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to exchange token"})
+		return err
+	}
+
+	tenantIDs := make([]string, len(connections))
+	for i, connection := range connections {
+		tenantIDs[i] = connection.TenantID
 	}
 
-	// Store token (in-memory for now; use a DB or cache in production)
-	//logger.Info("Xero Access Token:", token.AccessToken)
+	// Persist the token, including its refresh token, for every authorised tenant
+	err = h.Tokens.SaveToken(c.UserContext(), tenantIDs, token)
+	if err != nil {
+		return err
+	}
+	h.Tenants.Invalidate()
 
-	// Redirect user to success page or API response
 	return c.JSON(fiber.Map{
-		"message":      "Xero OAuth successful!",
-		"access_token": token.AccessToken,
-		"expires_in":   token.Expiry,
+		"message": "Xero OAuth successful!",
+		"tenants": connections,
 	})
 }
+
+func (h *XeroAuthHandler) stateSecret() []byte {
+	if h.Xero.StateSecret != "" {
+		return []byte(h.Xero.StateSecret)
+	}
+	return []byte(h.Xero.ClientSecret)
+}
@@ -0,0 +1,63 @@
+package http
+
+import (
+	"encoding/json"
+
+	"github.com/gofiber/fiber/v2"
+	"usdw/config"
+	"usdw/pkg/common/exception"
+	"usdw/pkg/logger"
+	"usdw/pkg/xero"
+)
+
+// XeroWebhookHandler receives webhook deliveries from Xero
+type XeroWebhookHandler struct {
+	Router *xero.WebhookRouter
+	config.Configuration
+	logger logger.Logger
+}
+
+// NewXeroWebhookHandler initializes the Xero webhook route
+func NewXeroWebhookHandler(app fiber.Router, config *config.Configuration, router *xero.WebhookRouter, logger logger.Logger) {
+	handler := &XeroWebhookHandler{
+		Router:        router,
+		Configuration: *config,
+		logger:        logger,
+	}
+	app.Post("/webhooks/xero", handler.HandleWebhook)
+}
+
+// @Summary Receive Xero webhooks
+// @Description Verifies the x-xero-signature header and dispatches the events. Answers the intent to receive handshake with 200 when the signature is valid and 401 otherwise.
+// @Tags Webhooks
+// @Accept json
+// @Param x-xero-signature header string true "Base64 HMAC-SHA256 of the body keyed with the webhook key"
+// @Success 200
+// @Failure 400 {object} exception.ProblemDetails
+// @Failure 401
+// @Router /webhooks/xero [post]
+func (h *XeroWebhookHandler) HandleWebhook(c *fiber.Ctx) error {
+	// Xero expects an empty body in both cases and treats anything but 200 as a failure
+	signature := c.Get(xero.WebhookSignatureHeader)
+	if !xero.VerifyWebhookSignature([]byte(h.Xero.WebhookKey), c.Body(), signature) {
+		if h.Xero.WebhookKey == "" {
+			h.logger.Warnf("Rejected Xero webhook because XERO_WEBHOOK_KEY is not set")
+		}
+		return c.Status(fiber.StatusUnauthorized).Send(nil)
+	}
+
+	var payload xero.WebhookPayload
+	err := json.Unmarshal(c.Body(), &payload)
+	if err != nil {
+		return exception.BadRequestError{Message: "Invalid webhook payload"}
+	}
+
+	for _, event := range payload.Events {
+		err = h.Router.Dispatch(c.UserContext(), event)
+		if err != nil {
+			h.logger.Errorf("Failed to handle Xero %s %s event for tenant %s: %s", event.EventCategory, event.EventType, event.TenantID, err)
+		}
+	}
+
+	return c.Status(fiber.StatusOK).Send(nil)
+}
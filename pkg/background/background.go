@@ -0,0 +1,76 @@
+// Package background runs the periodic jobs of the service, like polling Xero and
+// delivering webhooks.
+package background
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"usdw/pkg/logger"
+)
+
+// Worker runs a job every interval in the background until Stop is called
+type Worker struct {
+	name     string
+	interval time.Duration
+	run      func(ctx context.Context)
+	logger   logger.Logger
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewWorker(name string, interval time.Duration, run func(ctx context.Context), logger logger.Logger) *Worker {
+	return &Worker{
+		name:     name,
+		interval: interval,
+		run:      run,
+		logger:   logger,
+	}
+}
+
+// Start runs the job straight away and then every interval. A zero interval disables
+// the worker.
+func (w *Worker) Start() {
+	if w.interval <= 0 {
+		w.logger.Infof("%s is disabled", w.name)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		for {
+			w.run(ctx)
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop cancels the current run and waits for it to finish
+func (w *Worker) Stop() {
+	if w.cancel == nil {
+		return
+	}
+	w.cancel()
+	w.wg.Wait()
+}
+
+// Backoff doubles base after every attempt, up to limit
+func Backoff(base, limit time.Duration, attempts int) time.Duration {
+	wait := base
+	for i := 1; i < attempts && wait < limit; i++ {
+		wait *= 2
+	}
+	return min(wait, limit)
+}
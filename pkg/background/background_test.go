@@ -0,0 +1,50 @@
+package background
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"usdw/pkg/logger"
+)
+
+func TestWorker(t *testing.T) {
+	var runs atomic.Int32
+	stopped := make(chan struct{})
+	worker := NewWorker("test worker", time.Hour, func(ctx context.Context) {
+		runs.Add(1)
+		<-ctx.Done()
+		close(stopped)
+	}, logger.NewLogger())
+
+	worker.Start()
+	assert.Eventually(t, func() bool { return runs.Load() == 1 }, time.Second, time.Millisecond)
+
+	// Stop cancels the running job and waits for it
+	worker.Stop()
+	select {
+	case <-stopped:
+	default:
+		t.Fatal("Stop returned before the job finished")
+	}
+	assert.Equal(t, int32(1), runs.Load())
+}
+
+func TestWorkerDisabled(t *testing.T) {
+	worker := NewWorker("test worker", 0, func(ctx context.Context) {
+		t.Fatal("a disabled worker ran")
+	}, logger.NewLogger())
+
+	worker.Start()
+	worker.Stop()
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, time.Minute, Backoff(time.Minute, 5*time.Minute, 1))
+	assert.Equal(t, 2*time.Minute, Backoff(time.Minute, 5*time.Minute, 2))
+	assert.Equal(t, 4*time.Minute, Backoff(time.Minute, 5*time.Minute, 3))
+	assert.Equal(t, 5*time.Minute, Backoff(time.Minute, 5*time.Minute, 4))
+	assert.Equal(t, 5*time.Minute, Backoff(time.Minute, 5*time.Minute, 40))
+}
@@ -1,31 +1,65 @@
 package cache
 
 import (
+	"context"
 	"time"
 	"usdw/config"
 	"usdw/pkg/cache/inmem"
 	"usdw/pkg/cache/redis"
+
+	"github.com/gofiber/fiber/v2"
 )
 
+// ErrNotFound is returned by Get for missing and expired keys
+var ErrNotFound = fiber.ErrNotFound
+
 type Engine interface {
-	Get(key string) ([]byte, error)
-	Set(key string, val []byte, exp time.Duration) error
-	Delete(key string) error
-	Reset() error
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, val []byte, ttl time.Duration) error
+	// SetNX stores val only when key is missing, atomically, and reports whether it did
+	SetNX(ctx context.Context, key string, val []byte, ttl time.Duration) (bool, error)
+	Delete(ctx context.Context, keys ...string) error
+	// DeletePrefix removes every key starting with prefix
+	DeletePrefix(ctx context.Context, prefix string) error
+	// Reset removes every key of the engine; with a key prefix only the keys under it
+	Reset(ctx context.Context) error
 	Close() error
-	Ping() error
+	Ping(ctx context.Context) error
+}
+
+// Scripter is implemented by engines that can run Lua scripts atomically,
+// i.e. the Redis standalone, cluster and Sentinel engines
+type Scripter interface {
+	Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error)
 }
 
 func NewCache(configuration *config.Configuration) (Engine, error) {
+	var engine Engine
 	switch configuration.Server.CacheDeploymentType {
 	case 1:
 		client, err := redis.NewStandaloneConn(configuration)
-		return client, err
+		if err != nil {
+			return nil, err
+		}
+		engine = client
 	case 2:
 		client, err := redis.NewClusterConn(configuration)
-		return client, err
+		if err != nil {
+			return nil, err
+		}
+		engine = client
+	case 3:
+		client, err := redis.NewSentinelConn(configuration)
+		if err != nil {
+			return nil, err
+		}
+		engine = client
 	default:
-		client := inmem.NewInMemoryCache()
-		return client, nil
+		engine = inmem.NewInMemoryCache(configuration.Server.CacheMaxItems, configuration.Server.CacheCleanupInterval)
+	}
+
+	if configuration.Server.CacheKeyPrefix != "" {
+		engine = Namespace(engine, configuration.Server.CacheKeyPrefix)
 	}
+	return engine, nil
 }
@@ -0,0 +1,161 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"usdw/config"
+	"usdw/pkg/cache/inmem"
+	"usdw/pkg/cache/redis"
+	"usdw/pkg/logger"
+)
+
+func engines(t *testing.T) map[string]Engine {
+	server := miniredis.RunT(t)
+	redisEngine, err := redis.NewStandaloneConn(&config.Configuration{Redis: config.RedisConfig{Address: server.Addr()}})
+	require.NoError(t, err)
+	t.Cleanup(func() { redisEngine.Close() })
+
+	return map[string]Engine{
+		"inmem": inmem.NewInMemoryCache(0, 0),
+		"redis": redisEngine,
+	}
+}
+
+func TestNamespaces(t *testing.T) {
+	for name, engine := range engines(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			tenantA := ForTenant(engine, "a")
+			tenantB := ForTenant(engine, "b")
+
+			require.NoError(t, tenantA.Set(ctx, "xero:connections:1", []byte("a1"), time.Minute))
+			require.NoError(t, tenantA.Set(ctx, "xero:connections:2", []byte("a2"), time.Minute))
+			require.NoError(t, tenantA.Set(ctx, "xero:token", []byte("a"), 0))
+			require.NoError(t, tenantB.Set(ctx, "xero:connections:1", []byte("b1"), time.Minute))
+			// Glob characters in a prefix are matched literally
+			require.NoError(t, tenantA.Set(ctx, "x*", []byte("star"), time.Minute))
+
+			val, err := engine.Get(ctx, "tenant:a:xero:connections:1")
+			require.NoError(t, err)
+			assert.Equal(t, "a1", string(val))
+
+			require.NoError(t, tenantA.DeletePrefix(ctx, "xero:connections:"))
+			_, err = tenantA.Get(ctx, "xero:connections:2")
+			assert.ErrorIs(t, err, ErrNotFound)
+			_, err = tenantA.Get(ctx, "xero:token")
+			assert.NoError(t, err)
+			_, err = tenantB.Get(ctx, "xero:connections:1")
+			assert.NoError(t, err)
+
+			require.NoError(t, tenantA.DeletePrefix(ctx, "x*"))
+			_, err = tenantA.Get(ctx, "xero:token")
+			assert.NoError(t, err)
+
+			// Reset only reaches the namespace
+			require.NoError(t, tenantA.Reset(ctx))
+			_, err = tenantA.Get(ctx, "xero:token")
+			assert.ErrorIs(t, err, ErrNotFound)
+			_, err = tenantB.Get(ctx, "xero:connections:1")
+			assert.NoError(t, err)
+
+			require.NoError(t, engine.Delete(ctx, "tenant:b:xero:connections:1", "missing"))
+			_, err = tenantB.Get(ctx, "xero:connections:1")
+			assert.ErrorIs(t, err, ErrNotFound)
+		})
+	}
+}
+
+func TestSetNX(t *testing.T) {
+	for name, engine := range engines(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			tenant := ForTenant(engine, "a")
+
+			var stored atomic.Int32
+			var wg sync.WaitGroup
+			for range 20 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					ok, err := tenant.SetNX(ctx, "lock", []byte("held"), time.Minute)
+					assert.NoError(t, err)
+					if ok {
+						stored.Add(1)
+					}
+				}()
+			}
+			wg.Wait()
+			assert.Equal(t, int32(1), stored.Load())
+
+			val, err := engine.Get(ctx, "tenant:a:lock")
+			require.NoError(t, err)
+			assert.Equal(t, "held", string(val))
+
+		})
+	}
+}
+
+func TestNamespaceKeepsScripting(t *testing.T) {
+	for name, engine := range engines(t) {
+		_, scripting := engine.(Scripter)
+		_, namespaced := Namespace(engine, "usdw:").(Scripter)
+		assert.Equal(t, scripting, namespaced, name)
+	}
+}
+
+func TestJSONGetOrSet(t *testing.T) {
+	type connection struct {
+		ID string `json:"id"`
+	}
+	store := NewJSON[connection](inmem.NewInMemoryCache(0, 0), logger.NewLogger())
+	ctx := context.Background()
+
+	var loads atomic.Int32
+	release := make(chan struct{})
+	load := func(ctx context.Context) (connection, error) {
+		loads.Add(1)
+		<-release
+		return connection{ID: "id-1"}, nil
+	}
+
+	// Concurrent misses share one load
+	var wg sync.WaitGroup
+	results := make([]connection, 10)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := store.GetOrSet(ctx, "connection", time.Minute, load)
+			assert.NoError(t, err)
+			results[i] = value
+		}()
+	}
+	assert.Eventually(t, func() bool { return loads.Load() == 1 }, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), loads.Load())
+	for _, value := range results {
+		assert.Equal(t, "id-1", value.ID)
+	}
+
+	value, err := store.Get(ctx, "connection")
+	require.NoError(t, err)
+	assert.Equal(t, "id-1", value.ID)
+
+	// A caller giving up does not wait for the load
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = store.GetOrSet(cancelled, "other", time.Minute, func(ctx context.Context) (connection, error) {
+		time.Sleep(50 * time.Millisecond)
+		return connection{}, nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -1,65 +1,194 @@
 package inmem
 
 import (
-	"errors"
+	"container/list"
+	"context"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/gofiber/fiber/v2"
 )
 
+// InMemoryCache keeps items in process, evicting the least recently used one once
+// maxItems is reached. A janitor removes expired items every cleanupInterval.
 type InMemoryCache struct {
-	mu    sync.RWMutex
-	store map[string]Item
+	mu       sync.Mutex
+	items    map[string]*list.Element
+	lru      *list.List // front is the most recently used
+	maxItems int
+	stop     chan struct{}
+	stopOnce sync.Once
 }
 
 type Item struct {
+	Key        string
 	Value      []byte
-	Expiration int64
+	Expiration int64 // unix nanoseconds, zero never expires
 }
 
-func NewInMemoryCache() *InMemoryCache {
-	return &InMemoryCache{
-		store: make(map[string]Item),
+// NewInMemoryCache returns a cache of at most maxItems items; zero means no limit.
+// A zero cleanupInterval leaves expired items until they are read or evicted.
+func NewInMemoryCache(maxItems int, cleanupInterval time.Duration) *InMemoryCache {
+	c := &InMemoryCache{
+		items:    make(map[string]*list.Element),
+		lru:      list.New(),
+		maxItems: maxItems,
+		stop:     make(chan struct{}),
+	}
+	if cleanupInterval > 0 {
+		go c.janitor(cleanupInterval)
 	}
+	return c
 }
 
-func (c *InMemoryCache) Set(key string, value []byte, duration time.Duration) error {
+func (c *InMemoryCache) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	var expiration int64
+	if ttl > 0 {
+		expiration = time.Now().Add(ttl).UnixNano()
+	}
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	expiration := time.Now().Add(duration).UnixNano()
-	c.store[key] = Item{
-		Value:      value,
-		Expiration: expiration,
+
+	if element, ok := c.items[key]; ok {
+		element.Value = &Item{Key: key, Value: value, Expiration: expiration}
+		c.lru.MoveToFront(element)
+		return nil
 	}
 
+	c.insert(&Item{Key: key, Value: value, Expiration: expiration})
 	return nil
 }
 
-func (c *InMemoryCache) Get(key string) ([]byte, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	item, found := c.store[key]
-	if !found || time.Now().UnixNano() > item.Expiration {
-		return nil, errors.New("cache miss")
+// SetNX stores value only when key is missing or expired
+func (c *InMemoryCache) SetNX(_ context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	var expiration int64
+	if ttl > 0 {
+		expiration = now.Add(ttl).UnixNano()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.items[key]; ok {
+		if !element.Value.(*Item).expired(now.UnixNano()) {
+			return false, nil
+		}
+		c.remove(element)
+	}
+
+	c.insert(&Item{Key: key, Value: value, Expiration: expiration})
+	return true, nil
+}
+
+func (c *InMemoryCache) Get(_ context.Context, key string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.items[key]
+	if !ok {
+		return nil, fiber.ErrNotFound
 	}
+	item := element.Value.(*Item)
+	if item.expired(time.Now().UnixNano()) {
+		c.remove(element)
+		return nil, fiber.ErrNotFound
+	}
+	c.lru.MoveToFront(element)
 	return item.Value, nil
 }
 
-func (c *InMemoryCache) Delete(key string) error {
+func (c *InMemoryCache) Delete(_ context.Context, keys ...string) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	delete(c.store, key)
 
+	for _, key := range keys {
+		if element, ok := c.items[key]; ok {
+			c.remove(element)
+		}
+	}
 	return nil
 }
 
-func (c *InMemoryCache) Close() error {
+func (c *InMemoryCache) DeletePrefix(_ context.Context, prefix string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, element := range c.items {
+		if strings.HasPrefix(key, prefix) {
+			c.remove(element)
+		}
+	}
+	return nil
+}
+
+func (c *InMemoryCache) Reset(_ context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.items = make(map[string]*list.Element)
+	c.lru.Init()
 	return nil
 }
 
-func (c *InMemoryCache) Reset() error {
+// Close stops the janitor
+func (c *InMemoryCache) Close() error {
+	c.stopOnce.Do(func() { close(c.stop) })
 	return nil
 }
 
-func (c *InMemoryCache) Ping() error {
+func (c *InMemoryCache) Ping(_ context.Context) error {
 	return nil
 }
+
+// Len is the number of items held, including expired ones not removed yet
+func (c *InMemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+func (c *InMemoryCache) janitor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.deleteExpired()
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+func (c *InMemoryCache) deleteExpired() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now().UnixNano()
+	for _, element := range c.items {
+		if element.Value.(*Item).expired(now) {
+			c.remove(element)
+		}
+	}
+}
+
+// insert adds a new item as the most recently used, evicting the least recently used one if full
+func (c *InMemoryCache) insert(item *Item) {
+	c.items[item.Key] = c.lru.PushFront(item)
+	if c.maxItems > 0 && c.lru.Len() > c.maxItems {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *InMemoryCache) remove(element *list.Element) {
+	c.lru.Remove(element)
+	delete(c.items, element.Value.(*Item).Key)
+}
+
+func (i *Item) expired(now int64) bool {
+	return i.Expiration > 0 && now > i.Expiration
+}
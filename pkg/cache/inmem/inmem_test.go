@@ -0,0 +1,62 @@
+package inmem
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInMemoryCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := NewInMemoryCache(2, 0)
+	ctx := context.Background()
+
+	require.NoError(t, cache.Set(ctx, "a", []byte("a"), 0))
+	require.NoError(t, cache.Set(ctx, "b", []byte("b"), 0))
+	_, err := cache.Get(ctx, "a")
+	require.NoError(t, err)
+	require.NoError(t, cache.Set(ctx, "c", []byte("c"), 0))
+
+	_, err = cache.Get(ctx, "b")
+	assert.ErrorIs(t, err, fiber.ErrNotFound)
+	_, err = cache.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, cache.Len())
+}
+
+func TestInMemoryCacheJanitor(t *testing.T) {
+	cache := NewInMemoryCache(0, 10*time.Millisecond)
+	defer cache.Close()
+	ctx := context.Background()
+
+	require.NoError(t, cache.Set(ctx, "expiring", []byte("x"), time.Millisecond))
+	require.NoError(t, cache.Set(ctx, "kept", []byte("x"), 0))
+
+	assert.Eventually(t, func() bool { return cache.Len() == 1 }, time.Second, 5*time.Millisecond)
+	_, err := cache.Get(ctx, "kept")
+	assert.NoError(t, err)
+}
+
+func TestInMemoryCacheSetNXTakesExpiredKeys(t *testing.T) {
+	cache := NewInMemoryCache(0, 0)
+	ctx := context.Background()
+
+	require.NoError(t, cache.Set(ctx, "lock", []byte("old"), time.Millisecond))
+	ok, err := cache.SetNX(ctx, "held", []byte("x"), time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = cache.SetNX(ctx, "held", []byte("y"), time.Minute)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	time.Sleep(5 * time.Millisecond)
+	ok, err = cache.SetNX(ctx, "lock", []byte("new"), time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	val, err := cache.Get(ctx, "lock")
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(val))
+}
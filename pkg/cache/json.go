@@ -0,0 +1,87 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+	"usdw/pkg/logger"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// JSON stores values of type T in an engine as JSON
+type JSON[T any] struct {
+	engine Engine
+	group  singleflight.Group
+	logger logger.Logger
+}
+
+func NewJSON[T any](engine Engine, logger logger.Logger) *JSON[T] {
+	return &JSON[T]{engine: engine, logger: logger}
+}
+
+// Get returns ErrNotFound for missing keys
+func (c *JSON[T]) Get(ctx context.Context, key string) (T, error) {
+	var value T
+	val, err := c.engine.Get(ctx, key)
+	if err != nil {
+		return value, err
+	}
+	err = json.Unmarshal(val, &value)
+	return value, err
+}
+
+func (c *JSON[T]) Set(ctx context.Context, key string, value T, ttl time.Duration) error {
+	val, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return c.engine.Set(ctx, key, val, ttl)
+}
+
+// SetNX stores value only when key is missing and reports whether it did
+func (c *JSON[T]) SetNX(ctx context.Context, key string, value T, ttl time.Duration) (bool, error) {
+	val, err := json.Marshal(value)
+	if err != nil {
+		return false, err
+	}
+	return c.engine.SetNX(ctx, key, val, ttl)
+}
+
+// GetOrSet returns the value cached under key, or loads it and caches it for ttl.
+// Concurrent misses for the same key share a single load, which is not cancelled when
+// one of the callers gives up. Cache failures are logged and fall back to loading.
+func (c *JSON[T]) GetOrSet(ctx context.Context, key string, ttl time.Duration, load func(ctx context.Context) (T, error)) (T, error) {
+	value, err := c.Get(ctx, key)
+	if err == nil {
+		return value, nil
+	}
+	if !errors.Is(err, ErrNotFound) {
+		c.logger.Errorf("Failed to read %s from cache: %s", key, err)
+	}
+
+	loadCtx := context.WithoutCancel(ctx)
+	result := c.group.DoChan(key, func() (interface{}, error) {
+		value, err := load(loadCtx)
+		if err != nil {
+			return value, err
+		}
+		if err := c.Set(loadCtx, key, value, ttl); err != nil {
+			c.logger.Errorf("Failed to cache %s: %s", key, err)
+		}
+		return value, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	case shared := <-result:
+		if shared.Err != nil {
+			var zero T
+			return zero, shared.Err
+		}
+		return shared.Val.(T), nil
+	}
+}
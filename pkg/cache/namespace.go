@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+type namespace struct {
+	engine Engine
+	prefix string
+}
+
+// Namespace returns a view of engine in which every key is prefixed. DeletePrefix and
+// Reset only reach the keys of the namespace. Closing it closes engine.
+func Namespace(engine Engine, prefix string) Engine {
+	ns := &namespace{engine: engine, prefix: prefix}
+	if _, ok := engine.(Scripter); ok {
+		return &scriptingNamespace{ns}
+	}
+	return ns
+}
+
+// ForTenant is the namespace of one Xero tenant
+func ForTenant(engine Engine, tenantID string) Engine {
+	return Namespace(engine, TenantPrefix(tenantID))
+}
+
+// TenantPrefix is the key prefix of the tenant namespace
+func TenantPrefix(tenantID string) string {
+	return "tenant:" + tenantID + ":"
+}
+
+func (n *namespace) Get(ctx context.Context, key string) ([]byte, error) {
+	return n.engine.Get(ctx, n.prefix+key)
+}
+
+func (n *namespace) Set(ctx context.Context, key string, val []byte, ttl time.Duration) error {
+	return n.engine.Set(ctx, n.prefix+key, val, ttl)
+}
+
+func (n *namespace) SetNX(ctx context.Context, key string, val []byte, ttl time.Duration) (bool, error) {
+	return n.engine.SetNX(ctx, n.prefix+key, val, ttl)
+}
+
+func (n *namespace) Delete(ctx context.Context, keys ...string) error {
+	return n.engine.Delete(ctx, n.keys(keys)...)
+}
+
+func (n *namespace) DeletePrefix(ctx context.Context, prefix string) error {
+	return n.engine.DeletePrefix(ctx, n.prefix+prefix)
+}
+
+func (n *namespace) Reset(ctx context.Context) error {
+	return n.engine.DeletePrefix(ctx, n.prefix)
+}
+
+func (n *namespace) Close() error {
+	return n.engine.Close()
+}
+
+func (n *namespace) Ping(ctx context.Context) error {
+	return n.engine.Ping(ctx)
+}
+
+func (n *namespace) keys(keys []string) []string {
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = n.prefix + key
+	}
+	return prefixed
+}
+
+// scriptingNamespace keeps Eval available when the engine has it
+type scriptingNamespace struct {
+	*namespace
+}
+
+func (n *scriptingNamespace) Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	return n.engine.(Scripter).Eval(ctx, script, n.keys(keys), args...)
+}
@@ -10,19 +10,11 @@ import (
 
 type ClusterClient struct {
 	client *redis.ClusterClient
-	ctx    context.Context
-}
-
-// WithContext for operate
-func (c *ClusterClient) WithContext(ctx context.Context) *ClusterClient {
-	cp := *c
-	cp.ctx = ctx
-	return &cp
 }
 
 // Get gets the value for the given key.
-func (c *ClusterClient) Get(key string) ([]byte, error) {
-	result := c.client.Get(c.ctx, key)
+func (c *ClusterClient) Get(ctx context.Context, key string) ([]byte, error) {
+	result := c.client.Get(ctx, key)
 	val, err := result.Bytes()
 	if redis.Nil == err {
 		return val, fiber.ErrNotFound
@@ -30,22 +22,42 @@ func (c *ClusterClient) Get(key string) ([]byte, error) {
 	return val, err
 }
 
-// Set stores the given value for the given key along with a
-func (c *ClusterClient) Set(key string, val []byte, ttl time.Duration) error {
-	result := c.client.Set(c.ctx, key, val, ttl)
+// Set stores the given value for the given key along with a ttl, zero never expires.
+func (c *ClusterClient) Set(ctx context.Context, key string, val []byte, ttl time.Duration) error {
+	result := c.client.Set(ctx, key, val, ttl)
 	return result.Err()
 }
 
-// Delete deletes the value for the given key.
-func (c *ClusterClient) Delete(key string) error {
-	result := c.client.Del(c.ctx, key)
-	return result.Err()
+// SetNX stores the given value only when the key does not exist, along with a ttl.
+func (c *ClusterClient) SetNX(ctx context.Context, key string, val []byte, ttl time.Duration) (bool, error) {
+	return c.client.SetNX(ctx, key, val, ttl).Result()
 }
 
-// Reset resets the storage and delete all keys.
-func (c *ClusterClient) Reset() error {
-	result := c.client.FlushAll(c.ctx)
-	return result.Err()
+// Delete deletes the values for the given keys. Keys may live in different slots, so
+// they are deleted one by one in a pipeline.
+func (c *ClusterClient) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	_, err := c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.Del(ctx, key)
+		}
+		return nil
+	})
+	return err
+}
+
+// DeletePrefix deletes every key starting with prefix on every master.
+func (c *ClusterClient) DeletePrefix(ctx context.Context, prefix string) error {
+	return c.client.ForEachMaster(ctx, func(ctx context.Context, shard *redis.Client) error {
+		return deleteMatching(ctx, shard, prefix)
+	})
+}
+
+// Reset deletes every key of the cluster.
+func (c *ClusterClient) Reset(ctx context.Context) error {
+	return c.DeletePrefix(ctx, "")
 }
 
 // Close closes the storage and will stop any running garbage
@@ -54,15 +66,15 @@ func (c *ClusterClient) Close() error {
 }
 
 // Ping check connection
-func (c *ClusterClient) Ping() error {
-	err := c.client.ForEachShard(c.ctx, func(ctx context.Context, shard *redis.Client) error {
+func (c *ClusterClient) Ping(ctx context.Context) error {
+	err := c.client.ForEachShard(ctx, func(ctx context.Context, shard *redis.Client) error {
 		return shard.Ping(ctx).Err()
 	})
 	if err != nil {
 		return err
 	}
 
-	err = c.client.ForEachSlave(c.ctx, func(ctx context.Context, shard *redis.Client) error {
+	err = c.client.ForEachSlave(ctx, func(ctx context.Context, shard *redis.Client) error {
 		return shard.Ping(ctx).Err()
 	})
 	if err != nil {
@@ -71,3 +83,8 @@ func (c *ClusterClient) Ping() error {
 
 	return nil
 }
+
+// Eval runs a Lua script atomically on the shard owning keys
+func (c *ClusterClient) Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	return c.client.Eval(ctx, script, keys, args...).Result()
+}
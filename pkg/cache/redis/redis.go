@@ -2,6 +2,10 @@ package redis
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
 	"strings"
 	"time"
 	"usdw/config"
@@ -23,13 +27,20 @@ func NewClusterConn(cfg *config.Configuration) (*ClusterClient, error) {
 
 	adds := strings.Split(cfg.RedisCluster.Address, cfg.RedisCluster.Delimiter)
 
+	tlsConfig, err := newTLSConfig(cfg.RedisCluster.TLS, cfg.RedisCluster.TLSCAFile, cfg.RedisCluster.TLSServerName)
+	if err != nil {
+		return nil, err
+	}
+
 	client := redis.NewClusterClient(&redis.ClusterOptions{
 		Addrs:           adds,
 		ReadOnly:        cfg.RedisCluster.ReadOnly,
 		MinIdleConns:    cfg.RedisCluster.MinIdleCons,
 		PoolSize:        cfg.RedisCluster.PoolSize,
 		PoolTimeout:     time.Duration(cfg.RedisCluster.PoolTimeout) * time.Second,
+		Username:        cfg.RedisCluster.Username,
 		Password:        cfg.RedisCluster.Password, // no password set
+		TLSConfig:       tlsConfig,
 		MaxRetries:      maxRetries,
 		MinRetryBackoff: minRetryBackoff,
 		MaxRetryBackoff: maxRetryBackoff,
@@ -38,11 +49,9 @@ func NewClusterConn(cfg *config.Configuration) (*ClusterClient, error) {
 		WriteTimeout:    writeTimeout,
 	})
 
-	clusterClient := &ClusterClient{
-		client: client,
-		ctx:    context.Background(),
-	}
-	if err := clusterClient.Ping(); err != nil {
+	clusterClient := &ClusterClient{client: client}
+	if err := clusterClient.Ping(context.Background()); err != nil {
+		_ = client.Close()
 		return nil, err
 	}
 
@@ -57,12 +66,19 @@ func NewStandaloneConn(cfg *config.Configuration) (*StandaloneClient, error) {
 		redisHost = ":6379"
 	}
 
+	tlsConfig, err := newTLSConfig(cfg.Redis.TLS, cfg.Redis.TLSCAFile, cfg.Redis.TLSServerName)
+	if err != nil {
+		return nil, err
+	}
+
 	client := redis.NewClient(&redis.Options{
 		Addr:            redisHost,
 		MinIdleConns:    cfg.Redis.MinIdleCons,
 		PoolSize:        cfg.Redis.PoolSize,
 		PoolTimeout:     time.Duration(cfg.Redis.PoolTimeout) * time.Second,
+		Username:        cfg.Redis.Username,
 		Password:        cfg.Redis.Password, // no password set
+		TLSConfig:       tlsConfig,
 		DB:              cfg.Redis.DB,
 		MaxRetries:      maxRetries,
 		MinRetryBackoff: minRetryBackoff,
@@ -72,12 +88,80 @@ func NewStandaloneConn(cfg *config.Configuration) (*StandaloneClient, error) {
 		WriteTimeout:    writeTimeout,
 	})
 
-	standaloneClient := &StandaloneClient{
-		client: client,
-		ctx:    context.Background(),
-	}
-	if err := standaloneClient.Ping(); err != nil {
+	standaloneClient := &StandaloneClient{client: client}
+	if err := standaloneClient.Ping(context.Background()); err != nil {
+		_ = client.Close()
 		return nil, err
 	}
 	return standaloneClient, nil
 }
+
+// NewSentinelConn returns a redis client for the master Sentinel reports, following
+// failovers. It behaves like a standalone client.
+func NewSentinelConn(cfg *config.Configuration) (*StandaloneClient, error) {
+	if cfg.RedisSentinel.MasterName == "" || cfg.RedisSentinel.Address == "" {
+		return nil, fmt.Errorf("redis sentinel needs a master name and at least one sentinel address")
+	}
+
+	tlsConfig, err := newTLSConfig(cfg.RedisSentinel.TLS, cfg.RedisSentinel.TLSCAFile, cfg.RedisSentinel.TLSServerName)
+	if err != nil {
+		return nil, err
+	}
+
+	client := redis.NewFailoverClient(&redis.FailoverOptions{
+		MasterName:       cfg.RedisSentinel.MasterName,
+		SentinelAddrs:    strings.Split(cfg.RedisSentinel.Address, cfg.RedisSentinel.Delimiter),
+		SentinelUsername: cfg.RedisSentinel.SentinelUsername,
+		SentinelPassword: cfg.RedisSentinel.SentinelPassword,
+		Username:         cfg.RedisSentinel.Username,
+		Password:         cfg.RedisSentinel.Password,
+		DB:               cfg.RedisSentinel.DB,
+		MinIdleConns:     cfg.RedisSentinel.MinIdleCons,
+		PoolSize:         cfg.RedisSentinel.PoolSize,
+		PoolTimeout:      time.Duration(cfg.RedisSentinel.PoolTimeout) * time.Second,
+		TLSConfig:        tlsConfig,
+		MaxRetries:       maxRetries,
+		MinRetryBackoff:  minRetryBackoff,
+		MaxRetryBackoff:  maxRetryBackoff,
+		DialTimeout:      dialTimeout,
+		ReadTimeout:      readTimeout,
+		WriteTimeout:     writeTimeout,
+	})
+
+	sentinelClient := &StandaloneClient{client: client}
+	if err := sentinelClient.Ping(context.Background()); err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+	return sentinelClient, nil
+}
+
+// newTLSConfig returns nil when TLS is off. caFile adds a PEM CA, e.g. of a managed
+// Redis, to the system roots.
+func newTLSConfig(enabled bool, caFile, serverName string) (*tls.Config, error) {
+	if !enabled {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: serverName,
+	}
+	if caFile == "" {
+		return tlsConfig, nil
+	}
+
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read redis CA file: %w", err)
+	}
+	roots, err := x509.SystemCertPool()
+	if err != nil {
+		roots = x509.NewCertPool()
+	}
+	if !roots.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("redis CA file %s holds no PEM certificates", caFile)
+	}
+	tlsConfig.RootCAs = roots
+	return tlsConfig, nil
+}
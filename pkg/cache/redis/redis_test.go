@@ -0,0 +1,200 @@
+package redis
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"usdw/config"
+)
+
+// engine is the part of cache.Engine these tests use; cache imports this package
+type engine interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, val []byte, ttl time.Duration) error
+	DeletePrefix(ctx context.Context, prefix string) error
+	Close() error
+}
+
+func exercise(t *testing.T, client engine) {
+	ctx := context.Background()
+	require.NoError(t, client.Set(ctx, "usdw-test:a", []byte("a"), time.Minute))
+	require.NoError(t, client.Set(ctx, "usdw-test:b", []byte("b"), time.Minute))
+
+	val, err := client.Get(ctx, "usdw-test:a")
+	require.NoError(t, err)
+	assert.Equal(t, "a", string(val))
+
+	require.NoError(t, client.DeletePrefix(ctx, "usdw-test:"))
+	_, err = client.Get(ctx, "usdw-test:b")
+	assert.ErrorIs(t, err, fiber.ErrNotFound)
+}
+
+// newTestTLS returns a server certificate for 127.0.0.1 and the file of the CA signing it
+func newTestTLS(t *testing.T) (*tls.Config, string) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "usdw test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	ca, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	serverKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	serverDER, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "redis"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		DNSNames:     []string{"redis.internal"},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, ca, &serverKey.PublicKey, caKey)
+	require.NoError(t, err)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}), 0o600))
+
+	return &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{serverDER}, PrivateKey: serverKey}}}, caFile
+}
+
+func TestStandaloneTLSWithACLUser(t *testing.T) {
+	serverTLS, caFile := newTestTLS(t)
+	server := miniredis.NewMiniRedis()
+	require.NoError(t, server.StartTLS(serverTLS))
+	t.Cleanup(server.Close)
+	server.RequireUserAuth("usdw", "secret")
+
+	cfg := &config.Configuration{Redis: config.RedisConfig{
+		Address:   server.Addr(),
+		Username:  "usdw",
+		Password:  "secret",
+		TLS:       true,
+		TLSCAFile: caFile,
+	}}
+	client, err := NewStandaloneConn(cfg)
+	require.NoError(t, err)
+	defer client.Close()
+	exercise(t, client)
+
+	// The server name can differ from the address
+	named := *cfg
+	named.Redis.TLSServerName = "redis.internal"
+	client, err = NewStandaloneConn(&named)
+	require.NoError(t, err)
+	client.Close()
+
+	wrongUser := *cfg
+	wrongUser.Redis.Username = "default"
+	_, err = NewStandaloneConn(&wrongUser)
+	assert.Error(t, err)
+
+	// The test CA is not in the system roots
+	noCA := *cfg
+	noCA.Redis.TLSCAFile = ""
+	_, err = NewStandaloneConn(&noCA)
+	assert.Error(t, err)
+}
+
+func TestClusterTLS(t *testing.T) {
+	serverTLS, caFile := newTestTLS(t)
+	server := miniredis.NewMiniRedis()
+	require.NoError(t, server.StartTLS(serverTLS))
+	t.Cleanup(server.Close)
+
+	client, err := NewClusterConn(&config.Configuration{RedisCluster: config.RedisClusterConfig{
+		Address:   server.Addr(),
+		Delimiter: ",",
+		TLS:       true,
+		TLSCAFile: caFile,
+	}})
+	require.NoError(t, err)
+	defer client.Close()
+	exercise(t, client)
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	tlsConfig, err := newTLSConfig(false, "missing.pem", "")
+	assert.NoError(t, err)
+	assert.Nil(t, tlsConfig)
+
+	_, err = newTLSConfig(true, filepath.Join(t.TempDir(), "missing.pem"), "")
+	assert.Error(t, err)
+
+	notPEM := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(notPEM, []byte("not a certificate"), 0o600))
+	_, err = newTLSConfig(true, notPEM, "")
+	assert.Error(t, err)
+}
+
+func TestSentinelNeedsMasterAndAddress(t *testing.T) {
+	_, err := NewSentinelConn(&config.Configuration{RedisSentinel: config.RedisSentinelConfig{Address: "localhost:26379", Delimiter: ","}})
+	assert.Error(t, err)
+}
+
+// TestLocalRedisServer runs against a real redis-server, e.g. from docker-compose, when
+// USDW_TEST_REDIS_ADDRESS, USDW_TEST_REDIS_CLUSTER_ADDRESS or USDW_TEST_REDIS_SENTINEL_ADDRESS
+// (with USDW_TEST_REDIS_SENTINEL_MASTER) are set
+func TestLocalRedisServer(t *testing.T) {
+	t.Run("standalone", func(t *testing.T) {
+		address := os.Getenv("USDW_TEST_REDIS_ADDRESS")
+		if address == "" {
+			t.Skip("USDW_TEST_REDIS_ADDRESS is not set")
+		}
+		client, err := NewStandaloneConn(&config.Configuration{Redis: config.RedisConfig{Address: address}})
+		require.NoError(t, err)
+		defer client.Close()
+		exercise(t, client)
+	})
+
+	t.Run("cluster", func(t *testing.T) {
+		address := os.Getenv("USDW_TEST_REDIS_CLUSTER_ADDRESS")
+		if address == "" {
+			t.Skip("USDW_TEST_REDIS_CLUSTER_ADDRESS is not set")
+		}
+		client, err := NewClusterConn(&config.Configuration{RedisCluster: config.RedisClusterConfig{Address: address, Delimiter: ","}})
+		require.NoError(t, err)
+		defer client.Close()
+		exercise(t, client)
+	})
+
+	t.Run("sentinel", func(t *testing.T) {
+		address := os.Getenv("USDW_TEST_REDIS_SENTINEL_ADDRESS")
+		if address == "" {
+			t.Skip("USDW_TEST_REDIS_SENTINEL_ADDRESS is not set")
+		}
+		client, err := NewSentinelConn(&config.Configuration{RedisSentinel: config.RedisSentinelConfig{
+			MasterName: os.Getenv("USDW_TEST_REDIS_SENTINEL_MASTER"),
+			Address:    address,
+			Delimiter:  ",",
+		}})
+		require.NoError(t, err)
+		defer client.Close()
+		exercise(t, client)
+	})
+}
@@ -0,0 +1,44 @@
+package redis
+
+import (
+	"context"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// scanCount is the number of keys asked for per SCAN call
+const scanCount = 500
+
+var globEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`)
+
+// deleteMatching deletes the keys starting with prefix on one server, page by page so
+// the server is never blocked like it is by KEYS or FLUSHALL. On a cluster shard every
+// key is deleted on its own, as a page can span slots.
+func deleteMatching(ctx context.Context, client *redis.Client, prefix string) error {
+	match := globEscaper.Replace(prefix) + "*"
+	var cursor uint64
+	for {
+		keys, next, err := client.Scan(ctx, cursor, match, scanCount).Result()
+		if err != nil {
+			return err
+		}
+
+		if len(keys) > 0 {
+			_, err = client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+				for _, key := range keys {
+					pipe.Del(ctx, key)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		cursor = next
+		if cursor == 0 {
+			return nil
+		}
+	}
+}
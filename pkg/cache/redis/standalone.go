@@ -11,19 +11,11 @@ import (
 
 type StandaloneClient struct {
 	client *redis.Client
-	ctx    context.Context
-}
-
-// WithContext for operate
-func (c *StandaloneClient) WithContext(ctx context.Context) *StandaloneClient {
-	cp := *c
-	cp.ctx = ctx
-	return &cp
 }
 
 // Get gets the value for the given key.
-func (c *StandaloneClient) Get(key string) ([]byte, error) {
-	result := c.client.Get(c.ctx, key)
+func (c *StandaloneClient) Get(ctx context.Context, key string) ([]byte, error) {
+	result := c.client.Get(ctx, key)
 	val, err := result.Bytes()
 	if redis.Nil == err {
 		return val, fiber.ErrNotFound
@@ -31,31 +23,48 @@ func (c *StandaloneClient) Get(key string) ([]byte, error) {
 	return val, err
 }
 
-// Set stores the given value for the given key along with a
-func (c *StandaloneClient) Set(key string, val []byte, ttl time.Duration) error {
-	result := c.client.Set(c.ctx, key, val, ttl)
+// Set stores the given value for the given key along with a ttl, zero never expires.
+func (c *StandaloneClient) Set(ctx context.Context, key string, val []byte, ttl time.Duration) error {
+	result := c.client.Set(ctx, key, val, ttl)
 	return result.Err()
 }
 
-// Delete deletes the value for the given key.
-func (c *StandaloneClient) Delete(key string) error {
-	result := c.client.Del(c.ctx, key)
-	return result.Err()
+// SetNX stores the given value only when the key does not exist, along with a ttl.
+func (c *StandaloneClient) SetNX(ctx context.Context, key string, val []byte, ttl time.Duration) (bool, error) {
+	return c.client.SetNX(ctx, key, val, ttl).Result()
 }
 
-// Reset resets the storage and delete all keys.
-func (c *StandaloneClient) Reset() error {
-	result := c.client.FlushAll(c.ctx)
+// Delete deletes the values for the given keys.
+func (c *StandaloneClient) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	result := c.client.Del(ctx, keys...)
 	return result.Err()
 }
 
+// DeletePrefix deletes every key starting with prefix.
+func (c *StandaloneClient) DeletePrefix(ctx context.Context, prefix string) error {
+	return deleteMatching(ctx, c.client, prefix)
+}
+
+// Reset deletes every key of the selected database, without touching other databases.
+func (c *StandaloneClient) Reset(ctx context.Context) error {
+	return c.DeletePrefix(ctx, "")
+}
+
 // Close closes the storage and will stop any running garbage
 func (c *StandaloneClient) Close() error {
 	return c.client.Close()
 }
 
 // Ping check connection
-func (c *StandaloneClient) Ping() error {
-	_, err := c.client.Ping(c.ctx).Result()
+func (c *StandaloneClient) Ping(ctx context.Context) error {
+	_, err := c.client.Ping(ctx).Result()
 	return err
 }
+
+// Eval runs a Lua script atomically on the server
+func (c *StandaloneClient) Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	return c.client.Eval(ctx, script, keys, args...).Result()
+}
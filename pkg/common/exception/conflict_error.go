@@ -0,0 +1,9 @@
+package exception
+
+type ConflictError struct {
+	Message string
+}
+
+func (err ConflictError) Error() string {
+	return err.Message
+}
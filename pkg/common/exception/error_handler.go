@@ -1,35 +1,21 @@
 package exception
 
 import (
-	"usdw/internal/domain/constant"
-
 	"errors"
+	"strconv"
 
 	"github.com/gofiber/fiber/v2"
+	"usdw/pkg/xero"
 )
 
 func ErrorHandler(ctx *fiber.Ctx, err error) error {
-	var resp *ErrorResponse
-	switch err.(type) {
-	case BadRequestError:
-		resp = DefaultErrBadRequest
-		resp.Errors = err.Error()
-	case NotFoundError:
-		resp = DefaultErrNotFound
-		resp.Errors = err.Error()
-	case UnauthorizedError:
-		resp = DefaultErrUnauthenticated
-		resp.Errors = err.Error()
-	default:
-		resp = &DefaultErrorResponse
-		resp.Errors = err.Error()
+	problem := NewProblemDetails(err)
+	problem.Instance = ctx.OriginalURL()
 
-	}
-	var e *fiber.Error
-	if errors.As(err, &e) {
-		resp.Code = constant.FRAMEWORK_ERROR
-		resp.Message = e.Message
+	var apiErr *xero.APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		ctx.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(apiErr.RetryAfter.Seconds())))
 	}
 
-	return ctx.Status(*resp.HTTPStatus).JSON(resp)
+	return ctx.Status(problem.Status).JSON(problem, ProblemContentType)
 }
@@ -0,0 +1,50 @@
+package exception
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"usdw/pkg/xero"
+)
+
+func TestErrorHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		title  string
+	}{
+		{"xero conflict", &xero.APIError{StatusCode: 409, Problem: xero.Problem{Type: "duplicate-statement", Title: "Duplicate Statement"}}, 409, "Duplicate Statement"},
+		{"xero rate limit", &xero.APIError{StatusCode: 429, Problem: xero.Problem{Title: "Too Many Requests"}, RetryAfter: 30 * time.Second}, 429, "Too Many Requests"},
+		{"xero token revoked", &xero.APIError{StatusCode: 401, Problem: xero.Problem{Title: "Unauthorized"}}, 502, "Unauthorized"},
+		{"xero scope missing", &xero.APIError{StatusCode: 403, Problem: xero.Problem{Title: "Forbidden"}}, 403, "Forbidden"},
+		{"xero outage", &xero.APIError{StatusCode: 503, Problem: xero.Problem{Title: "Service Unavailable"}}, 502, "Service Unavailable"},
+		{"missing tenant", xero.ErrTenantRequired, 400, "Bad Request"},
+		{"bad request", BadRequestError{Message: "Invalid request format"}, 400, "Bad Request"},
+		{"conflict", ConflictError{Message: "Idempotency-Key reused"}, 409, "Conflict"},
+		{"unknown", errors.New("boom"), 500, "Internal Server Error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+			app.Get("/", func(c *fiber.Ctx) error { return tt.err })
+
+			resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.status, resp.StatusCode)
+			assert.Equal(t, ProblemContentType, resp.Header.Get(fiber.HeaderContentType))
+
+			var problem ProblemDetails
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(&problem))
+			assert.Equal(t, tt.status, problem.Status)
+			assert.Equal(t, tt.title, problem.Title)
+			assert.Equal(t, "/", problem.Instance)
+		})
+	}
+}
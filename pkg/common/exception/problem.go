@@ -0,0 +1,99 @@
+package exception
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"usdw/internal/domain"
+	"usdw/internal/domain/constant"
+	"usdw/pkg/xero"
+)
+
+const ProblemContentType = "application/problem+json"
+
+// ProblemDetails is an RFC 7807 problem response
+type ProblemDetails struct {
+	Type     string      `json:"type"`
+	Title    string      `json:"title"`
+	Status   int         `json:"status"`
+	Detail   string      `json:"detail,omitempty"`
+	Instance string      `json:"instance,omitempty"`
+	Code     string      `json:"code,omitempty"`
+	Errors   interface{} `json:"errors,omitempty"`
+}
+
+// NewProblemDetails maps an error returned by a handler to a problem response
+func NewProblemDetails(err error) *ProblemDetails {
+	var apiErr *xero.APIError
+	var fiberErr *fiber.Error
+	var validationErr *domain.StatementValidationError
+
+	switch {
+	case errors.As(err, &apiErr):
+		return xeroProblem(apiErr)
+	case errors.As(err, &validationErr):
+		problem := newProblem(http.StatusBadRequest, constant.INVALID, err)
+		problem.Type = "statement-validation-failed"
+		problem.Errors = validationErr.Items
+		return problem
+	case errors.Is(err, xero.ErrTenantRequired):
+		return newProblem(http.StatusBadRequest, constant.INVALID, err)
+	case errors.Is(err, xero.ErrTenantNotConnected), errors.Is(err, xero.ErrNoTenants):
+		return newProblem(http.StatusForbidden, constant.PERMISSION_DENIED, err)
+	case errors.Is(err, xero.ErrReauthorizationRequired):
+		return newProblem(http.StatusUnauthorized, constant.UNAUTHENTICATED, err)
+	case errors.As(err, &fiberErr):
+		return newProblem(fiberErr.Code, constant.FRAMEWORK_ERROR, err)
+	}
+
+	switch err.(type) {
+	case BadRequestError:
+		return newProblem(http.StatusBadRequest, constant.INVALID, err)
+	case NotFoundError:
+		return newProblem(http.StatusNotFound, constant.NOT_FOUND, err)
+	case ConflictError:
+		return newProblem(http.StatusConflict, constant.CONFLICT, err)
+	case UnauthorizedError:
+		return newProblem(http.StatusUnauthorized, constant.UNAUTHENTICATED, err)
+	default:
+		return newProblem(http.StatusInternalServerError, constant.INTERNAL_ERROR, err)
+	}
+}
+
+func newProblem(status int, code string, err error) *ProblemDetails {
+	return &ProblemDetails{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: err.Error(),
+		Code:   code,
+	}
+}
+
+// xeroProblem surfaces client errors from Xero with their own status, including a 403
+// for a tenant or feed connection the app may not access. Server errors on the Xero side
+// become a 502, and so does a 401: it concerns our own Xero token, not the credentials of
+// the API caller.
+func xeroProblem(apiErr *xero.APIError) *ProblemDetails {
+	status := apiErr.StatusCode
+	switch {
+	case status >= http.StatusInternalServerError, status == http.StatusUnauthorized:
+		status = http.StatusBadGateway
+	}
+
+	problem := &ProblemDetails{
+		Type:   apiErr.Type,
+		Title:  apiErr.Title,
+		Status: status,
+		Detail: apiErr.Detail,
+		Code:   constant.UPSTREAM_ERROR,
+	}
+	if problem.Type == "" {
+		problem.Type = "about:blank"
+	}
+	if len(apiErr.ValidationErrors) > 0 {
+		problem.Errors = apiErr.ValidationErrors
+	}
+	return problem
+}
@@ -1,13 +1,72 @@
 package db
 
 import (
+	"fmt"
 	"usdw/config"
+	"usdw/pkg/logger"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
 )
 
 type DB struct {
+	*gorm.DB
 }
 
-func NewDB(conf *config.Configuration) (*DB, error) {
+func NewDB(conf *config.Configuration, logger logger.Logger) (*DB, error) {
+	dialector, err := newDialector(conf.Database)
+	if err != nil {
+		return nil, err
+	}
+
+	gormDB, err := gorm.Open(dialector, &gorm.Config{
+		Logger: logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s database: %w", conf.Database.Driver, err)
+	}
+
+	sqlDB, err := gormDB.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(conf.Database.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(conf.Database.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(conf.Database.ConnMaxLifetime)
+
+	return &DB{DB: gormDB}, nil
+}
+
+// Close closes the underlying connection pool
+func (d *DB) Close() error {
+	sqlDB, err := d.DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+func newDialector(conf config.DatabaseConfig) (gorm.Dialector, error) {
+	switch conf.Driver {
+	case "sqlite", "":
+		return sqlite.Open(conf.SQLitePath), nil
+	case "postgres":
+		dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+			conf.Host, conf.Port, conf.User, conf.Password, conf.DBName, sslMode(conf.SSLMode))
+		return postgres.Open(dsn), nil
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", conf.Driver)
+	}
+}
 
-	return &DB{}, nil
+// sslMode accepts libpq modes as well as true/false
+func sslMode(mode string) string {
+	switch mode {
+	case "true":
+		return "require"
+	case "false", "":
+		return "disable"
+	}
+	return mode
 }
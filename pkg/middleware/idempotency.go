@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"usdw/pkg/cache"
+	"usdw/pkg/common/exception"
+	"usdw/pkg/logger"
+	"usdw/pkg/xero"
+)
+
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+	// idempotencyLockTTL frees the key if the first request never completes, e.g. on a crash
+	idempotencyLockTTL = 5 * time.Minute
+)
+
+// idempotentResponse is the outcome of the first request made with a key
+type idempotentResponse struct {
+	BodyHash    string `json:"bodyHash"`
+	InProgress  bool   `json:"inProgress,omitempty"`
+	Status      int    `json:"status,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
+
+// IdempotencyMiddleware stores the response of a request carrying an Idempotency-Key
+// for ttl and replays it when the request is retried with the same key. Keys are scoped
+// to the path in the tenant's cache namespace. Reusing a key with a different body, or
+// while the first request is still running, is a conflict. Requests without the header
+// pass through.
+func IdempotencyMiddleware(engine cache.Engine, ttl time.Duration, logger logger.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := c.Get(IdempotencyKeyHeader)
+		if key == "" {
+			return c.Next()
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			return exception.BadRequestError{Message: fmt.Sprintf("%s must be at most %d characters", IdempotencyKeyHeader, maxIdempotencyKeyLength)}
+		}
+
+		ctx := c.UserContext()
+		hash := sha256.Sum256(c.Body())
+		bodyHash := hex.EncodeToString(hash[:])
+		tenantCache := cache.ForTenant(engine, xero.TenantIDFromContext(ctx))
+		responses := cache.NewJSON[idempotentResponse](tenantCache, logger)
+		cacheKey := fmt.Sprintf("idempotency:%s:%s:%s", c.Method(), c.Path(), key)
+
+		// Only the request taking the lock runs, concurrent retries see it and back off
+		locked, err := responses.SetNX(ctx, cacheKey, idempotentResponse{BodyHash: bodyHash, InProgress: true}, idempotencyLockTTL)
+		if err != nil {
+			return fmt.Errorf("failed to lock idempotency key: %w", err)
+		}
+		if !locked {
+			return replay(c, responses, cacheKey, bodyHash)
+		}
+
+		// Render errors here so their response can be stored like any other
+		if err := c.Next(); err != nil {
+			if err := c.App().ErrorHandler(c, err); err != nil {
+				_ = tenantCache.Delete(ctx, cacheKey)
+				return err
+			}
+		}
+
+		// Server errors and rate limits are not final, so a retry runs the request again
+		status := c.Response().StatusCode()
+		if status >= fiber.StatusInternalServerError || status == fiber.StatusTooManyRequests {
+			err = tenantCache.Delete(ctx, cacheKey)
+		} else {
+			err = responses.Set(ctx, cacheKey, idempotentResponse{
+				BodyHash:    bodyHash,
+				Status:      status,
+				ContentType: string(c.Response().Header.ContentType()),
+				Body:        c.Response().Body(),
+			}, ttl)
+		}
+		if err != nil {
+			logger.Errorf("Failed to store idempotent response for %s: %s", cacheKey, err)
+		}
+		return nil
+	}
+}
+
+// replay sends the stored response of the request that holds the key
+func replay(c *fiber.Ctx, responses *cache.JSON[idempotentResponse], cacheKey, bodyHash string) error {
+	stored, err := responses.Get(c.UserContext(), cacheKey)
+	if errors.Is(err, cache.ErrNotFound) {
+		// The first request failed and released the key just now
+		return exception.ConflictError{Message: "A request with this " + IdempotencyKeyHeader + " is still in progress"}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load idempotent response: %w", err)
+	}
+
+	switch {
+	case stored.BodyHash != bodyHash:
+		return exception.ConflictError{Message: IdempotencyKeyHeader + " was already used with a different request body"}
+	case stored.InProgress:
+		return exception.ConflictError{Message: "A request with this " + IdempotencyKeyHeader + " is still in progress"}
+	}
+
+	c.Set(IdempotentReplayedHeader, "true")
+	c.Set(fiber.HeaderContentType, stored.ContentType)
+	return c.Status(stored.Status).Send(stored.Body)
+}
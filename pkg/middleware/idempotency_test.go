@@ -0,0 +1,128 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"usdw/pkg/cache/inmem"
+	"usdw/pkg/common/exception"
+	"usdw/pkg/logger"
+)
+
+func newIdempotentApp(handler fiber.Handler) *fiber.App {
+	app := fiber.New(fiber.Config{ErrorHandler: exception.ErrorHandler})
+	app.Post("/statements", IdempotencyMiddleware(inmem.NewInMemoryCache(0, 0), time.Hour, logger.NewLogger()), handler)
+	return app
+}
+
+func post(t *testing.T, app *fiber.App, key, body string) (int, string, string) {
+	req := httptest.NewRequest("POST", "/statements", strings.NewReader(body))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, string(data), resp.Header.Get(IdempotentReplayedHeader)
+}
+
+func TestIdempotencyMiddlewareReplays(t *testing.T) {
+	calls := 0
+	app := newIdempotentApp(func(c *fiber.Ctx) error {
+		calls++
+		return c.Status(fiber.StatusAccepted).SendString("call " + strconv.Itoa(calls))
+	})
+
+	status, body, replayed := post(t, app, "key-1", `{"items":[]}`)
+	assert.Equal(t, fiber.StatusAccepted, status)
+	assert.Equal(t, "call 1", body)
+	assert.Empty(t, replayed)
+
+	status, body, replayed = post(t, app, "key-1", `{"items":[]}`)
+	assert.Equal(t, fiber.StatusAccepted, status)
+	assert.Equal(t, "call 1", body)
+	assert.Equal(t, "true", replayed)
+
+	status, _, _ = post(t, app, "key-1", `{"items":[{}]}`)
+	assert.Equal(t, fiber.StatusConflict, status)
+
+	_, body, _ = post(t, app, "key-2", `{"items":[]}`)
+	assert.Equal(t, "call 2", body)
+
+	_, body, _ = post(t, app, "", `{"items":[]}`)
+	assert.Equal(t, "call 3", body)
+	assert.Equal(t, 3, calls)
+}
+
+func TestIdempotencyMiddlewareStoresClientErrorsOnly(t *testing.T) {
+	calls := 0
+	app := newIdempotentApp(func(c *fiber.Ctx) error {
+		calls++
+		if calls == 1 {
+			return errors.New("boom")
+		}
+		return exception.BadRequestError{Message: "Invalid request format"}
+	})
+
+	status, _, _ := post(t, app, "key-1", `{}`)
+	assert.Equal(t, fiber.StatusInternalServerError, status)
+
+	// The server error was not stored, the retry runs the handler again
+	status, _, _ = post(t, app, "key-1", `{}`)
+	assert.Equal(t, fiber.StatusBadRequest, status)
+
+	status, body, replayed := post(t, app, "key-1", `{}`)
+	assert.Equal(t, fiber.StatusBadRequest, status)
+	assert.Contains(t, body, "Invalid request format")
+	assert.Equal(t, "true", replayed)
+	assert.Equal(t, 2, calls)
+}
+
+func TestIdempotencyMiddlewareRejectsLongKeys(t *testing.T) {
+	app := newIdempotentApp(func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusAccepted) })
+
+	status, _, _ := post(t, app, strings.Repeat("k", maxIdempotencyKeyLength+1), `{}`)
+	assert.Equal(t, fiber.StatusBadRequest, status)
+}
+
+func TestIdempotencyMiddlewareRunsConcurrentRequestsOnce(t *testing.T) {
+	var calls atomic.Int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	app := newIdempotentApp(func(c *fiber.Ctx) error {
+		calls.Add(1)
+		close(started)
+		<-release
+		return c.SendStatus(fiber.StatusAccepted)
+	})
+
+	var wg sync.WaitGroup
+	var first int
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		first, _, _ = post(t, app, "key-1", `{}`)
+	}()
+	<-started
+
+	// The first request holds the key until it completes
+	status, _, _ := post(t, app, "key-1", `{}`)
+	assert.Equal(t, fiber.StatusConflict, status)
+
+	close(release)
+	wg.Wait()
+	assert.Equal(t, fiber.StatusAccepted, first)
+	assert.Equal(t, int32(1), calls.Load())
+}
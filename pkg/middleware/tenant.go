@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"usdw/pkg/xero"
+)
+
+const (
+	TenantIDHeader = "xero-tenant-id"
+	TenantIDParam  = "tenantId"
+)
+
+// TenantMiddleware selects the Xero tenant for the request from the
+// :tenantId path segment, falling back to the xero-tenant-id header
+func TenantMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		tenantID := c.Params(TenantIDParam)
+		if tenantID == "" {
+			tenantID = c.Get(TenantIDHeader)
+		}
+
+		if tenantID != "" {
+			c.SetUserContext(xero.WithTenantID(c.UserContext(), tenantID))
+		}
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,251 @@
+package money
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Scale is the number of fractional digits an Amount can hold
+const Scale = 4
+
+const (
+	unit         = 10000 // 10^Scale
+	maxIntDigits = 14    // keeps every amount and sum well inside int64
+)
+
+var ErrInvalidAmount = errors.New("invalid amount")
+
+// Amount is a fixed-point decimal with Scale fractional digits. It is parsed from
+// and marshalled to JSON numbers without going through float64, so no cents are lost.
+type Amount struct {
+	units int64
+}
+
+// currencyMinorUnits lists ISO 4217 currencies that do not use two decimal places
+var currencyMinorUnits = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0,
+	"PYG": 0, "RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	"CLF": 4, "UYW": 4,
+}
+
+// MinorUnits returns the number of decimal places used by an ISO 4217 currency
+func MinorUnits(currency string) int {
+	if digits, ok := currencyMinorUnits[strings.ToUpper(currency)]; ok {
+		return digits
+	}
+	return 2
+}
+
+// Parse reads a decimal string such as "-1234.56" exactly
+func Parse(value string) (Amount, error) {
+	s := strings.TrimSpace(value)
+	negative := false
+	switch {
+	case strings.HasPrefix(s, "-"):
+		negative = true
+		s = s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+
+	intPart, fracPart, _ := strings.Cut(s, ".")
+	fracPart = strings.TrimRight(fracPart, "0")
+	if intPart == "" && fracPart == "" || !isDigits(intPart) || !isDigits(fracPart) {
+		return Amount{}, fmt.Errorf("%w: %q", ErrInvalidAmount, value)
+	}
+	intPart = strings.TrimLeft(intPart, "0")
+	if len(intPart) > maxIntDigits {
+		return Amount{}, fmt.Errorf("%w: %q is too large", ErrInvalidAmount, value)
+	}
+	if len(fracPart) > Scale {
+		return Amount{}, fmt.Errorf("%w: %q has more than %d decimal places", ErrInvalidAmount, value, Scale)
+	}
+
+	digits := intPart + fracPart + strings.Repeat("0", Scale-len(fracPart))
+	units, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return Amount{}, fmt.Errorf("%w: %q", ErrInvalidAmount, value)
+	}
+	if negative {
+		units = -units
+	}
+	return Amount{units: units}, nil
+}
+
+// MustParse is like Parse but panics on error; meant for constants and tests
+func MustParse(value string) Amount {
+	amount, err := Parse(value)
+	if err != nil {
+		panic(err)
+	}
+	return amount
+}
+
+// FromMinor builds an amount from an integer count of the currency's minor units,
+// e.g. FromMinor(1050, "USD") is 10.50
+func FromMinor(minor int64, currency string) Amount {
+	units := minor
+	for i := MinorUnits(currency); i < Scale; i++ {
+		units *= 10
+	}
+	return Amount{units: units}
+}
+
+func (a Amount) Add(b Amount) Amount {
+	return Amount{units: a.units + b.units}
+}
+
+func (a Amount) Sub(b Amount) Amount {
+	return Amount{units: a.units - b.units}
+}
+
+func (a Amount) Neg() Amount {
+	return Amount{units: -a.units}
+}
+
+func (a Amount) Abs() Amount {
+	if a.units < 0 {
+		return a.Neg()
+	}
+	return a
+}
+
+// Cmp returns -1, 0 or +1 depending on whether a is less than, equal to or greater than b
+func (a Amount) Cmp(b Amount) int {
+	switch {
+	case a.units < b.units:
+		return -1
+	case a.units > b.units:
+		return 1
+	}
+	return 0
+}
+
+func (a Amount) Equal(b Amount) bool {
+	return a.units == b.units
+}
+
+func (a Amount) IsZero() bool {
+	return a.units == 0
+}
+
+func (a Amount) IsNegative() bool {
+	return a.units < 0
+}
+
+// FitsCurrency reports whether the amount has no more decimal places than the currency allows
+func (a Amount) FitsCurrency(currency string) bool {
+	return a.Decimals() <= MinorUnits(currency)
+}
+
+// Decimals returns the number of significant fractional digits
+func (a Amount) Decimals() int {
+	frac := a.Abs().units % unit
+	decimals := Scale
+	for decimals > 0 && frac%10 == 0 {
+		frac /= 10
+		decimals--
+	}
+	return decimals
+}
+
+// StringFixed formats the amount with exactly the given number of decimal places,
+// rounding half away from zero
+func (a Amount) StringFixed(decimals int) string {
+	if decimals > Scale {
+		decimals = Scale
+	}
+	if decimals < 0 {
+		decimals = 0
+	}
+
+	divisor := int64(1)
+	for i := decimals; i < Scale; i++ {
+		divisor *= 10
+	}
+	abs := a.Abs().units
+	rounded := (abs + divisor/2) / divisor
+
+	scaled := int64(1)
+	for i := 0; i < decimals; i++ {
+		scaled *= 10
+	}
+
+	sign := ""
+	if a.units < 0 && rounded != 0 {
+		sign = "-"
+	}
+	if decimals == 0 {
+		return fmt.Sprintf("%s%d", sign, rounded)
+	}
+	return fmt.Sprintf("%s%d.%0*d", sign, rounded/scaled, decimals, rounded%scaled)
+}
+
+// String formats the amount with as many decimal places as it needs
+func (a Amount) String() string {
+	return a.StringFixed(a.Decimals())
+}
+
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalJSON accepts both JSON numbers and quoted decimal strings
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	value := string(data)
+	if unquoted, err := strconv.Unquote(value); err == nil {
+		value = unquoted
+	}
+	if strings.ContainsAny(value, "eE") {
+		return fmt.Errorf("%w: exponent notation %q is not supported", ErrInvalidAmount, value)
+	}
+
+	amount, err := Parse(value)
+	if err != nil {
+		return err
+	}
+	*a = amount
+	return nil
+}
+
+// Value stores the amount as its exact decimal string
+func (a Amount) Value() (driver.Value, error) {
+	return a.String(), nil
+}
+
+func (a *Amount) Scan(src interface{}) error {
+	var err error
+	switch v := src.(type) {
+	case nil:
+		*a = Amount{}
+	case string:
+		*a, err = Parse(v)
+	case []byte:
+		*a, err = Parse(string(v))
+	case int64:
+		*a = Amount{units: v * unit}
+	case float64:
+		*a, err = Parse(strconv.FormatFloat(v, 'f', -1, 64))
+	default:
+		err = fmt.Errorf("%w: cannot scan %T", ErrInvalidAmount, src)
+	}
+	return err
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,80 @@
+package money
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"100.50", "100.5"},
+		{"-0.01", "-0.01"},
+		{"+12", "12"},
+		{".5", "0.5"},
+		{"007.1000", "7.1"},
+		{"99999999999999.9999", "99999999999999.9999"},
+	}
+	for _, tt := range tests {
+		amount, err := Parse(tt.input)
+		assert.NoError(t, err, tt.input)
+		assert.Equal(t, tt.expected, amount.String(), tt.input)
+	}
+
+	for _, input := range []string{"", "-", "1.2.3", "abc", "1.23456", "100000000000000"} {
+		_, err := Parse(input)
+		assert.ErrorIs(t, err, ErrInvalidAmount, input)
+	}
+}
+
+func TestSumIsExact(t *testing.T) {
+	// 0.1 summed a thousand times drifts with float64
+	total := Amount{}
+	for i := 0; i < 1000; i++ {
+		total = total.Add(MustParse("0.1"))
+	}
+	assert.True(t, total.Equal(MustParse("100")))
+}
+
+func TestCurrency(t *testing.T) {
+	assert.Equal(t, 2, MinorUnits("USD"))
+	assert.Equal(t, 0, MinorUnits("jpy"))
+	assert.Equal(t, 3, MinorUnits("KWD"))
+
+	assert.True(t, FromMinor(1050, "USD").Equal(MustParse("10.50")))
+	assert.True(t, FromMinor(1050, "JPY").Equal(MustParse("1050")))
+
+	assert.True(t, MustParse("10.5").FitsCurrency("USD"))
+	assert.False(t, MustParse("10.505").FitsCurrency("USD"))
+	assert.True(t, MustParse("10.505").FitsCurrency("KWD"))
+	assert.False(t, MustParse("10.5").FitsCurrency("JPY"))
+}
+
+func TestStringFixed(t *testing.T) {
+	assert.Equal(t, "10.50", MustParse("10.5").StringFixed(2))
+	assert.Equal(t, "10.51", MustParse("10.505").StringFixed(2))
+	assert.Equal(t, "-10.51", MustParse("-10.505").StringFixed(2))
+	assert.Equal(t, "0.00", MustParse("-0.001").StringFixed(2))
+	assert.Equal(t, "11", MustParse("10.5").StringFixed(0))
+}
+
+func TestJSON(t *testing.T) {
+	var payload struct {
+		Amount Amount `json:"amount"`
+		Quoted Amount `json:"quoted"`
+	}
+	err := json.Unmarshal([]byte(`{"amount": 1234.56, "quoted": "0.10"}`), &payload)
+	assert.NoError(t, err)
+	assert.Equal(t, "1234.56", payload.Amount.String())
+	assert.Equal(t, "0.1", payload.Quoted.String())
+
+	out, err := json.Marshal(payload)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"amount": 1234.56, "quoted": 0.1}`, string(out))
+
+	assert.Error(t, json.Unmarshal([]byte(`{"amount": 1e3}`), &payload))
+}
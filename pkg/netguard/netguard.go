@@ -0,0 +1,67 @@
+// Package netguard keeps requests to URLs supplied by API callers, like webhook
+// subscriptions, away from the private network the service runs in.
+package netguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+var ErrForbiddenAddress = errors.New("address is not a public unicast address")
+
+// sharedAddressSpace is the carrier-grade NAT range, used by some clouds for metadata services
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// Allowed reports whether ip is a public unicast address, i.e. not private, loopback,
+// link-local (like the 169.254.169.254 metadata endpoint), multicast or unspecified
+func Allowed(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !sharedAddressSpace.Contains(ip)
+}
+
+// CheckHost resolves host and fails unless every address it resolves to is allowed
+func CheckHost(ctx context.Context, host string) error {
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", host, err)
+	}
+	for _, addr := range addrs {
+		if !Allowed(addr) {
+			return fmt.Errorf("%w: %s resolves to %s", ErrForbiddenAddress, host, addr)
+		}
+	}
+	return nil
+}
+
+// NewTransport returns an HTTP transport that refuses to connect to addresses that are
+// not allowed. The check runs on the resolved address when dialling, so neither a DNS
+// change after CheckHost nor a redirect can reach an internal address.
+func NewTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   control,
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would connect on our behalf, past the check
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return transport
+}
+
+func control(_, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !Allowed(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, addrPort.Addr())
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package netguard
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllowed(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:4700::1111", true},
+		{"127.0.0.1", false},
+		{"10.0.0.1", false},
+		{"172.16.5.4", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.100.100.200", false},
+		{"0.0.0.0", false},
+		{"224.0.0.1", false},
+		{"::1", false},
+		{"fd00::1", false},
+		{"fe80::1", false},
+		{"::ffff:127.0.0.1", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Allowed(netip.MustParseAddr(tt.ip)), tt.ip)
+	}
+}
+
+func TestCheckHost(t *testing.T) {
+	ctx := context.Background()
+	assert.NoError(t, CheckHost(ctx, "93.184.216.34"))
+	assert.ErrorIs(t, CheckHost(ctx, "169.254.169.254"), ErrForbiddenAddress)
+	assert.ErrorIs(t, CheckHost(ctx, "localhost"), ErrForbiddenAddress)
+}
+
+func TestTransportRefusesInternalAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	client := &http.Client{Transport: NewTransport()}
+	_, err := client.Get(server.URL)
+	assert.ErrorIs(t, err, ErrForbiddenAddress)
+}
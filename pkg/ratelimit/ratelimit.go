@@ -0,0 +1,166 @@
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"usdw/pkg/cache"
+	"usdw/pkg/logger"
+)
+
+// Metrics published on /debug/vars, keyed by limiter name. Delays per key are only
+// logged, so the maps stay bounded however many tenants connect.
+var (
+	waitCount   = expvar.NewMap("ratelimit_waits")
+	waitSeconds = expvar.NewMap("ratelimit_wait_seconds")
+)
+
+// takeScript refills the bucket from the elapsed Redis time and takes a token.
+// It returns 0 when a token was taken, otherwise the milliseconds to wait.
+const takeScript = `
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or burst
+local ts = tonumber(state[2]) or now
+tokens = math.min(burst, tokens + (now - ts) * rate / 1000)
+local wait = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+else
+	wait = math.ceil((1 - tokens) * 1000 / rate)
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst * 1000 / rate) + 1000)
+return wait
+`
+
+// Limiter paces calls per key
+type Limiter interface {
+	// Wait blocks until a call for key is allowed or ctx is done and reports how long it waited
+	Wait(ctx context.Context, key string) (time.Duration, error)
+}
+
+type bucketState struct {
+	Tokens float64 `json:"tokens"`
+	TS     int64   `json:"ts"`
+}
+
+type tokenBucket struct {
+	name   string
+	engine cache.Engine
+	rate   float64 // tokens per second
+	burst  int
+	logger logger.Logger
+	mutex  sync.Mutex
+}
+
+// unlimited is the Limiter of a disabled rate limit
+type unlimited struct{}
+
+func (unlimited) Wait(context.Context, string) (time.Duration, error) {
+	return 0, nil
+}
+
+// NewTokenBucket returns a token bucket limiter refilling ratePerMinute tokens up to
+// burst. Bucket state lives in the cache engine: with Redis it is updated atomically
+// by a Lua script so the limit holds across replicas; otherwise it is per process.
+// A rate of zero or less disables the limiter, and a burst below one is raised to one.
+func NewTokenBucket(name string, engine cache.Engine, ratePerMinute, burst int, logger logger.Logger) Limiter {
+	if ratePerMinute <= 0 {
+		return unlimited{}
+	}
+	burst = max(burst, 1)
+
+	return &tokenBucket{
+		name:   name,
+		engine: engine,
+		rate:   float64(ratePerMinute) / 60,
+		burst:  burst,
+		logger: logger,
+	}
+}
+
+func (b *tokenBucket) Wait(ctx context.Context, key string) (time.Duration, error) {
+	start := time.Now()
+	for {
+		wait, err := b.take(ctx, key)
+		if err != nil {
+			return time.Since(start), err
+		}
+		if wait == 0 {
+			break
+		}
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return time.Since(start), ctx.Err()
+		}
+	}
+
+	waited := time.Since(start)
+	if waited > time.Millisecond {
+		waitCount.Add(b.name, 1)
+		waitSeconds.AddFloat(b.name, waited.Seconds())
+		b.logger.Infof("Rate limiter %s delayed call for %s by %s", b.name, key, waited)
+	}
+	return waited, nil
+}
+
+func (b *tokenBucket) take(ctx context.Context, key string) (time.Duration, error) {
+	cacheKey := fmt.Sprintf("ratelimit:%s:%s", b.name, key)
+
+	if scripter, ok := b.engine.(cache.Scripter); ok {
+		result, err := scripter.Eval(ctx, takeScript, []string{cacheKey}, b.rate, b.burst)
+		if err != nil {
+			return 0, fmt.Errorf("failed to run rate limit script: %w", err)
+		}
+		millis, _ := result.(int64)
+		return time.Duration(millis) * time.Millisecond, nil
+	}
+
+	return b.takeLocal(ctx, cacheKey)
+}
+
+// takeLocal runs the same algorithm with plain Get/Set under a process lock
+func (b *tokenBucket) takeLocal(ctx context.Context, cacheKey string) (time.Duration, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	now := time.Now().UnixMilli()
+	state := bucketState{Tokens: float64(b.burst), TS: now}
+
+	val, err := b.engine.Get(ctx, cacheKey)
+	if err == nil {
+		err = json.Unmarshal(val, &state)
+	}
+	if err != nil && !errors.Is(err, cache.ErrNotFound) {
+		return 0, fmt.Errorf("failed to load rate limit state: %w", err)
+	}
+
+	state.Tokens = math.Min(float64(b.burst), state.Tokens+float64(now-state.TS)*b.rate/1000)
+	state.TS = now
+
+	var wait time.Duration
+	if state.Tokens >= 1 {
+		state.Tokens--
+	} else {
+		wait = time.Duration(math.Ceil((1-state.Tokens)*1000/b.rate)) * time.Millisecond
+	}
+
+	val, err = json.Marshal(state)
+	if err != nil {
+		return 0, err
+	}
+	ttl := time.Duration(float64(b.burst)/b.rate*float64(time.Second)) + time.Second
+	return wait, b.engine.Set(ctx, cacheKey, val, ttl)
+}
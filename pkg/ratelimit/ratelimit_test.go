@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"usdw/config"
+	"usdw/pkg/cache"
+	"usdw/pkg/cache/inmem"
+	"usdw/pkg/cache/redis"
+	"usdw/pkg/logger"
+)
+
+func TestTokenBucket(t *testing.T) {
+	server := miniredis.RunT(t)
+	redisEngine, err := redis.NewStandaloneConn(&config.Configuration{Redis: config.RedisConfig{Address: server.Addr()}})
+	assert.NoError(t, err)
+
+	engines := map[string]cache.Engine{
+		"inmem": inmem.NewInMemoryCache(0, 0),
+		"redis": redisEngine,
+	}
+
+	for name, engine := range engines {
+		t.Run(name, func(t *testing.T) {
+			// 600 per minute refills one token every 100ms
+			limiter := NewTokenBucket("test", engine, 600, 2, logger.NewLogger())
+			ctx := context.Background()
+
+			// The burst is served immediately
+			for i := 0; i < 2; i++ {
+				waited, err := limiter.Wait(ctx, "tenant")
+				assert.NoError(t, err)
+				assert.Less(t, waited, 50*time.Millisecond)
+			}
+
+			// Other keys have their own bucket
+			waited, err := limiter.Wait(ctx, "other")
+			assert.NoError(t, err)
+			assert.Less(t, waited, 50*time.Millisecond)
+
+			// The next call waits for a refill
+			waited, err = limiter.Wait(ctx, "tenant")
+			assert.NoError(t, err)
+			assert.GreaterOrEqual(t, waited, 50*time.Millisecond)
+
+			// Metrics are kept per limiter, not per key
+			assert.NotNil(t, waitCount.Get("test"))
+			assert.Nil(t, waitCount.Get("test:tenant"))
+
+			// A cancelled context stops waiting
+			cancelled, cancel := context.WithCancel(ctx)
+			cancel()
+			_, err = limiter.Wait(cancelled, "tenant")
+			assert.ErrorIs(t, err, context.Canceled)
+		})
+	}
+}
+
+func TestTokenBucketDisabled(t *testing.T) {
+	limiter := NewTokenBucket("disabled", inmem.NewInMemoryCache(0, 0), 0, 0, logger.NewLogger())
+	for i := 0; i < 100; i++ {
+		waited, err := limiter.Wait(context.Background(), "tenant")
+		assert.NoError(t, err)
+		assert.Zero(t, waited)
+	}
+}
@@ -2,10 +2,10 @@ package server
 
 import (
 	"encoding/json"
-	"github.com/go-resty/resty/v2"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/etag"
+	"github.com/gofiber/fiber/v2/middleware/expvar"
 	fiberLog "github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
 	"github.com/gofiber/swagger"
@@ -17,18 +17,21 @@ import (
 
 	_ "usdw/docs"
 	apiv1 "usdw/internal/app"
+	"usdw/internal/domain/entity"
 	cachePkg "usdw/pkg/cache"
 	"usdw/pkg/common/exception"
 	dbPkg "usdw/pkg/db"
 	loggerPkg "usdw/pkg/logger"
+	"usdw/pkg/xero"
 )
 
 type Server struct {
-	app    *fiber.App
-	conf   *config.Configuration
-	logger loggerPkg.Logger
-	cache  cachePkg.Engine
-	db     *dbPkg.DB
+	app     *fiber.App
+	metrics *fiber.App
+	conf    *config.Configuration
+	logger  loggerPkg.Logger
+	cache   cachePkg.Engine
+	db      *dbPkg.DB
 }
 
 func New() (*Server, error) {
@@ -41,20 +44,36 @@ func New() (*Server, error) {
 	if err != nil {
 		return nil, err
 	}
-	db, err := dbPkg.NewDB(conf)
+	db, err := dbPkg.NewDB(conf, logger)
+	if err != nil {
+		return nil, err
+	}
+	err = db.AutoMigrate(
+		&entity.FeedConnectionRecord{},
+		&entity.StatementRecord{},
+		&entity.WebhookSubscriptionRecord{},
+		&entity.WebhookDeliveryRecord{},
+		&entity.ImportProfileRecord{},
+		&entity.PostedTransactionRecord{},
+	)
 	if err != nil {
 		return nil, err
 	}
-	// db := &db.DB{}
 
 	app := NewFiberApp(conf, logger, cacheEngine, db)
 
+	var metrics *fiber.App
+	if conf.Server.MetricsAddress != "" {
+		metrics = NewMetricsApp()
+	}
+
 	return &Server{
-		conf:   conf,
-		logger: logger,
-		cache:  cacheEngine,
-		db:     db,
-		app:    app,
+		conf:    conf,
+		logger:  logger,
+		cache:   cacheEngine,
+		db:      db,
+		app:     app,
+		metrics: metrics,
 	}, nil
 }
 
@@ -77,6 +96,7 @@ func NewFiberApp(
 	app.Use(etag.New())
 	app.Use(recover.New())
 	app.Use(middleware.RequestIDMiddleware())
+	app.Use(middleware.TenantMiddleware())
 
 	app.Use(fiberLog.New(fiberLog.Config{
 		Next:         nil,
@@ -92,8 +112,12 @@ func NewFiberApp(
 
 	api := app.Group("/api")
 	v1 := api.Group("/v1")
-	client := resty.New()
-	apiv1.NewApplication(v1, logger, client, db, cacheEngine, conf)
+	client := xero.NewRetryClient(conf.Xero, logger)
+	stopWorkers := apiv1.NewApplication(v1, logger, client, db, cacheEngine, conf)
+	app.Hooks().OnShutdown(func() error {
+		stopWorkers()
+		return nil
+	})
 
 	app.Get("/healthz", func(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusOK).JSON(fiber.Map{
@@ -109,10 +133,24 @@ func NewFiberApp(
 	return app
 }
 
+// NewMetricsApp exposes runtime and rate limiter metrics on /debug/vars. They include
+// tenant IDs and the process command line, so it is served on the internal
+// SERVER_METRICS_ADDRESS rather than the public API port.
+func NewMetricsApp() *fiber.App {
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Use(expvar.New())
+	return app
+}
+
 func (serv Server) App() *fiber.App {
 	return serv.app
 }
 
+// Metrics is the internal metrics app, nil when SERVER_METRICS_ADDRESS is empty
+func (serv Server) Metrics() *fiber.App {
+	return serv.metrics
+}
+
 func (serv Server) Config() *config.Configuration {
 	return serv.conf
 }
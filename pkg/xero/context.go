@@ -0,0 +1,16 @@
+package xero
+
+import "context"
+
+type tenantIDKey struct{}
+
+// WithTenantID returns a copy of ctx carrying the requested Xero tenant ID
+func WithTenantID(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantIDKey{}, tenantID)
+}
+
+// TenantIDFromContext returns the requested Xero tenant ID, or "" if none was set
+func TenantIDFromContext(ctx context.Context) string {
+	tenantID, _ := ctx.Value(tenantIDKey{}).(string)
+	return tenantID
+}
@@ -0,0 +1,129 @@
+package xero
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// Sentinels matched by APIError.Is according to the Xero status code
+var (
+	ErrBadRequest   = errors.New("xero: bad request")
+	ErrUnauthorized = errors.New("xero: unauthorized")
+	ErrForbidden    = errors.New("xero: forbidden")
+	ErrNotFound     = errors.New("xero: not found")
+	ErrConflict     = errors.New("xero: conflict")
+	ErrRateLimited  = errors.New("xero: rate limited")
+	ErrUnavailable  = errors.New("xero: unavailable")
+)
+
+// Problem is a single problem object as returned by the Xero Bank Feeds API,
+// the same shape as entity.FeedError
+type Problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status,omitempty"`
+	Detail string `json:"detail"`
+}
+
+// APIError is a non-2xx response from the Xero API
+type APIError struct {
+	StatusCode int
+	Problem
+	// ValidationErrors holds per-field or per-item problems reported alongside the main one
+	ValidationErrors []Problem
+	// RetryAfter is the wait requested by Xero on 429 and 503 responses
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	if e.Detail != "" {
+		return fmt.Sprintf("Xero API error [%d]: %s", e.StatusCode, e.Detail)
+	}
+	return fmt.Sprintf("Xero API error [%d]: %s", e.StatusCode, e.Title)
+}
+
+// Is lets callers classify errors with errors.Is(err, xero.ErrNotFound)
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest || e.StatusCode == http.StatusUnprocessableEntity
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrUnavailable:
+		return e.StatusCode >= http.StatusInternalServerError
+	}
+	return false
+}
+
+// NewAPIError builds an APIError from a failed response. defaultDetail is used
+// when Xero does not describe the problem, e.g. on an empty 404.
+func NewAPIError(resp *resty.Response, defaultDetail string) *APIError {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode(),
+		RetryAfter: parseRetryAfter(resp.Header().Get("Retry-After")),
+	}
+
+	var body struct {
+		Problem
+		Errors []Problem `json:"errors"`
+		// Accounting style validation errors
+		Elements []struct {
+			ValidationErrors []struct {
+				Message string `json:"Message"`
+			} `json:"ValidationErrors"`
+		} `json:"Elements"`
+	}
+	if err := json.Unmarshal(resp.Body(), &body); err == nil {
+		apiErr.Problem = body.Problem
+		apiErr.ValidationErrors = body.Errors
+		for _, element := range body.Elements {
+			for _, validation := range element.ValidationErrors {
+				apiErr.ValidationErrors = append(apiErr.ValidationErrors, Problem{
+					Type:   "validation-error",
+					Title:  "Validation Error",
+					Detail: validation.Message,
+				})
+			}
+		}
+	} else if len(resp.Body()) > 0 {
+		apiErr.Detail = resp.String()
+	}
+
+	if apiErr.Title == "" {
+		apiErr.Title = http.StatusText(apiErr.StatusCode)
+	}
+	if apiErr.Detail == "" {
+		apiErr.Detail = defaultDetail
+	}
+	apiErr.Status = apiErr.StatusCode
+
+	return apiErr
+}
+
+// parseRetryAfter accepts both delay-seconds and HTTP-date values
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date)
+	}
+	return 0
+}
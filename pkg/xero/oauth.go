@@ -3,7 +3,9 @@ package xero
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -12,88 +14,211 @@ import (
 	"usdw/config"
 )
 
-// TokenManager struct for caching token
+// Tokens are refreshed when they expire within this window
+const expiryLeeway = 5 * time.Minute
+
+var ErrReauthorizationRequired = errors.New("xero authorization was revoked or expired, reconnect the tenant")
+
+// TokenManager hands out access tokens per tenant. Tokens live in a TokenStore
+// so they are shared by every replica and survive restarts.
 type TokenManager struct {
-	Token       *oauth2.Token
-	LastRefresh time.Time
-	Mutex       sync.Mutex
-	Client      *resty.Client
+	store            TokenStore
+	client           *resty.Client
+	customConnection bool
+	mutex            sync.Mutex
+	locks            map[string]*sync.Mutex
 }
 
-// Global TokenManager instance
-var tokenManager *TokenManager
+// NewTokenManager only requests tokens with the client_credentials grant when the app
+// is a custom connection; otherwise a tenant without a stored token has to reconnect
+func NewTokenManager(store TokenStore, customConnection bool) *TokenManager {
+	return &TokenManager{
+		store:            store,
+		client:           resty.New(),
+		customConnection: customConnection,
+		locks:            make(map[string]*sync.Mutex),
+	}
+}
 
-// GetAccessToken retrieves a valid token, refreshing if needed
-func GetAccessToken(ctx context.Context) (string, error) {
-	// Initialize token manager if needed
-	if tokenManager == nil {
-		tokenManager = &TokenManager{
-			Client: resty.New(),
-		}
+// lockFor serialises refreshes of the same grant within this process
+func (m *TokenManager) lockFor(key string) *sync.Mutex {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	lock, ok := m.locks[key]
+	if !ok {
+		lock = &sync.Mutex{}
+		m.locks[key] = lock
+	}
+	return lock
+}
+
+// GetAccessToken retrieves a valid token for the tenant, refreshing if needed.
+// An empty tenantID returns the app-level token used to list connections.
+func (m *TokenManager) GetAccessToken(ctx context.Context, tenantID string) (string, error) {
+	token, err := m.load(ctx, tenantID)
+	if err != nil {
+		return "", err
+	}
+	if token != nil && !token.ExpiresWithin(expiryLeeway) {
+		return token.AccessToken, nil
 	}
 
-	tokenManager.Mutex.Lock()
-	defer tokenManager.Mutex.Unlock()
+	// Tenants authorised by the same consent share one rotating refresh token
+	lock := m.lockFor(grantKey(tenantID, token))
+	lock.Lock()
+	defer lock.Unlock()
 
-	// Refresh if token is missing or expired
-	if tokenManager.Token == nil || tokenManager.Token.Expiry.Before(time.Now().Add(-5*time.Minute)) {
-		//logger.Info("Fetching new Xero Access Token...")
-		newToken, err := refreshAccessToken(ctx)
+	// Another request may have refreshed the token while we waited
+	token, err = m.load(ctx, tenantID)
+	if err != nil {
+		return "", err
+	}
+	if token != nil && !token.ExpiresWithin(expiryLeeway) {
+		return token.AccessToken, nil
+	}
+
+	switch {
+	case token != nil && token.RefreshToken != "":
+		token, err = m.refreshGrant(ctx, token)
+	case m.customConnection:
+		token, err = m.clientCredentialsGrant(ctx, tenantID)
+	default:
+		// Never mint a token for a tenant ID nobody authorised
+		return "", ErrReauthorizationRequired
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return token.AccessToken, nil
+}
+
+// SaveToken persists a token obtained from the authorization code flow for every
+// tenant the consent was granted for
+func (m *TokenManager) SaveToken(ctx context.Context, tenantIDs []string, token *oauth2.Token) error {
+	stored := NewStoredToken(token)
+	stored.TenantIDs = tenantIDs
+	return m.saveAll(ctx, stored)
+}
+
+// DeleteToken forgets the token of a tenant, e.g. after it disconnected the app
+func (m *TokenManager) DeleteToken(ctx context.Context, tenantID string) error {
+	return m.store.Delete(ctx, tenantID)
+}
+
+func (m *TokenManager) load(ctx context.Context, tenantID string) (*StoredToken, error) {
+	token, err := m.store.Load(ctx, tenantID)
+	if errors.Is(err, ErrTokenNotFound) {
+		return nil, nil
+	}
+	return token, err
+}
+
+func (m *TokenManager) saveAll(ctx context.Context, token *StoredToken) error {
+	for _, tenantID := range token.TenantIDs {
+		err := m.store.Save(ctx, tenantID, token)
 		if err != nil {
-			//logger.Error("Failed to get Xero access token:", err)
-			return "", err
+			return err
 		}
-		tokenManager.Token = newToken
+	}
+	return nil
+}
+
+// refreshGrant exchanges the refresh token and stores the rotated token for
+// every tenant of the grant
+func (m *TokenManager) refreshGrant(ctx context.Context, current *StoredToken) (*StoredToken, error) {
+	token, err := m.requestToken(ctx, map[string]string{
+		"grant_type":    "refresh_token",
+		"refresh_token": current.RefreshToken,
+	})
+	if errors.Is(err, ErrReauthorizationRequired) {
+		for _, tenantID := range current.TenantIDs {
+			_ = m.store.Delete(ctx, tenantID)
+		}
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	return tokenManager.Token.AccessToken, nil
+	token.TenantIDs = current.TenantIDs
+	if token.RefreshToken == "" {
+		token.RefreshToken = current.RefreshToken
+	}
+
+	err = m.saveAll(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
 }
 
-// refreshAccessToken requests a new token from Xero
-func refreshAccessToken(ctx context.Context) (*oauth2.Token, error) {
-	client := resty.New()
+// clientCredentialsGrant requests a token for a custom connection
+func (m *TokenManager) clientCredentialsGrant(ctx context.Context, tenantID string) (*StoredToken, error) {
+	token, err := m.requestToken(ctx, map[string]string{
+		"grant_type": "client_credentials",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	token.TenantIDs = []string{tenantID}
+	err = m.store.Save(ctx, tenantID, token)
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
 
-	resp, err := client.R().
+// requestToken requests a new token from Xero
+func (m *TokenManager) requestToken(ctx context.Context, form map[string]string) (*StoredToken, error) {
+	resp, err := m.client.R().
 		SetContext(ctx).
-		SetFormData(map[string]string{
-			"grant_type":    "client_credentials",
-			"client_id":     config.XeroOAuthConfig.ClientID,
-			"client_secret": config.XeroOAuthConfig.ClientSecret,
-			//"scope":         "offline_access",
-		}).
+		SetBasicAuth(config.XeroOAuthConfig.ClientID, config.XeroOAuthConfig.ClientSecret).
+		SetFormData(form).
 		Post(config.XeroOAuthConfig.Endpoint.TokenURL)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to refresh access token: %w", err)
 	}
 
-	// Debugging: Print the raw response from Xero
-	fmt.Println("Xero Token Response:", string(resp.Body()))
-
 	// Create a struct to match the response
 	var rawToken struct {
-		AccessToken string `json:"access_token"`
-		ExpiresIn   int    `json:"expires_in"`
-		TokenType   string `json:"token_type"`
-		Scope       string `json:"scope"`
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int    `json:"expires_in"`
+		TokenType    string `json:"token_type"`
+		Scope        string `json:"scope"`
+		Error        string `json:"error"`
 	}
 
-	// Parse the response correctly
 	err = json.Unmarshal(resp.Body(), &rawToken)
-	if err != nil {
+	if err != nil && resp.StatusCode() < 400 {
 		return nil, fmt.Errorf("failed to parse token response: %w", err)
 	}
 
-	// ✅ Correctly set expiry time based on `expires_in`
-	token := &oauth2.Token{
-		AccessToken: rawToken.AccessToken,
-		TokenType:   rawToken.TokenType,
-		Expiry:      time.Now().Add(time.Duration(rawToken.ExpiresIn) * time.Second),
+	if rawToken.Error == "invalid_grant" {
+		return nil, ErrReauthorizationRequired
 	}
 
-	// Debugging: Print new expiry time and scope
-	fmt.Println("New Access Token Expiry:", token.Expiry)
-	fmt.Println("New Access Token Scope:", rawToken.Scope)
+	if resp.StatusCode() >= 400 {
+		return nil, fmt.Errorf("Xero token error [%d]: %s", resp.StatusCode(), resp.String())
+	}
 
-	return token, nil
+	// Set expiry time based on `expires_in`
+	return &StoredToken{
+		AccessToken:  rawToken.AccessToken,
+		RefreshToken: rawToken.RefreshToken,
+		TokenType:    rawToken.TokenType,
+		Expiry:       time.Now().Add(time.Duration(rawToken.ExpiresIn) * time.Second),
+		Scopes:       strings.Fields(rawToken.Scope),
+	}, nil
+}
+
+func grantKey(tenantID string, token *StoredToken) string {
+	if token != nil && len(token.TenantIDs) > 0 {
+		return token.TenantIDs[0]
+	}
+	return tenantID
 }
@@ -0,0 +1,52 @@
+package xero
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+	"usdw/config"
+	"usdw/pkg/cache/inmem"
+)
+
+func TestGetAccessTokenForUnknownTenant(t *testing.T) {
+	config.XeroOAuthConfig = &oauth2.Config{Endpoint: oauth2.Endpoint{TokenURL: "https://identity.xero.com/connect/token"}}
+	var grants []string
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		require.NoError(t, req.ParseForm())
+		grants = append(grants, req.PostForm.Get("grant_type"))
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"access_token":"app-token","expires_in":1800}`)),
+			Request:    req,
+		}, nil
+	})
+	ctx := context.Background()
+
+	// An app using the OAuth flow never mints a token for a tenant it does not know
+	store := NewCacheTokenStore(inmem.NewInMemoryCache(0, 0))
+	manager := NewTokenManager(store, false)
+	manager.client = resty.New().SetTransport(transport)
+
+	_, err := manager.GetAccessToken(ctx, "unknown")
+	assert.ErrorIs(t, err, ErrReauthorizationRequired)
+	assert.Empty(t, grants)
+	_, err = store.Load(ctx, "unknown")
+	assert.ErrorIs(t, err, ErrTokenNotFound)
+
+	// A custom connection fetches its own token
+	manager = NewTokenManager(store, true)
+	manager.client = resty.New().SetTransport(transport)
+
+	token, err := manager.GetAccessToken(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, "app-token", token)
+	assert.Equal(t, []string{"client_credentials"}, grants)
+}
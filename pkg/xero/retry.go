@@ -0,0 +1,98 @@
+package xero
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"usdw/config"
+	"usdw/pkg/logger"
+)
+
+const (
+	IdempotencyKeyHeader    = "Idempotency-Key"
+	minLimitRemainingHeader = "X-MinLimit-Remaining"
+	dayLimitRemainingHeader = "X-DayLimit-Remaining"
+)
+
+// retryPolicy decides which Xero calls are retried and how long to wait
+type retryPolicy struct {
+	maxWait time.Duration
+	logger  logger.Logger
+}
+
+// NewRetryClient returns a resty client that retries rate limited and transient
+// failures with exponential backoff and jitter. Only idempotent requests, or
+// requests carrying an Idempotency-Key header, are retried.
+func NewRetryClient(cfg config.XeroConfig, logger logger.Logger) *resty.Client {
+	policy := &retryPolicy{
+		maxWait: cfg.RetryMaxWaitTime,
+		logger:  logger,
+	}
+
+	return resty.New().
+		SetRetryCount(cfg.RetryCount).
+		SetRetryWaitTime(cfg.RetryWaitTime).
+		SetRetryMaxWaitTime(cfg.RetryMaxWaitTime).
+		SetRetryAfter(policy.retryAfter).
+		AddRetryCondition(policy.shouldRetry).
+		AddRetryHook(policy.logRetry)
+}
+
+func (p *retryPolicy) shouldRetry(resp *resty.Response, err error) bool {
+	if resp == nil || resp.Request == nil || !isIdempotent(resp.Request) {
+		return false
+	}
+
+	// Transport errors such as timeouts and connection resets
+	if err != nil {
+		return true
+	}
+
+	switch resp.StatusCode() {
+	case http.StatusTooManyRequests:
+		// Waiting for the daily limit to reset is not worth holding the request
+		if resp.Header().Get(dayLimitRemainingHeader) == "0" {
+			return false
+		}
+		return parseRetryAfter(resp.Header().Get("Retry-After")) <= p.maxWait
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryAfter honours Retry-After; returning 0 falls back to jittered backoff
+func (p *retryPolicy) retryAfter(_ *resty.Client, resp *resty.Response) (time.Duration, error) {
+	if wait := parseRetryAfter(resp.Header().Get("Retry-After")); wait > 0 {
+		return wait, nil
+	}
+
+	// The per-minute limit is spent but Xero did not say when to come back
+	if resp.StatusCode() == http.StatusTooManyRequests && resp.Header().Get(minLimitRemainingHeader) == "0" {
+		return time.Minute, nil
+	}
+
+	return 0, nil
+}
+
+func (p *retryPolicy) logRetry(resp *resty.Response, err error) {
+	if resp == nil || resp.Request == nil {
+		return
+	}
+	if err != nil {
+		p.logger.Warnf("Retrying Xero %s %s after error (attempt %d): %s", resp.Request.Method, resp.Request.URL, resp.Request.Attempt, err)
+		return
+	}
+	p.logger.Warnf("Retrying Xero %s %s after status %d (attempt %d, minute remaining %q, day remaining %q)",
+		resp.Request.Method, resp.Request.URL, resp.StatusCode(), resp.Request.Attempt,
+		resp.Header().Get(minLimitRemainingHeader), resp.Header().Get(dayLimitRemainingHeader))
+}
+
+func isIdempotent(req *resty.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get(IdempotencyKeyHeader) != ""
+}
@@ -0,0 +1,73 @@
+package xero
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"usdw/config"
+	"usdw/pkg/logger"
+)
+
+func TestRetryClient(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewRetryClient(config.XeroConfig{
+		RetryCount:       2,
+		RetryWaitTime:    time.Millisecond,
+		RetryMaxWaitTime: 10 * time.Millisecond,
+	}, logger.NewLogger())
+
+	// GET is idempotent and retried
+	resp, err := client.R().Get(server.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+	assert.Equal(t, int32(2), calls)
+
+	// POST without an idempotency key is not retried
+	atomic.StoreInt32(&calls, 0)
+	resp, err = client.R().Post(server.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode())
+	assert.Equal(t, int32(1), calls)
+
+	// POST with an idempotency key is retried
+	atomic.StoreInt32(&calls, 0)
+	resp, err = client.R().SetHeader(IdempotencyKeyHeader, "key").Post(server.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+	assert.Equal(t, int32(2), calls)
+}
+
+func TestRetryClientStopsOnDailyLimit(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set(dayLimitRemainingHeader, "0")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client := NewRetryClient(config.XeroConfig{
+		RetryCount:       2,
+		RetryWaitTime:    time.Millisecond,
+		RetryMaxWaitTime: 10 * time.Millisecond,
+	}, logger.NewLogger())
+
+	resp, err := client.R().Get(server.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode())
+	assert.Equal(t, int32(1), calls)
+}
@@ -0,0 +1,71 @@
+package xero
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrInvalidState = errors.New("invalid oauth state")
+
+type statePayload struct {
+	Nonce     string `json:"n"`
+	ExpiresAt int64  `json:"e"`
+}
+
+// NewState returns a signed OAuth state parameter valid for ttl, together with the
+// random nonce it carries so the caller can bind it to the user's browser
+func NewState(secret []byte, ttl time.Duration) (state string, nonce string, err error) {
+	raw := make([]byte, 16)
+	if _, err = rand.Read(raw); err != nil {
+		return "", "", fmt.Errorf("failed to generate state nonce: %w", err)
+	}
+	nonce = base64.RawURLEncoding.EncodeToString(raw)
+
+	payload, err := json.Marshal(statePayload{
+		Nonce:     nonce,
+		ExpiresAt: time.Now().Add(ttl).Unix(),
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + sign(secret, encoded), nonce, nil
+}
+
+// VerifyState checks the signature and expiry of state and returns its nonce
+func VerifyState(secret []byte, state string) (string, error) {
+	encoded, signature, ok := strings.Cut(state, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(sign(secret, encoded))) {
+		return "", ErrInvalidState
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", ErrInvalidState
+	}
+
+	var payload statePayload
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return "", ErrInvalidState
+	}
+
+	if time.Now().Unix() > payload.ExpiresAt {
+		return "", fmt.Errorf("%w: expired", ErrInvalidState)
+	}
+
+	return payload.Nonce, nil
+}
+
+func sign(secret []byte, value string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
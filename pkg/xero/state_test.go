@@ -0,0 +1,33 @@
+package xero
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyState(t *testing.T) {
+	secret := []byte("secret")
+
+	state, nonce, err := NewState(secret, time.Minute)
+	assert.NoError(t, err)
+
+	verified, err := VerifyState(secret, state)
+	assert.NoError(t, err)
+	assert.Equal(t, nonce, verified)
+
+	// Wrong secret
+	_, err = VerifyState([]byte("other"), state)
+	assert.ErrorIs(t, err, ErrInvalidState)
+
+	// Tampered payload
+	_, err = VerifyState(secret, "x"+state)
+	assert.ErrorIs(t, err, ErrInvalidState)
+
+	// Expired
+	expired, _, err := NewState(secret, -time.Minute)
+	assert.NoError(t, err)
+	_, err = VerifyState(secret, expired)
+	assert.ErrorIs(t, err, ErrInvalidState)
+}
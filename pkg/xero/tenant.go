@@ -3,66 +3,185 @@ package xero
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/go-resty/resty/v2"
+	"golang.org/x/sync/singleflight"
 )
 
-// XeroConnection represents the tenant ID response
+const (
+	connectionsURL = "https://api.xero.com/connections"
+	connectionsTTL = 5 * time.Minute
+)
+
+var (
+	ErrNoTenants          = errors.New("no tenants found for this Xero account")
+	ErrTenantRequired     = errors.New("xero tenant id is required when more than one tenant is connected")
+	ErrTenantNotConnected = errors.New("xero tenant is not connected")
+)
+
+// XeroConnection represents a tenant returned by the Xero connections endpoint
 type XeroConnection struct {
-	TenantID string `json:"tenantId"`
+	ID         string `json:"id"`
+	TenantID   string `json:"tenantId"`
+	TenantType string `json:"tenantType"`
+	TenantName string `json:"tenantName"`
+}
+
+type cachedConnections struct {
+	connections []XeroConnection
+	fetchedAt   time.Time
+}
+
+// TenantResolver checks requested tenants against the connections visible to
+// their token. Results are cached per token owner for connectionsTTL, and concurrent
+// fetches for the same owner share one call to Xero.
+type TenantResolver struct {
+	tokens *TokenManager
+	client *resty.Client
+	group  singleflight.Group
+	mutex  sync.Mutex // guards cache only, never held across calls to Xero
+	cache  map[string]cachedConnections
+}
+
+func NewTenantResolver(tokens *TokenManager) *TenantResolver {
+	return &TenantResolver{
+		tokens: tokens,
+		client: resty.New(),
+		cache:  make(map[string]cachedConnections),
+	}
 }
 
-// Singleton Tenant ID cache
-var tenantIDCache string
-var tenantMutex sync.Mutex
+// GetXeroConnections returns every tenant connected to the app-level token
+func (r *TenantResolver) GetXeroConnections(ctx context.Context) ([]XeroConnection, error) {
+	return r.getXeroConnections(ctx, "", false)
+}
+
+// ResolveTenantID returns the tenant requested in ctx after checking it against the
+// connected tenants. When no tenant was requested the only connected tenant is used.
+func (r *TenantResolver) ResolveTenantID(ctx context.Context) (string, error) {
+	requested := TenantIDFromContext(ctx)
+	connections, err := r.getXeroConnections(ctx, requested, false)
+	if err != nil {
+		return "", err
+	}
 
-// GetXeroTenantID fetches the tenant ID using the access token
-func GetXeroTenantID(ctx context.Context) (string, error) {
-	tenantMutex.Lock()
-	defer tenantMutex.Unlock()
+	if requested == "" {
+		if len(connections) == 1 {
+			return connections[0].TenantID, nil
+		}
+		return "", ErrTenantRequired
+	}
 
-	// Return cached tenant ID if available
-	if tenantIDCache != "" {
-		return tenantIDCache, nil
+	if containsTenant(connections, requested) {
+		return requested, nil
 	}
 
-	// Get access token
-	accessToken, err := GetAccessToken(ctx)
+	// The tenant may have been connected after the list was cached
+	connections, err = r.getXeroConnections(ctx, requested, true)
 	if err != nil {
 		return "", err
 	}
+	if containsTenant(connections, requested) {
+		return requested, nil
+	}
+
+	return "", fmt.Errorf("%w: %s", ErrTenantNotConnected, requested)
+}
 
-	client := resty.New()
-	url := "https://api.xero.com/connections"
+// Invalidate drops the cached connections so the next call asks Xero again
+func (r *TenantResolver) Invalidate() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.cache = make(map[string]cachedConnections)
+}
 
-	//logger.Info("Fetching Xero tenant ID...")
+// getXeroConnections returns the connections visible to the token of tenantID
+// ("" for the app-level token), fetching them when the cached list is stale or force is set
+func (r *TenantResolver) getXeroConnections(ctx context.Context, tenantID string, force bool) ([]XeroConnection, error) {
+	r.mutex.Lock()
+	cached, ok := r.cache[tenantID]
+	r.mutex.Unlock()
+	if !force && ok && time.Since(cached.fetchedAt) < connectionsTTL {
+		return cached.connections, nil
+	}
+
+	// The shared fetch is not cancelled when one of the callers gives up
+	fetchCtx := context.WithoutCancel(ctx)
+	result := r.group.DoChan(tenantID, func() (interface{}, error) {
+		return r.fetch(fetchCtx, tenantID)
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case shared := <-result:
+		if shared.Err != nil {
+			return nil, shared.Err
+		}
+		return shared.Val.([]XeroConnection), nil
+	}
+}
+
+func (r *TenantResolver) fetch(ctx context.Context, tenantID string) ([]XeroConnection, error) {
+	accessToken, err := r.tokens.GetAccessToken(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	connections, err := FetchConnections(ctx, r.client, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.cache[tenantID] = cachedConnections{
+		connections: connections,
+		fetchedAt:   time.Now(),
+	}
+	return connections, nil
+}
+
+// FetchConnections lists the tenants authorised by accessToken
+func FetchConnections(ctx context.Context, client *resty.Client, accessToken string) ([]XeroConnection, error) {
 	resp, err := client.R().
 		SetContext(ctx).
 		SetHeader("Authorization", "Bearer "+accessToken).
 		SetHeader("Content-Type", "application/json").
-		Get(url)
+		Get(connectionsURL)
 
 	if err != nil {
-		return "", fmt.Errorf("failed to call Xero Connections API: %w", err)
+		return nil, fmt.Errorf("failed to call Xero Connections API: %w", err)
+	}
+
+	if resp.StatusCode() >= 400 {
+		return nil, NewAPIError(resp, "")
 	}
-	
-	fmt.Println("Xero Tenants Response:", string(resp.Body()))
-	// Parse response
+
 	var connections []XeroConnection
 	err = json.Unmarshal(resp.Body(), &connections)
 	if err != nil {
-		return "", fmt.Errorf("failed to parse tenant ID response: %w", err)
+		return nil, fmt.Errorf("failed to parse tenant ID response: %w", err)
 	}
 
 	// Ensure at least one tenant exists
 	if len(connections) == 0 {
-		return "", fmt.Errorf("no tenants found for this Xero account")
+		return nil, ErrNoTenants
 	}
 
-	// Cache and return the first tenant ID
-	tenantIDCache = connections[0].TenantID
-	//logger.Info("Xero Tenant ID:", tenantIDCache)
-	return tenantIDCache, nil
+	return connections, nil
+}
+
+func containsTenant(connections []XeroConnection, tenantID string) bool {
+	for _, connection := range connections {
+		if connection.TenantID == tenantID {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,70 @@
+package xero
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"usdw/pkg/cache/inmem"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestTenantResolverDoesNotSerialiseTenants(t *testing.T) {
+	ctx := context.Background()
+	store := NewCacheTokenStore(inmem.NewInMemoryCache(0, 0))
+	for _, tenantID := range []string{"slow", "fast"} {
+		require.NoError(t, store.Save(ctx, tenantID, &StoredToken{AccessToken: tenantID, Expiry: time.Now().Add(time.Hour)}))
+	}
+
+	var slowCalls atomic.Int32
+	release := make(chan struct{})
+	resolver := NewTenantResolver(NewTokenManager(store, false))
+	resolver.client = resty.New().SetTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		tenantID := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+		if tenantID == "slow" {
+			slowCalls.Add(1)
+			<-release
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(fmt.Sprintf(`[{"tenantId":%q}]`, tenantID))),
+			Request:    req,
+		}, nil
+	}))
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tenantID, err := resolver.ResolveTenantID(WithTenantID(ctx, "slow"))
+			assert.NoError(t, err)
+			assert.Equal(t, "slow", tenantID)
+		}()
+	}
+	assert.Eventually(t, func() bool { return slowCalls.Load() == 1 }, time.Second, time.Millisecond)
+
+	// Another tenant is resolved while the slow fetch is still running
+	tenantID, err := resolver.ResolveTenantID(WithTenantID(ctx, "fast"))
+	require.NoError(t, err)
+	assert.Equal(t, "fast", tenantID)
+
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), slowCalls.Load())
+}
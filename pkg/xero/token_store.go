@@ -0,0 +1,107 @@
+package xero
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+	"usdw/pkg/cache"
+)
+
+const (
+	tokenKeyPrefix = "xero:token:"
+	// Xero refresh tokens expire after 60 days without use
+	tokenStoreTTL = 60 * 24 * time.Hour
+)
+
+var ErrTokenNotFound = errors.New("xero token not found")
+
+// StoredToken is the OAuth token persisted for a tenant
+type StoredToken struct {
+	AccessToken  string    `json:"accessToken"`
+	RefreshToken string    `json:"refreshToken,omitempty"`
+	TokenType    string    `json:"tokenType"`
+	Expiry       time.Time `json:"expiry"`
+	Scopes       []string  `json:"scopes,omitempty"`
+	// TenantIDs lists every tenant authorised by the same grant; a refresh
+	// rotates the refresh token for all of them
+	TenantIDs []string `json:"tenantIds,omitempty"`
+}
+
+// NewStoredToken converts a token returned by the oauth2 package
+func NewStoredToken(token *oauth2.Token) *StoredToken {
+	stored := &StoredToken{
+		AccessToken:  token.AccessToken,
+		RefreshToken: token.RefreshToken,
+		TokenType:    token.TokenType,
+		Expiry:       token.Expiry,
+	}
+	if scope, ok := token.Extra("scope").(string); ok {
+		stored.Scopes = strings.Fields(scope)
+	}
+	return stored
+}
+
+// ExpiresWithin reports whether the access token expires before now+d
+func (t *StoredToken) ExpiresWithin(d time.Duration) bool {
+	return t.Expiry.Before(time.Now().Add(d))
+}
+
+// TokenStore persists tokens per tenant. The app-level token uses an empty tenant ID.
+type TokenStore interface {
+	Load(ctx context.Context, tenantID string) (*StoredToken, error)
+	Save(ctx context.Context, tenantID string, token *StoredToken) error
+	Delete(ctx context.Context, tenantID string) error
+}
+
+type cacheTokenStore struct {
+	engine cache.Engine
+}
+
+// NewCacheTokenStore returns a TokenStore backed by the configured cache engine,
+// so tokens are shared by every replica when Redis is used
+func NewCacheTokenStore(engine cache.Engine) TokenStore {
+	return &cacheTokenStore{engine: engine}
+}
+
+func (s *cacheTokenStore) Load(ctx context.Context, tenantID string) (*StoredToken, error) {
+	val, err := s.engine.Get(ctx, tokenKey(tenantID))
+	if err != nil {
+		if errors.Is(err, cache.ErrNotFound) {
+			return nil, ErrTokenNotFound
+		}
+		return nil, fmt.Errorf("failed to load xero token: %w", err)
+	}
+
+	var token StoredToken
+	err = json.Unmarshal(val, &token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse stored xero token: %w", err)
+	}
+
+	return &token, nil
+}
+
+func (s *cacheTokenStore) Save(ctx context.Context, tenantID string, token *StoredToken) error {
+	val, err := json.Marshal(token)
+	if err != nil {
+		return fmt.Errorf("failed to encode xero token: %w", err)
+	}
+
+	return s.engine.Set(ctx, tokenKey(tenantID), val, tokenStoreTTL)
+}
+
+func (s *cacheTokenStore) Delete(ctx context.Context, tenantID string) error {
+	return s.engine.Delete(ctx, tokenKey(tenantID))
+}
+
+func tokenKey(tenantID string) string {
+	if tenantID == "" {
+		return tokenKeyPrefix + "app"
+	}
+	return tokenKeyPrefix + tenantID
+}
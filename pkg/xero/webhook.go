@@ -0,0 +1,103 @@
+package xero
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"sync"
+)
+
+// WebhookSignatureHeader carries the base64 HMAC-SHA256 of the raw body keyed with the webhook key
+const WebhookSignatureHeader = "x-xero-signature"
+
+// Connection events, sent when an organisation connects or disconnects the app
+const (
+	EventCategoryConnection = "CONNECTION"
+	EventTypeCreate         = "CREATE"
+	EventTypeDelete         = "DELETE"
+)
+
+// Wildcard matches any category or event type in WebhookRouter.Handle
+const Wildcard = "*"
+
+// WebhookPayload is the body of a Xero webhook delivery. The "intent to receive"
+// handshake uses the same shape with no events.
+type WebhookPayload struct {
+	Events             []WebhookEvent `json:"events"`
+	FirstEventSequence int            `json:"firstEventSequence"`
+	LastEventSequence  int            `json:"lastEventSequence"`
+	Entropy            string         `json:"entropy"`
+}
+
+type WebhookEvent struct {
+	ResourceURL   string `json:"resourceUrl"`
+	ResourceID    string `json:"resourceId"`
+	EventDateUTC  string `json:"eventDateUtc"`
+	EventType     string `json:"eventType"`
+	EventCategory string `json:"eventCategory"`
+	TenantID      string `json:"tenantId"`
+	TenantType    string `json:"tenantType"`
+}
+
+// VerifyWebhookSignature checks the x-xero-signature header against the raw request body
+func VerifyWebhookSignature(key, body []byte, signature string) bool {
+	expected, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil || len(key) == 0 {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// WebhookHandlerFunc reacts to one verified webhook event
+type WebhookHandlerFunc func(ctx context.Context, event WebhookEvent) error
+
+type webhookRoute struct {
+	category  string
+	eventType string
+	handler   WebhookHandlerFunc
+}
+
+// WebhookRouter dispatches verified events to the handlers registered for their
+// category and type
+type WebhookRouter struct {
+	mutex  sync.RWMutex
+	routes []webhookRoute
+}
+
+func NewWebhookRouter() *WebhookRouter {
+	return &WebhookRouter{}
+}
+
+// Handle registers handler for events of category and eventType, either of which may be Wildcard
+func (r *WebhookRouter) Handle(category, eventType string, handler WebhookHandlerFunc) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.routes = append(r.routes, webhookRoute{category: category, eventType: eventType, handler: handler})
+}
+
+// Dispatch runs every matching handler in registration order and joins their errors
+func (r *WebhookRouter) Dispatch(ctx context.Context, event WebhookEvent) error {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var errs []error
+	for _, route := range r.routes {
+		if !matches(route.category, event.EventCategory) || !matches(route.eventType, event.EventType) {
+			continue
+		}
+		if err := route.handler(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func matches(pattern, value string) bool {
+	return pattern == Wildcard || pattern == value
+}
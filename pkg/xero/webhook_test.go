@@ -0,0 +1,50 @@
+package xero
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func signWebhook(key, body string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(body))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	body := `{"events":[],"firstEventSequence":0,"lastEventSequence":0,"entropy":"S0M3R4ND0M"}`
+
+	assert.True(t, VerifyWebhookSignature([]byte("key"), []byte(body), signWebhook("key", body)))
+	assert.False(t, VerifyWebhookSignature([]byte("key"), []byte(body), signWebhook("other", body)))
+	assert.False(t, VerifyWebhookSignature([]byte("key"), []byte(body+" "), signWebhook("key", body)))
+	assert.False(t, VerifyWebhookSignature([]byte("key"), []byte(body), "not base64!"))
+	assert.False(t, VerifyWebhookSignature(nil, []byte(body), signWebhook("", body)))
+}
+
+func TestWebhookRouterDispatch(t *testing.T) {
+	router := NewWebhookRouter()
+	var calls []string
+	record := func(name string, err error) WebhookHandlerFunc {
+		return func(_ context.Context, event WebhookEvent) error {
+			calls = append(calls, name+":"+event.TenantID)
+			return err
+		}
+	}
+	router.Handle(Wildcard, Wildcard, record("any", nil))
+	router.Handle("CONNECTION", "DELETE", record("disconnect", errors.New("boom")))
+	router.Handle("INVOICE", Wildcard, record("invoice", nil))
+
+	err := router.Dispatch(context.Background(), WebhookEvent{EventCategory: "CONNECTION", EventType: "DELETE", TenantID: "t1"})
+	assert.EqualError(t, err, "boom")
+	assert.Equal(t, []string{"any:t1", "disconnect:t1"}, calls)
+
+	calls = nil
+	assert.NoError(t, router.Dispatch(context.Background(), WebhookEvent{EventCategory: "INVOICE", EventType: "UPDATE", TenantID: "t2"}))
+	assert.Equal(t, []string{"any:t2", "invoice:t2"}, calls)
+}